@@ -0,0 +1 @@
+534
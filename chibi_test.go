@@ -61,6 +61,11 @@ func runFile(t *testing.T, fname string) (bool, string) {
 
 	scm := base + ".scm"
 	res := base + ".res"
+	// Where the result depends on something Scheme leaves unspecified,
+	// such as the order chibi evaluates let inits in, we have our own
+	if _, err := os.Stat(base + ".gol.res"); err == nil {
+		res = base + ".gol.res"
+	}
 
 	cmd := exec.Command("go", "run", "cmd/gol/gol.go", "-f", scm)
 	bytesOutput, err := cmd.CombinedOutput()
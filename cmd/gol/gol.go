@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/jbert/gol"
 	"github.com/jbert/gol/eval"
 	"github.com/jbert/gol/golang"
 )
 
 type options struct {
+	checkOnly      bool
 	displayResult  bool
 	fileName       string
 	outputFileName string
@@ -25,6 +27,7 @@ func (o options) validate() error {
 func main() {
 	o := options{}
 
+	flag.BoolVar(&o.checkOnly, "c", false, "Check syntax only, reporting every error")
 	flag.BoolVar(&o.displayResult, "e", false, "Show result evaluation")
 	flag.StringVar(&o.fileName, "f", "", "Name of file to evaluate")
 	flag.StringVar(&o.outputFileName, "o", "", "Name of file to compile to")
@@ -38,7 +41,13 @@ func main() {
 		os.Exit(-1)
 	}
 
-	if o.outputFileName != "" {
+	if o.checkOnly {
+		err = checkFile(o.fileName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(-1)
+		}
+	} else if o.outputFileName != "" {
 		// Compiling
 		err = golang.CompileFile(o.fileName, o.outputFileName)
 		if err != nil {
@@ -59,3 +68,18 @@ func main() {
 		}
 	}
 }
+
+func checkFile(fname string) error {
+	f, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	p := gol.NewLexerParser(gol.NewLexer(fname, f))
+	_, diags := p.ParseRecover()
+	if diags != nil {
+		return diags
+	}
+	return nil
+}
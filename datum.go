@@ -0,0 +1,335 @@
+package gol
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// ReadDatum reads the next datum from r, as data rather than code: the
+// quote abbreviations become the lists they stand for and nothing is
+// transformed. It returns io.EOF if r has no more data.
+//
+// The lexer reads ahead, so unread is any input from r which followed
+// the datum.
+func ReadDatum(srcName string, r io.Reader) (datum Node, unread []byte, err error) {
+	l := NewLexer(srcName, r)
+	numTokens := 0
+	p := &Parser{
+		next: func() (Token, error) {
+			tok, err := l.Next()
+			if err == io.EOF {
+				return TokBug, ErrNoMoreTokens
+			}
+			numTokens++
+			return tok, err
+		},
+	}
+	n, err := p.parseSexp()
+	unread = l.buf[l.pos:]
+	if err == ErrNoMoreTokens {
+		if numTokens == 0 {
+			return nil, unread, io.EOF
+		}
+		return nil, unread, posErrorf(l.currentPosition(), "Unexpected end of input in datum")
+	}
+	if err != nil {
+		return nil, unread, err
+	}
+	return unabbreviate(n), unread, nil
+}
+
+// unabbreviate replaces 'x, `x and ,x with (quote x), (quasiquote x)
+// and (unquote x) throughout a datum
+func unabbreviate(n Node) Node {
+	switch d := n.(type) {
+	case *NodeQuote:
+		name := "quote"
+		if d.Quasi {
+			name = "quasiquote"
+		}
+		return synthList(d, synthIdentifier(name, d), unabbreviate(d.Arg))
+	case *NodeUnQuote:
+		return synthList(d, synthIdentifier("unquote", d), unabbreviate(d.Arg))
+	case *NodeList:
+		nl, _ := d.Map(func(child Node) (Node, error) {
+			return unabbreviate(child), nil
+		})
+		nl.SetSpan(d.Span())
+		return nl
+	case *NodePair:
+		if d.IsNil() {
+			return d
+		}
+		return Cons(unabbreviate(d.Car), unabbreviate(d.Cdr))
+	case *NodeVector:
+		elems := make([]Node, len(d.elems))
+		for i, elem := range d.elems {
+			elems[i] = unabbreviate(elem)
+		}
+		nv := NewNodeVector(elems)
+		nv.SetSpan(d.Span())
+		return nv
+	default:
+		return n
+	}
+}
+
+// WriteString is the external representation of n, which ReadDatum
+// reads back as an equal datum. Unlike String, strings and chars are
+// written as literals. A pair or vector which contains itself is
+// written with a datum label, #0= where it's first written and #0#
+// after, so that circular data is written finitely. ReadDatum doesn't
+// read those back.
+func WriteString(n Node) string {
+	w := datumWriter{labels: circularData(n)}
+	w.writeDatum(n)
+	return w.sb.String()
+}
+
+// datumWriter writes an external representation
+type datumWriter struct {
+	sb strings.Builder
+	// labels has the pairs and vectors which need a datum label, with
+	// the label once it has been written, or -1 before
+	labels map[Node]int
+	next   int
+}
+
+// labelKey is what identifies n for a datum label, or nil if it can't
+// have one
+func labelKey(n Node) Node {
+	switch d := n.(type) {
+	case *NodeList:
+		return labelKey(d.children)
+	case *NodePair:
+		if d.IsNil() {
+			return nil
+		}
+		return d
+	case *NodeVector:
+		return d
+	}
+	return nil
+}
+
+// circularData finds the pairs and vectors in n which are reached again
+// from within themselves
+func circularData(n Node) map[Node]int {
+	labels := make(map[Node]int)
+	inside := make(map[Node]bool)
+	seen := make(map[Node]bool)
+	var visit func(n Node)
+	visit = func(n Node) {
+		switch d := n.(type) {
+		case *NodeQuote:
+			visit(d.Arg)
+			return
+		case *NodeUnQuote:
+			visit(d.Arg)
+			return
+		}
+		key := labelKey(n)
+		if key == nil {
+			return
+		}
+		if inside[key] {
+			labels[key] = -1
+			return
+		}
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		inside[key] = true
+		switch d := key.(type) {
+		case *NodePair:
+			visit(d.Car)
+			visit(d.Cdr)
+		case *NodeVector:
+			for _, elem := range d.elems {
+				visit(elem)
+			}
+		}
+		delete(inside, key)
+	}
+	visit(n)
+	return labels
+}
+
+// writeLabel writes the datum label for n, if it needs one, and says
+// whether that is all that's needed since n has been written already
+func (w *datumWriter) writeLabel(n Node) bool {
+	key := labelKey(n)
+	label, ok := w.labels[key]
+	if key == nil || !ok {
+		return false
+	}
+	if label >= 0 {
+		fmt.Fprintf(&w.sb, "#%d#", label)
+		return true
+	}
+	w.labels[key] = w.next
+	fmt.Fprintf(&w.sb, "#%d=", w.next)
+	w.next++
+	return false
+}
+
+func (w *datumWriter) writeDatum(n Node) {
+	sb := &w.sb
+	switch d := n.(type) {
+	case *NodeString:
+		writeStringLiteral(sb, d.value, '"')
+	case *NodeIdentifier:
+		if readsAsIdentifier(d.String()) {
+			sb.WriteString(d.String())
+		} else {
+			writeStringLiteral(sb, d.String(), '|')
+		}
+	case *NodeChar:
+		writeCharLiteral(sb, d.value)
+	case *NodeQuote:
+		if d.Quasi {
+			sb.WriteString("`")
+		} else {
+			sb.WriteString("'")
+		}
+		w.writeDatum(d.Arg)
+	case *NodeUnQuote:
+		sb.WriteString(",")
+		w.writeDatum(d.Arg)
+	case *NodeList:
+		if !w.writeLabel(d) {
+			w.writePairChain(d.children)
+		}
+	case *NodePair:
+		if !w.writeLabel(d) {
+			w.writePairChain(d)
+		}
+	case *NodeVector:
+		if w.writeLabel(d) {
+			return
+		}
+		sb.WriteString("#(")
+		for i, elem := range d.elems {
+			if i > 0 {
+				sb.WriteString(" ")
+			}
+			w.writeDatum(elem)
+		}
+		sb.WriteString(")")
+	default:
+		sb.WriteString(n.String())
+	}
+}
+
+// writePairChain is pairChainString for external representations. A
+// labelled pair in the chain is written after a dot, with its label.
+func (w *datumWriter) writePairChain(p *NodePair) {
+	sb := &w.sb
+	sb.WriteString("(")
+	var rest Node = p
+	first := true
+	for {
+		if key := labelKey(rest); !first && key != nil {
+			if _, ok := w.labels[key]; ok {
+				sb.WriteString(" . ")
+				w.writeDatum(rest)
+				sb.WriteString(")")
+				return
+			}
+		}
+		switch r := rest.(type) {
+		case *NodePair:
+			if r.IsNil() {
+				sb.WriteString(")")
+				return
+			}
+			if !first {
+				sb.WriteString(" ")
+			}
+			first = false
+			w.writeDatum(r.Car)
+			rest = r.Cdr
+		case *NodeList:
+			rest = r.children
+		default:
+			sb.WriteString(" . ")
+			w.writeDatum(rest)
+			sb.WriteString(")")
+			return
+		}
+	}
+}
+
+// stringLiteralEscapes are the inverse of stringEscapes, for the runes
+// which have a short escape
+var stringLiteralEscapes = map[rune]string{
+	'\a': `\a`,
+	'\b': `\b`,
+	'\t': `\t`,
+	'\n': `\n`,
+	'\r': `\r`,
+	'"':  `\"`,
+	'\\': `\\`,
+}
+
+// writeStringLiteral writes s between quotes, which are '"' for a string
+// or '|' for an identifier which wouldn't otherwise read back
+func writeStringLiteral(sb *strings.Builder, s string, quote rune) {
+	sb.WriteRune(quote)
+	for _, r := range s {
+		if r == quote {
+			sb.WriteRune('\\')
+			sb.WriteRune(r)
+		} else if esc, ok := stringLiteralEscapes[r]; ok {
+			sb.WriteString(esc)
+		} else if !unicode.IsPrint(r) {
+			fmt.Fprintf(sb, `\x%x;`, r)
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteRune(quote)
+}
+
+// readsAsIdentifier is true if name can be written as it is, because
+// it reads back as the identifier name rather than a number, a dot or
+// several tokens
+func readsAsIdentifier(name string) bool {
+	if _, ok := specialReals[name]; ok || name == "." {
+		return false
+	}
+	l := NewLexer("<write>", strings.NewReader(name))
+	tok, err := l.Next()
+	if err != nil || tok.Type != tokIdentifier || tok.Value != name {
+		return false
+	}
+	_, err = l.Next()
+	return err == io.EOF
+}
+
+// charLiteralNames are the inverse of charNames
+var charLiteralNames = map[rune]string{
+	'\a': "alarm",
+	'\b': "backspace",
+	0x7f: "delete",
+	0x1b: "escape",
+	'\n': "newline",
+	0:    "null",
+	'\r': "return",
+	' ':  "space",
+	'\t': "tab",
+}
+
+func writeCharLiteral(sb *strings.Builder, r rune) {
+	sb.WriteString(`#\`)
+	if name, ok := charLiteralNames[r]; ok {
+		sb.WriteString(name)
+	} else if !unicode.IsPrint(r) {
+		fmt.Fprintf(sb, "x%x", r)
+	} else {
+		sb.WriteRune(r)
+	}
+}
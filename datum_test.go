@@ -0,0 +1,152 @@
+package gol
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadDatum(t *testing.T) {
+	r := strings.NewReader("(define x 'y) #(1 \"two\") rest")
+	datum, unread, err := ReadDatum("<test>", r)
+	if err != nil {
+		t.Fatalf("Error reading: %s", err)
+	}
+	if _, ok := datum.(*NodeList); !ok {
+		t.Fatalf("Datum was transformed: %T", datum)
+	}
+	if datum.String() != "(define x (quote y))" {
+		t.Errorf("Wrong datum: %s", datum)
+	}
+	if string(unread) != " #(1 \"two\") rest" {
+		t.Errorf("Wrong unread input: [%s]", unread)
+	}
+
+	_, _, err = ReadDatum("<test>", strings.NewReader("  ; nothing\n"))
+	if err != io.EOF {
+		t.Errorf("No EOF for empty input: %v", err)
+	}
+	_, _, err = ReadDatum("<test>", strings.NewReader("(a (b"))
+	if err == nil || !strings.HasPrefix(err.Error(), "Unclosed list: <test> line 1:4") {
+		t.Errorf("Wrong error for incomplete datum: %v", err)
+	}
+	_, _, err = ReadDatum("<test>", strings.NewReader("'"))
+	if err == nil || !strings.HasPrefix(err.Error(), "Unexpected end of input in datum") {
+		t.Errorf("Wrong error for quote with no datum: %v", err)
+	}
+}
+
+func TestWriteStringRoundTrip(t *testing.T) {
+	testCases := []string{
+		`"a\"b\\c\n"`,
+		`#\space`,
+		`#\alarm`,
+		`#\x1`,
+		`(a (b . c) #(1 "x") (quote q))`,
+		`(1.5 -2 +inf.0)`,
+		`""`,
+		`(|a b| |12| |.| |+inf.0| || |x\|y| |#t| foo->bar)`,
+	}
+	for _, src := range testCases {
+		datum, _, err := ReadDatum("<test>", strings.NewReader(src))
+		if err != nil {
+			t.Errorf("Error reading [%s]: %s", src, err)
+			continue
+		}
+		if WriteString(datum) != src {
+			t.Errorf("Wrong external representation: [%s] != [%s]", WriteString(datum), src)
+		}
+	}
+}
+
+func TestWriteStringIdentifiers(t *testing.T) {
+	testCases := []struct {
+		name    string
+		written string
+	}{
+		{"a b", "|a b|"},
+		{"12", "|12|"},
+		{"-", "-"},
+		{"a|b", `|a\|b|`},
+		{"tab\t", `|tab\t|`},
+		{"(x)", "|(x)|"},
+	}
+	for _, tc := range testCases {
+		written := WriteString(NewNodeIdentifier(tc.name))
+		if written != tc.written {
+			t.Errorf("Wrong external representation for [%s]: [%s] != [%s]", tc.name, written, tc.written)
+			continue
+		}
+		datum, _, err := ReadDatum("<test>", strings.NewReader(written))
+		if err != nil {
+			t.Errorf("Error reading [%s]: %s", written, err)
+			continue
+		}
+		id, ok := datum.(*NodeIdentifier)
+		if !ok || id.String() != tc.name {
+			t.Errorf("Read back [%s] as %T [%s]", written, datum, datum)
+		}
+	}
+}
+
+func TestTransformDatum(t *testing.T) {
+	datum, _, err := ReadDatum("<test>", strings.NewReader("(let ((x 1)) (when x 'y))"))
+	if err != nil {
+		t.Fatalf("Error reading: %s", err)
+	}
+	tree, err := TransformDatum(datum, nil)
+	if err != nil {
+		t.Fatalf("Error transforming: %s", err)
+	}
+	progn, ok := tree.(*NodeProgn)
+	if !ok || progn.Rest().Len() != 1 {
+		t.Fatalf("Not a progn of one expression: %T %s", tree, tree)
+	}
+	if _, ok := progn.Rest().First().(*NodeLet); !ok {
+		t.Errorf("Expression not transformed: %T", progn.Rest().First())
+	}
+	if datum.String() != "(let ((x 1)) (when x (quote y)))" {
+		t.Errorf("Datum was changed: %s", datum)
+	}
+}
+
+func TestWriteStringCircular(t *testing.T) {
+	// (1 2 . <itself>)
+	tail := NewNodePair(NewNodeInt(2), Nil())
+	list := NewNodePair(NewNodeInt(1), tail)
+	tail.Cdr = list
+
+	// (<itself>)
+	inCar := NewNodePair(Nil(), Nil())
+	inCar.Car = inCar
+
+	// #(1 <itself>)
+	vector := NewNodeVector([]Node{NewNodeInt(1), Nil()})
+	vector.Set(1, vector)
+
+	// Shared, but not circular, so no labels
+	shared := NewNodePair(NewNodeInt(1), Nil())
+	twice := NewNodePair(shared, NewNodePair(shared, Nil()))
+
+	// Circular below the top
+	inner := NewNodePair(NewNodeIdentifier("a"), Nil())
+	inner.Cdr = inner
+	below := NewNodePair(NewNodeInt(0), NewNodePair(inner, Nil()))
+
+	testCases := []struct {
+		datum   Node
+		written string
+	}{
+		{list, "#0=(1 2 . #0#)"},
+		{inCar, "#0=(#0#)"},
+		{vector, "#0=#(1 #0#)"},
+		{twice, "((1) (1))"},
+		{below, "(0 #0=(a . #0#))"},
+	}
+	for _, tc := range testCases {
+		written := WriteString(tc.datum)
+		if written != tc.written {
+			t.Errorf("Wrong external representation: [%s] != [%s]", written, tc.written)
+		}
+	}
+}
@@ -0,0 +1,636 @@
+package gol
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// The R7RS derived expressions are rewritten into the core forms
+// (if, let, letrec, lambda, progn) and the result transformed as
+// usual, so both backends only ever see the core forms.
+
+var gensymCount int64
+
+// gensym makes an identifier which can't clash with any the program
+// uses, for temporaries introduced by a rewrite
+func gensym(prefix string, source Node) *NodeIdentifier {
+	n := atomic.AddInt64(&gensymCount, 1)
+	return synthIdentifier(fmt.Sprintf("%%%s%d", prefix, n), source)
+}
+
+// DerivedBuiltins are the procedures which the rewrites call. They're
+// called by a reserved name, "%" and the builtin's name, so that a
+// local binding of the same name doesn't change what a rewrite does.
+var DerivedBuiltins = []string{"=", ">=", "apply", "call-with-values", "eqv?", "error", "length", "raise-continuable", "void"}
+
+// synthBuiltin names one of the DerivedBuiltins, for a call to it
+func synthBuiltin(name string, source Node) *NodeIdentifier {
+	return synthIdentifier("%"+name, source)
+}
+
+// synthList makes a list which stands in for source, taking its span
+func synthList(source Node, nodes ...Node) *NodeList {
+	nl := NewNodeList()
+	for i := len(nodes) - 1; i >= 0; i-- {
+		nl = nl.Cons(nodes[i])
+	}
+	nl.SetSpan(source.Span())
+	return nl
+}
+
+func synthBool(b bool, source Node) *NodeBool {
+	value := "#f"
+	if b {
+		value = "#t"
+	}
+	nb := &NodeBool{nodeAtom{tok: Token{Type: tokBool, Value: value}}}
+	nb.SetSpan(source.Span())
+	return nb
+}
+
+func isIdentifier(n Node, name string) bool {
+	id, ok := n.(*NodeIdentifier)
+	return ok && id.String() == name
+}
+
+// synthBody is a single expression for a non-empty body
+func synthBody(source Node, body *NodeList) Node {
+	if body.Len() == 1 {
+		return body.First()
+	}
+	return synthList(source, append([]Node{synthIdentifier("progn", source)}, listNodes(body)...)...)
+}
+
+// synthVoid is the unspecified value, for when no clause applies
+func synthVoid(source Node) Node {
+	return synthList(source, synthBuiltin("void", source))
+}
+
+func synthIf(source Node, test, tBranch, fBranch Node) *NodeList {
+	return synthList(source, synthIdentifier("if", source), test, tBranch, fBranch)
+}
+
+// synthLet1 binds a single id to value around body
+func synthLet1(source Node, id *NodeIdentifier, value Node, body Node) *NodeList {
+	bindings := synthList(source, synthList(source, id, value))
+	return synthList(source, synthIdentifier("let", source), bindings, body)
+}
+
+func listNodes(nl *NodeList) []Node {
+	nodes := make([]Node, 0, nl.Len())
+	nl.Foreach(func(n Node) error {
+		nodes = append(nodes, n)
+		return nil
+	})
+	return nodes
+}
+
+// transformDerived transforms the rewrite of a derived expression
+func transformDerived(expand func(n *NodeList) (Node, error)) func(n *NodeList) (Node, error) {
+	return func(n *NodeList) (Node, error) {
+		expansion, err := expand(n)
+		if err != nil {
+			return nil, err
+		}
+		return transform(expansion)
+	}
+}
+
+// (and) => #t
+// (and a) => a
+// (and a b ...) => (if a (and b ...) #f)
+func expandAnd(n *NodeList) (Node, error) {
+	args := n.Rest()
+	switch args.Len() {
+	case 0:
+		return synthBool(true, n), nil
+	case 1:
+		return args.First(), nil
+	}
+	rest := args.Rest().Cons(n.First())
+	rest.SetSpan(n.Span())
+	return synthIf(n, args.First(), rest, synthBool(false, n)), nil
+}
+
+// (or) => #f
+// (or a) => a
+// (or a b ...) => (let ((tmp a)) (if tmp tmp (or b ...)))
+func expandOr(n *NodeList) (Node, error) {
+	args := n.Rest()
+	switch args.Len() {
+	case 0:
+		return synthBool(false, n), nil
+	case 1:
+		return args.First(), nil
+	}
+	rest := args.Rest().Cons(n.First())
+	rest.SetSpan(n.Span())
+	tmp := gensym("or", n)
+	return synthLet1(n, tmp, args.First(), synthIf(n, tmp, tmp, rest)), nil
+}
+
+// (when test body ...) => (if test (progn body ...) (void))
+func expandWhen(n *NodeList) (Node, error) {
+	if n.Len() < 3 {
+		return nil, NodeErrorf(n, "Bad when expression - missing test or body")
+	}
+	body := synthBody(n, n.Rest().Rest())
+	return synthIf(n, n.Nth(1), body, synthVoid(n)), nil
+}
+
+// (unless test body ...) => (if test (void) (progn body ...))
+func expandUnless(n *NodeList) (Node, error) {
+	if n.Len() < 3 {
+		return nil, NodeErrorf(n, "Bad unless expression - missing test or body")
+	}
+	body := synthBody(n, n.Rest().Rest())
+	return synthIf(n, n.Nth(1), synthVoid(n), body), nil
+}
+
+func expandCond(n *NodeList) (Node, error) {
+	return expandCondClauses(n, n.Rest())
+}
+
+func expandCondClauses(n *NodeList, clauses *NodeList) (Node, error) {
+	if clauses.Len() == 0 {
+		return synthVoid(n), nil
+	}
+	clause, ok := clauses.First().(*NodeList)
+	if !ok || clause.Len() == 0 {
+		return nil, NodeErrorf(n, "Bad cond expression - clause must be a non-empty list")
+	}
+
+	if isIdentifier(clause.First(), "else") {
+		if clauses.Len() != 1 {
+			return nil, NodeErrorf(clause, "Bad cond expression - else clause must be last")
+		}
+		if clause.Len() == 1 {
+			return nil, NodeErrorf(clause, "Bad cond expression - empty else clause")
+		}
+		return synthBody(clause, clause.Rest()), nil
+	}
+
+	rest, err := expandCondClauses(n, clauses.Rest())
+	if err != nil {
+		return nil, err
+	}
+	test := clause.First()
+	switch {
+	case clause.Len() == 1:
+		// (test) yields the value of test if true
+		tmp := gensym("cond", clause)
+		return synthLet1(clause, tmp, test, synthIf(clause, tmp, tmp, rest)), nil
+	case isIdentifier(clause.Nth(1), "=>"):
+		if clause.Len() != 3 {
+			return nil, NodeErrorf(clause, "Bad cond expression - => takes exactly one receiver")
+		}
+		tmp := gensym("cond", clause)
+		call := synthList(clause, clause.Nth(2), tmp)
+		return synthLet1(clause, tmp, test, synthIf(clause, tmp, call, rest)), nil
+	default:
+		return synthIf(clause, test, synthBody(clause, clause.Rest()), rest), nil
+	}
+}
+
+// (case key ((d ...) e ...) ... (else e ...)) =>
+//
+//	(let ((k key)) (cond ((or (eqv? k 'd) ...) e ...) ... (else e ...)))
+func expandCase(n *NodeList) (Node, error) {
+	if n.Len() < 2 {
+		return nil, NodeErrorf(n, "Bad case expression - missing key")
+	}
+	key := gensym("case", n)
+	condClauses := []Node{synthIdentifier("cond", n)}
+	err := n.Rest().Rest().Foreach(func(clauseNode Node) error {
+		clause, ok := clauseNode.(*NodeList)
+		if !ok || clause.Len() < 2 {
+			return NodeErrorf(n, "Bad case expression - clause must have data and a body")
+		}
+
+		var test Node
+		if isIdentifier(clause.First(), "else") {
+			test = clause.First()
+		} else {
+			data, ok := clause.First().(*NodeList)
+			if !ok {
+				return NodeErrorf(clause, "Bad case expression - data must be a list")
+			}
+			tests := []Node{synthIdentifier("or", clause)}
+			data.Foreach(func(datum Node) error {
+				tests = append(tests, synthList(clause, synthBuiltin("eqv?", clause), key, quoteDatum(datum)))
+				return nil
+			})
+			test = synthList(clause, tests...)
+		}
+
+		body := clause.Rest()
+		if isIdentifier(body.First(), "=>") {
+			if body.Len() != 2 {
+				return NodeErrorf(clause, "Bad case expression - => takes exactly one receiver")
+			}
+			body = synthList(clause, synthList(clause, body.Nth(1), key))
+		}
+		condClauses = append(condClauses, synthList(clause, append([]Node{test}, listNodes(body)...)...))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return synthLet1(n, key, n.Nth(1), synthList(n, condClauses...)), nil
+}
+
+// quoteDatum leaves self-evaluating data alone, so the golang backend
+// can compile case over numbers, chars and strings
+func quoteDatum(datum Node) Node {
+	switch datum.(type) {
+	case *NodeInt, *NodeReal, *NodeRational, *NodeString, *NodeChar, *NodeBool:
+		return datum
+	default:
+		return synthList(datum, synthIdentifier("quote", datum), datum)
+	}
+}
+
+// (let* () body ...) => (let () body ...)
+// (let* (b) body ...) => (let (b) body ...)
+// (let* (b1 b2 ...) body ...) => (let (b1) (let* (b2 ...) body ...))
+func expandLetStar(n *NodeList) (Node, error) {
+	if n.Len() < 3 {
+		return nil, NodeErrorf(n, "Bad let* expression - missing bindings or body")
+	}
+	bindings, ok := n.Nth(1).(*NodeList)
+	if !ok {
+		return nil, NodeErrorf(n, "Bad let* expression - bindings must be a list")
+	}
+	body := listNodes(n.Rest().Rest())
+	let := synthIdentifier("let", n)
+	if bindings.Len() <= 1 {
+		return synthList(n, append([]Node{let, bindings}, body...)...), nil
+	}
+	inner := synthList(n, append([]Node{n.First(), bindings.Rest()}, body...)...)
+	return synthList(n, let, synthList(n, bindings.First()), inner), nil
+}
+
+// (let name ((v init) ...) body ...) =>
+//
+//	(let ((tmp init) ...)
+//	  (letrec ((name (lambda (v ...) body ...)))
+//	    (name tmp ...)))
+//
+// The inits are bound outside the letrec so they can't see name.
+func expandNamedLet(n *NodeList) (Node, error) {
+	if n.Len() < 4 {
+		return nil, NodeErrorf(n, "Bad named let expression - missing bindings or body")
+	}
+	name := n.Nth(1).(*NodeIdentifier)
+	bindings, ok := n.Nth(2).(*NodeList)
+	if !ok {
+		return nil, NodeErrorf(n, "Bad named let expression - bindings must be a list")
+	}
+
+	var args, outerBindings []Node
+	call := []Node{name}
+	err := bindings.Foreach(func(bindingNode Node) error {
+		binding, ok := bindingNode.(*NodeList)
+		if !ok || binding.Len() != 2 {
+			return NodeErrorf(n, "Bad named let expression - bindings must be pairs")
+		}
+		tmp := gensym("let", binding)
+		args = append(args, binding.First())
+		outerBindings = append(outerBindings, synthList(binding, tmp, binding.Nth(1)))
+		call = append(call, tmp)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lambda := synthList(n, append([]Node{synthIdentifier("lambda", n), synthList(n, args...)}, listNodes(n.Rest().Rest().Rest())...)...)
+	letrec := synthList(n,
+		synthIdentifier("letrec", n),
+		synthList(n, synthList(n, name, lambda)),
+		synthList(n, call...))
+	return synthList(n, synthIdentifier("let", n), synthList(n, outerBindings...), letrec), nil
+}
+
+// (do ((var init step) ...) (test result ...) command ...) =>
+//
+//	(let loop ((var init) ...)
+//	  (if test
+//	      (progn result ...)
+//	      (progn command ... (loop step ...))))
+//
+// A var without a step keeps its value, and no results gives (void).
+func expandDo(n *NodeList) (Node, error) {
+	if n.Len() < 3 {
+		return nil, NodeErrorf(n, "Bad do expression - missing specs or test")
+	}
+	specs, ok := n.Nth(1).(*NodeList)
+	if !ok {
+		return nil, NodeErrorf(n, "Bad do expression - specs must be a list")
+	}
+	exit, ok := n.Nth(2).(*NodeList)
+	if !ok || exit.Len() == 0 {
+		return nil, NodeErrorf(n, "Bad do expression - missing test")
+	}
+
+	loop := gensym("do", n)
+	var bindings []Node
+	recur := []Node{loop}
+	err := specs.Foreach(func(specNode Node) error {
+		spec, ok := specNode.(*NodeList)
+		if !ok || spec.Len() < 2 || spec.Len() > 3 {
+			return NodeErrorf(n, "Bad do expression - spec must be (var init [step])")
+		}
+		if _, ok := spec.First().(*NodeIdentifier); !ok {
+			return NodeErrorf(n, "Bad do expression - invalid identifier")
+		}
+		bindings = append(bindings, synthList(spec, spec.First(), spec.Nth(1)))
+		if spec.Len() == 3 {
+			recur = append(recur, spec.Nth(2))
+		} else {
+			recur = append(recur, spec.First())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result Node
+	if exit.Len() == 1 {
+		result = synthVoid(exit)
+	} else {
+		result = synthBody(exit, exit.Rest())
+	}
+	commands := append(listNodes(n.Rest().Rest().Rest()), synthList(n, recur...))
+	body := synthBody(n, synthList(n, commands...))
+
+	return synthList(n,
+		synthIdentifier("let", n),
+		loop,
+		synthList(n, bindings...),
+		synthIf(n, exit.First(), result, body)), nil
+}
+
+// (case-lambda (formals body ...) ...) becomes a variadic lambda which
+// applies the first clause taking that many args
+func expandCaseLambda(n *NodeList) (Node, error) {
+	args := gensym("args", n)
+	count := gensym("count", n)
+	msg := NewNodeString("No case-lambda clause matches the args")
+	msg.SetSpan(n.Span())
+	var dispatch Node = synthList(n, synthBuiltin("error", n), msg)
+
+	clauses := listNodes(n.Rest())
+	for i := len(clauses) - 1; i >= 0; i-- {
+		clause, ok := clauses[i].(*NodeList)
+		if !ok || clause.Len() < 2 {
+			return nil, NodeErrorf(n, "Bad case-lambda expression - clause must have formals and a body")
+		}
+		elems, tail, ok := listParts(clause.First())
+		if !ok {
+			if _, isId := clause.First().(*NodeIdentifier); !isId {
+				return nil, NodeErrorf(clause, "Bad case-lambda expression - formals must be a list")
+			}
+			tail = clause.First()
+		}
+		op := "="
+		if tail != nil {
+			op = ">="
+		}
+		numArgs := NewNodeInt(int64(len(elems)))
+		setSpan(numArgs, clause.Span())
+		test := synthList(clause, synthBuiltin(op, clause), count, numArgs)
+		lambda := synthList(clause, append([]Node{synthIdentifier("lambda", clause)}, listNodes(clause)...)...)
+		call := synthList(clause, synthBuiltin("apply", clause), lambda, args)
+		dispatch = synthIf(clause, test, call, dispatch)
+	}
+
+	length := synthList(n, synthBuiltin("length", n), args)
+	return synthList(n, synthIdentifier("lambda", n), args, synthLet1(n, count, length, dispatch)), nil
+}
+
+// (guard (var clause ...) body ...) =>
+//
+//	(%guard (lambda () body ...) (lambda (var) (cond clause ... (else (raise-continuable var)))))
+//
+// so the condition is raised again if no clause matches. That happens
+// in the dynamic environment of the guard, rather than of the raise.
+func expandGuard(n *NodeList) (Node, error) {
+	if n.Len() < 3 {
+		return nil, NodeErrorf(n, "Bad guard expression - missing clauses or body")
+	}
+	spec, ok := n.Nth(1).(*NodeList)
+	if !ok || spec.Len() == 0 {
+		return nil, NodeErrorf(n, "Bad guard expression - expected (var clause ...)")
+	}
+	id, ok := spec.First().(*NodeIdentifier)
+	if !ok {
+		return nil, NodeErrorf(spec, "Bad guard expression - invalid identifier")
+	}
+
+	clauses := listNodes(spec.Rest())
+	hasElse := false
+	if len(clauses) > 0 {
+		last, ok := clauses[len(clauses)-1].(*NodeList)
+		hasElse = ok && last.Len() > 0 && isIdentifier(last.First(), "else")
+	}
+	if !hasElse {
+		reraise := synthList(spec, synthBuiltin("raise-continuable", spec), id)
+		clauses = append(clauses, synthList(spec, synthIdentifier("else", spec), reraise))
+	}
+	cond := synthList(spec, append([]Node{synthIdentifier("cond", spec)}, clauses...)...)
+	handler := synthList(spec, synthIdentifier("lambda", spec), synthList(spec, id), cond)
+	thunk := synthList(n, append([]Node{synthIdentifier("lambda", n), synthList(n)}, listNodes(n.Rest().Rest())...)...)
+	return synthList(n, synthIdentifier("%guard", n), thunk, handler), nil
+}
+
+// valuesFormals checks the formals of a multiple values binding, which
+// are like lambda formals, and splits them as listParts does
+func valuesFormals(source Node, formals Node) ([]Node, Node, error) {
+	if _, ok := formals.(*NodeIdentifier); ok {
+		return nil, formals, nil
+	}
+	elems, tail, ok := listParts(formals)
+	if !ok {
+		return nil, nil, NodeErrorf(source, "Bad formals - must be a list or an identifier")
+	}
+	for _, elem := range elems {
+		if _, ok := elem.(*NodeIdentifier); !ok {
+			return nil, nil, NodeErrorf(source, "Bad formals - invalid identifier")
+		}
+	}
+	if _, ok := tail.(*NodeIdentifier); tail != nil && !ok {
+		return nil, nil, NodeErrorf(source, "Bad formals - invalid identifier")
+	}
+	return elems, tail, nil
+}
+
+// synthCallWithValues applies (lambda formals body ...) to the values
+// of init
+func synthCallWithValues(source Node, init Node, formals Node, body ...Node) *NodeList {
+	thunk := synthList(source, synthIdentifier("lambda", source), synthList(source), init)
+	consumer := synthList(source, append([]Node{synthIdentifier("lambda", source), formals}, body...)...)
+	return synthList(source, synthBuiltin("call-with-values", source), thunk, consumer)
+}
+
+// (let-values (((a b) init1) (c init2)) body ...) =>
+//
+//	(call-with-values (lambda () init1)
+//	  (lambda (t1 t2)
+//	    (call-with-values (lambda () init2)
+//	      (lambda t3
+//	        (let ((a t1) (b t2) (c t3)) body ...)))))
+//
+// The temporaries keep each init from seeing the other bindings.
+func expandLetValues(n *NodeList) (Node, error) {
+	if n.Len() < 3 {
+		return nil, NodeErrorf(n, "Bad let-values expression - missing bindings or body")
+	}
+	bindings, ok := n.Nth(1).(*NodeList)
+	if !ok {
+		return nil, NodeErrorf(n, "Bad let-values expression - bindings must be a list")
+	}
+
+	var inits, tmpFormals, letBindings []Node
+	err := bindings.Foreach(func(bindingNode Node) error {
+		binding, ok := bindingNode.(*NodeList)
+		if !ok || binding.Len() != 2 {
+			return NodeErrorf(n, "Bad let-values expression - bindings must be (formals init)")
+		}
+		elems, tail, err := valuesFormals(binding, binding.First())
+		if err != nil {
+			return err
+		}
+		tmps := make([]Node, len(elems))
+		for i, elem := range elems {
+			tmps[i] = gensym("lv", binding)
+			letBindings = append(letBindings, synthList(binding, elem, tmps[i]))
+		}
+		var tmpTail Node
+		if tail != nil {
+			tmpTail = gensym("lv", binding)
+			letBindings = append(letBindings, synthList(binding, tail, tmpTail))
+		}
+		inits = append(inits, binding.Nth(1))
+		tmpFormals = append(tmpFormals, buildList(binding.First(), tmps, tmpTail))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var body Node = synthList(n, append([]Node{synthIdentifier("let", n), synthList(n, letBindings...)}, listNodes(n.Rest().Rest())...)...)
+	for i := len(inits) - 1; i >= 0; i-- {
+		body = synthCallWithValues(n, inits[i], tmpFormals[i], body)
+	}
+	return body, nil
+}
+
+// (let*-values () body ...) => (let () body ...)
+// (let*-values ((formals init)) body ...) =>
+//
+//	(call-with-values (lambda () init) (lambda formals body ...))
+//
+// (let*-values ((formals init) b ...) body ...) =>
+//
+//	(call-with-values (lambda () init) (lambda formals (let*-values (b ...) body ...)))
+func expandLetStarValues(n *NodeList) (Node, error) {
+	if n.Len() < 3 {
+		return nil, NodeErrorf(n, "Bad let*-values expression - missing bindings or body")
+	}
+	bindings, ok := n.Nth(1).(*NodeList)
+	if !ok {
+		return nil, NodeErrorf(n, "Bad let*-values expression - bindings must be a list")
+	}
+	body := listNodes(n.Rest().Rest())
+	if bindings.Len() == 0 {
+		return synthList(n, append([]Node{synthIdentifier("let", n), bindings}, body...)...), nil
+	}
+	binding, ok := bindings.First().(*NodeList)
+	if !ok || binding.Len() != 2 {
+		return nil, NodeErrorf(n, "Bad let*-values expression - bindings must be (formals init)")
+	}
+	if _, _, err := valuesFormals(binding, binding.First()); err != nil {
+		return nil, err
+	}
+	if bindings.Len() > 1 {
+		body = []Node{synthList(n, append([]Node{n.First(), bindings.Rest()}, body...)...)}
+	}
+	return synthCallWithValues(binding, binding.Nth(1), binding.First(), body...), nil
+}
+
+// (receive formals expr body ...) =>
+//
+//	(call-with-values (lambda () expr) (lambda formals body ...))
+func expandReceive(n *NodeList) (Node, error) {
+	if n.Len() < 4 {
+		return nil, NodeErrorf(n, "Bad receive expression - expected formals, an expression and a body")
+	}
+	if _, _, err := valuesFormals(n, n.Nth(1)); err != nil {
+		return nil, err
+	}
+	return synthCallWithValues(n, n.Nth(2), n.Nth(1), listNodes(n.Rest().Rest().Rest())...), nil
+}
+
+// (define-values (a b) expr) =>
+//
+//	(begin
+//	  (define a #f)
+//	  (define b #f)
+//	  (call-with-values (lambda () expr)
+//	    (lambda (t1 t2) (set! a t1) (set! b t2) (void))))
+//
+// which is only for the top level. transformBody handles a
+// define-values in a body with expandBodyDefineValues.
+func expandDefineValues(n *NodeList) (Node, error) {
+	if n.Len() != 3 {
+		return nil, NodeErrorf(n, "Bad define-values expression - expected formals and an expression")
+	}
+	elems, tail, err := valuesFormals(n, n.Nth(1))
+	if err != nil {
+		return nil, err
+	}
+	ids := elems
+	if tail != nil {
+		ids = append(ids, tail)
+	}
+
+	forms := []Node{synthIdentifier("begin", n)}
+	var sets []Node
+	tmps := make([]Node, len(ids))
+	for i, id := range ids {
+		tmps[i] = gensym("dv", n)
+		forms = append(forms, synthList(n, synthIdentifier("define", n), id, synthBool(false, n)))
+		sets = append(sets, synthList(n, synthIdentifier("set!", n), id, tmps[i]))
+	}
+	var tmpTail Node
+	if tail != nil {
+		tmpTail = tmps[len(tmps)-1]
+	}
+	tmpFormals := buildList(n.Nth(1), tmps[:len(elems)], tmpTail)
+	sets = append(sets, synthVoid(n))
+	forms = append(forms, synthCallWithValues(n, n.Nth(2), tmpFormals, sets...))
+	return synthList(n, forms...), nil
+}
+
+// expandBodyDefineValues puts the rest of a body in the scope of a
+// define-values within it:
+//
+//	(define-values formals expr) rest ... =>
+//
+//	(call-with-values (lambda () expr) (lambda formals rest ...))
+//
+// Unlike a letrec*, expr can't see the defines which follow it.
+func expandBodyDefineValues(n *NodeList, rest *NodeList) (Node, error) {
+	if n.Len() != 3 {
+		return nil, NodeErrorf(n, "Bad define-values expression - expected formals and an expression")
+	}
+	if _, _, err := valuesFormals(n, n.Nth(1)); err != nil {
+		return nil, err
+	}
+	if rest.Len() == 0 {
+		return nil, NodeErrorf(n, "Bad body - no expression after internal defines")
+	}
+	return synthCallWithValues(n, n.Nth(2), n.Nth(1), listNodes(rest)...), nil
+}
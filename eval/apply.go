@@ -1,31 +1,55 @@
 package eval
 
 import (
-	"fmt"
-
 	"github.com/jbert/gol"
 )
 
 func MakeDefaultEnvironment() Environment {
-	defEnv := []gol.Frame{
-		gol.Frame{
-			"=":       &NodeBuiltin{f: equalInt, description: "="},
-			"+":       &NodeBuiltin{f: addInt, description: "+"},
-			"-":       &NodeBuiltin{f: subInt, description: "-"},
-			"*":       &NodeBuiltin{f: mulInt, description: "*"},
-			"display": &NodeBuiltin{f: display, description: "display"},
-			"list":    &NodeBuiltin{f: list, description: "list"},
-			"length":  &NodeBuiltin{f: length, description: "length"},
-			"reverse": &NodeBuiltin{f: reverse, description: "reverse"},
-			"append":  &NodeBuiltin{f: listAppend, description: "append"},
-			"apply":   &NodeBuiltin{f: apply, description: "apply"},
-			"zero?":   &NodeBuiltin{f: zerop, description: "zerop"},
-			"void":    &NodeBuiltin{f: void, description: "void"},
-		},
+	// Registered functions first, so that the builtins hide any of the
+	// same name which were registered before we added ours
+	defEnv := []gol.Frame{registeredBuiltins()}
+	for k, v := range builtinFrame() {
+		defEnv[0][k] = v
 	}
 	return defEnv
 }
 
+// builtinFrame has all the builtins, other than registered functions
+func builtinFrame() gol.Frame {
+	builtins := gol.Frame{
+		"display": &NodeBuiltin{f: display, description: "display"},
+		"list":    &NodeBuiltin{f: list, description: "list"},
+		"length":  &NodeBuiltin{f: length, description: "length"},
+		"reverse": &NodeBuiltin{f: reverse, description: "reverse"},
+		"append":  &NodeBuiltin{f: listAppend, description: "append"},
+		"apply":   &nodeControl{f: apply, description: "apply"},
+		"void":    &NodeBuiltin{f: void, description: "void"},
+	}
+	for _, f := range []gol.Frame{
+		charBuiltins(),
+		numberBuiltins(),
+		pairBuiltins(),
+		vectorBuiltins(),
+		stringBuiltins(),
+		equivBuiltins(),
+		macroBuiltins(),
+		controlBuiltins(),
+		exceptionBuiltins(),
+		valuesBuiltins(),
+		portBuiltins(),
+		readBuiltins(),
+		environmentBuiltins(),
+	} {
+		for k, v := range f {
+			builtins[k] = v
+		}
+	}
+	for _, name := range gol.DerivedBuiltins {
+		builtins["%"+name] = builtins[name]
+	}
+	return builtins
+}
+
 type NodeApplicable interface {
 	gol.Node
 	Apply(e *Evaluator, nodes *gol.NodeList) (gol.Node, error)
@@ -49,104 +73,17 @@ func (nb NodeBuiltin) Apply(e *Evaluator, args *gol.NodeList) (gol.Node, error)
 	return nb.f(e, args)
 }
 
-func equalInt(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
-	if nodes.Len() < 2 {
-		return nil, gol.NodeErrorf(nodes, "At least two arguments required")
-	}
-	first, ok := nodes.First().(*gol.NodeInt)
-	if !ok {
-		return nil, gol.NodeErrorf(nodes, "Non-int passed to equalInt")
-	}
-	ret := gol.NODE_TRUE
-
-	rest := nodes.Rest()
-	err := rest.Foreach(func(n gol.Node) error {
-		ni, ok := n.(*gol.NodeInt)
-		if !ok {
-			return gol.NodeErrorf(nodes, "Non-int passed to equalInt")
-		}
-		if first.Value() != ni.Value() {
-			ret = gol.NODE_FALSE
-		}
-		return nil
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	return ret, nil
-}
-
-func addInt(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
-	var sum int64
-	err := nodes.Foreach(func(n gol.Node) error {
-		ni, ok := n.(*gol.NodeInt)
-		if !ok {
-			return gol.NodeErrorf(nodes, "Non-int passed to addInt")
-		}
-		sum += ni.Value()
-		return nil
-	})
-	if err != nil {
-		return nil, err
-	}
-	return gol.NewNodeInt(sum), nil
-}
-
-func mulInt(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
-	var prod int64
-	prod = 1
-	err := nodes.Foreach(func(n gol.Node) error {
-		ni, ok := n.(*gol.NodeInt)
-		if !ok {
-			return gol.NodeErrorf(nodes, "Non-int passed to addInt")
-		}
-		prod *= ni.Value()
-		return nil
-	})
-	if err != nil {
-		return nil, err
-	}
-	return gol.NewNodeInt(prod), nil
-}
-
-func subInt(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
-	if nodes.Len() == 0 {
-		return nil, gol.NodeErrorf(nodes, "Arity-error: expected > 0 args")
-	}
-
-	ni, ok := nodes.First().(*gol.NodeInt)
-	if !ok {
-		return nil, gol.NodeErrorf(nodes, "Non-int passed to subInt")
-	}
-	result := ni.Value()
-	if nodes.Len() == 1 {
-		return gol.NewNodeInt(-result), nil
+func display(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() < 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected >= 1 args")
 	}
-
-	rest := nodes.Rest()
-	err := rest.Foreach(func(n gol.Node) error {
-		ni, ok := n.(*gol.NodeInt)
-		if !ok {
-			return gol.NodeErrorf(nodes, "Non-int passed to subInt")
-		}
-		result -= ni.Value()
-		return nil
-	})
+	port, err := outputPortArg(e, nodes, 1, "display")
 	if err != nil {
 		return nil, err
 	}
-	return gol.NewNodeInt(result), nil
-}
-
-func display(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
-	if nodes.Len() != 1 {
-		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
-	}
 
 	s := nodes.First().String()
-	fmt.Fprintf(e.out, "%s", s)
-	return gol.Nil(), nil
+	return gol.Nil(), writeTo(nodes, port, s)
 }
 
 func list(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
@@ -202,34 +139,24 @@ func listAppend(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
 	return ret, nil
 }
 
-func apply(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+// apply is a control builtin, so that a call through it in tail
+// position is still a tail call
+func apply(e *Evaluator, nodes *gol.NodeList, k *continuation) (step, error) {
+	if nodes.Len() == 0 {
+		return step{}, gol.NodeErrorf(nodes, "Arity-error: expected >= 1 args")
+	}
 	// Last should be a list, we
 	args := nodes.ReverseCopy()
 	l, ok := args.First().(*gol.NodeList)
 	if !ok {
-		return nil, gol.NodeErrorf(nodes, "Non-list passed as last arg to apply")
+		return step{}, gol.NodeErrorf(nodes, "Non-list passed as last arg to apply")
 	}
 	args.Rest().Foreach(func(child gol.Node) error {
 		l = l.Cons(child)
 		return nil
 	})
 
-	return e.Apply(l)
-}
-
-func zerop(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
-	if nodes.Len() != 1 {
-		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
-	}
-	ni, ok := nodes.First().(*gol.NodeInt)
-	if !ok {
-		return nil, gol.NodeErrorf(nodes, "Non-int passed to zero?")
-	}
-	if ni.Value() == 0 {
-		return gol.NODE_TRUE, nil
-	} else {
-		return gol.NODE_FALSE, nil
-	}
+	return step{apply: l, k: k}, nil
 }
 
 func void(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
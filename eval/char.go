@@ -0,0 +1,148 @@
+package eval
+
+import (
+	"unicode"
+
+	"github.com/jbert/gol"
+)
+
+func charBuiltins() gol.Frame {
+	return gol.Frame{
+		"char?":            &NodeBuiltin{f: charp, description: "char?"},
+		"char->integer":    &NodeBuiltin{f: charToInteger, description: "char->integer"},
+		"integer->char":    &NodeBuiltin{f: integerToChar, description: "integer->char"},
+		"char-upcase":      &NodeBuiltin{f: charMapper(unicode.ToUpper), description: "char-upcase"},
+		"char-downcase":    &NodeBuiltin{f: charMapper(unicode.ToLower), description: "char-downcase"},
+		"char-foldcase":    &NodeBuiltin{f: charMapper(foldCase), description: "char-foldcase"},
+		"char-alphabetic?": &NodeBuiltin{f: charPredicate(unicode.IsLetter), description: "char-alphabetic?"},
+		"char-numeric?":    &NodeBuiltin{f: charPredicate(unicode.IsDigit), description: "char-numeric?"},
+		"char-whitespace?": &NodeBuiltin{f: charPredicate(unicode.IsSpace), description: "char-whitespace?"},
+		"char-upper-case?": &NodeBuiltin{f: charPredicate(unicode.IsUpper), description: "char-upper-case?"},
+		"char-lower-case?": &NodeBuiltin{f: charPredicate(unicode.IsLower), description: "char-lower-case?"},
+		"digit-value":      &NodeBuiltin{f: digitValue, description: "digit-value"},
+
+		"char=?":  &NodeBuiltin{f: charCompare(charEq, false), description: "char=?"},
+		"char<?":  &NodeBuiltin{f: charCompare(charLt, false), description: "char<?"},
+		"char>?":  &NodeBuiltin{f: charCompare(charGt, false), description: "char>?"},
+		"char<=?": &NodeBuiltin{f: charCompare(charLe, false), description: "char<=?"},
+		"char>=?": &NodeBuiltin{f: charCompare(charGe, false), description: "char>=?"},
+
+		"char-ci=?":  &NodeBuiltin{f: charCompare(charEq, true), description: "char-ci=?"},
+		"char-ci<?":  &NodeBuiltin{f: charCompare(charLt, true), description: "char-ci<?"},
+		"char-ci>?":  &NodeBuiltin{f: charCompare(charGt, true), description: "char-ci>?"},
+		"char-ci<=?": &NodeBuiltin{f: charCompare(charLe, true), description: "char-ci<=?"},
+		"char-ci>=?": &NodeBuiltin{f: charCompare(charGe, true), description: "char-ci>=?"},
+	}
+}
+
+func foldCase(r rune) rune {
+	return unicode.ToLower(unicode.ToUpper(r))
+}
+
+func charEq(a, b rune) bool { return a == b }
+func charLt(a, b rune) bool { return a < b }
+func charGt(a, b rune) bool { return a > b }
+func charLe(a, b rune) bool { return a <= b }
+func charGe(a, b rune) bool { return a >= b }
+
+func singleChar(nodes *gol.NodeList) (rune, error) {
+	if nodes.Len() != 1 {
+		return 0, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	nc, ok := nodes.First().(*gol.NodeChar)
+	if !ok {
+		return 0, gol.NodeErrorf(nodes, "Non-char passed to char function")
+	}
+	return nc.Value(), nil
+}
+
+func charp(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	_, ok := nodes.First().(*gol.NodeChar)
+	return gol.NewNodeBool(ok), nil
+}
+
+func charToInteger(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	r, err := singleChar(nodes)
+	if err != nil {
+		return nil, err
+	}
+	return gol.NewNodeInt(int64(r)), nil
+}
+
+func integerToChar(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	ni, ok := nodes.First().(*gol.NodeInt)
+	if !ok {
+		return nil, gol.NodeErrorf(nodes, "Non-int passed to integer->char")
+	}
+	if ni.IsBig() || ni.Value() < 0 || ni.Value() > unicode.MaxRune {
+		return nil, gol.NodeErrorf(nodes, "Integer out of range for integer->char: %s", ni)
+	}
+	return gol.NewNodeChar(rune(ni.Value())), nil
+}
+
+func digitValue(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	r, err := singleChar(nodes)
+	if err != nil {
+		return nil, err
+	}
+	if r < '0' || r > '9' {
+		return gol.NODE_FALSE, nil
+	}
+	return gol.NewNodeInt(int64(r - '0')), nil
+}
+
+func charMapper(f func(rune) rune) func(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	return func(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+		r, err := singleChar(nodes)
+		if err != nil {
+			return nil, err
+		}
+		return gol.NewNodeChar(f(r)), nil
+	}
+}
+
+func charPredicate(f func(rune) bool) func(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	return func(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+		r, err := singleChar(nodes)
+		if err != nil {
+			return nil, err
+		}
+		return gol.NewNodeBool(f(r)), nil
+	}
+}
+
+// charCompare checks that f holds between each adjacent pair of args
+func charCompare(f func(a, b rune) bool, ci bool) func(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	return func(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+		if nodes.Len() < 2 {
+			return nil, gol.NodeErrorf(nodes, "At least two arguments required")
+		}
+		ret := gol.NODE_TRUE
+		var prev *rune
+		err := nodes.Foreach(func(n gol.Node) error {
+			nc, ok := n.(*gol.NodeChar)
+			if !ok {
+				return gol.NodeErrorf(nodes, "Non-char passed to char comparison")
+			}
+			r := nc.Value()
+			if ci {
+				r = foldCase(r)
+			}
+			if prev != nil && !f(*prev, r) {
+				ret = gol.NODE_FALSE
+			}
+			prev = &r
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return ret, nil
+	}
+}
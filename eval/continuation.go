@@ -0,0 +1,168 @@
+package eval
+
+import (
+	"github.com/jbert/gol"
+)
+
+func controlBuiltins() gol.Frame {
+	return gol.Frame{
+		"call/cc":                        &nodeControl{f: callCC, description: "call/cc"},
+		"call-with-current-continuation": &nodeControl{f: callCC, description: "call-with-current-continuation"},
+		"dynamic-wind":                   &nodeControl{f: dynamicWind, description: "dynamic-wind"},
+	}
+}
+
+// nodeControl is a builtin which is given the continuation of its call,
+// rather than returning a value
+type nodeControl struct {
+	gol.NodeBase
+	f           func(e *Evaluator, nodes *gol.NodeList, k *continuation) (step, error)
+	description string
+}
+
+func (nc *nodeControl) Pos() gol.Position {
+	return gol.Position{File: "<builtin>"}
+}
+
+func (nc *nodeControl) String() string {
+	return nc.description
+}
+
+func (nc *nodeControl) Apply(e *Evaluator, args *gol.NodeList) (gol.Node, error) {
+	return e.Apply(args.Cons(nc))
+}
+
+// NodeContinuation is a continuation captured by call/cc, as a
+// procedure of one argument
+type NodeContinuation struct {
+	gol.NodeBase
+	k        *continuation
+	winders  *winder
+	handlers *handler
+	run      *runMark
+}
+
+func (nc *NodeContinuation) Pos() gol.Position {
+	return gol.Position{File: "<builtin>"}
+}
+
+func (nc *NodeContinuation) String() string {
+	return "#<continuation>"
+}
+
+func (nc *NodeContinuation) Apply(e *Evaluator, args *gol.NodeList) (gol.Node, error) {
+	if nc.run.done {
+		return nil, gol.NodeErrorf(args, "Continuation invoked after the evaluation which captured it finished")
+	}
+	var value gol.Node
+	switch args.Len() {
+	case 0:
+		value = gol.Nil()
+	case 1:
+		value = args.First()
+	default:
+		return nil, gol.NodeErrorf(args, "Arity-error: expected <= 1 args")
+	}
+	// Unwinds the Go stack back to the run which captured it
+	return nil, &escape{c: nc, value: value}
+}
+
+// escape is returned as an error by an invoked continuation, so that
+// builtins which call back into the evaluator let it through
+type escape struct {
+	c     *NodeContinuation
+	value gol.Node
+}
+
+func (esc *escape) Error() string {
+	return "Continuation invoked outside the evaluation which captured it"
+}
+
+func callCC(e *Evaluator, nodes *gol.NodeList, k *continuation) (step, error) {
+	if nodes.Len() != 1 {
+		return step{}, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	c := &NodeContinuation{k: k, winders: e.winders, handlers: e.handlers, run: e.run}
+	return step{apply: gol.NewNodeList().Cons(c).Cons(nodes.First()), k: k}, nil
+}
+
+// winder is a dynamic-wind extent we are inside, innermost first
+type winder struct {
+	before, after gol.Node
+	depth         int
+	next          *winder
+}
+
+// depthOrNil is the depth of w, or -1 outside any extent
+func (w *winder) depthOrNil() int {
+	if w == nil {
+		return -1
+	}
+	return w.depth
+}
+
+func dynamicWind(e *Evaluator, nodes *gol.NodeList, k *continuation) (step, error) {
+	if nodes.Len() != 3 {
+		return step{}, gol.NodeErrorf(nodes, "Arity-error: expected == 3 args")
+	}
+	before, thunk, after := nodes.First(), nodes.Nth(1), nodes.Nth(2)
+
+	_, err := e.Apply(gol.NewNodeList().Cons(before))
+	if err != nil {
+		return step{}, err
+	}
+	w := &winder{before: before, after: after, next: e.winders}
+	if w.next != nil {
+		w.depth = w.next.depth + 1
+	}
+	e.winders = w
+
+	k = push(k, func(e *Evaluator, value gol.Node, k *continuation) (step, error) {
+		e.winders = w.next
+		_, err := e.Apply(gol.NewNodeList().Cons(after))
+		if err != nil {
+			return step{}, err
+		}
+		return step{value: value, k: k}, nil
+	})
+	return step{apply: gol.NewNodeList().Cons(thunk), k: k}, nil
+}
+
+// rewind leaves the extents we are in which to isn't, innermost first,
+// then enters the extents to is in which we aren't, outermost first
+func (e *Evaluator) rewind(to *winder) error {
+	// Find the innermost extent we are in on both sides
+	common, other := to, e.winders
+	for common.depthOrNil() > other.depthOrNil() {
+		common = common.next
+	}
+	for other.depthOrNil() > common.depthOrNil() {
+		other = other.next
+	}
+	for common != other {
+		common, other = common.next, other.next
+	}
+
+	for e.winders != common {
+		w := e.winders
+		e.winders = w.next
+		_, err := e.Apply(gol.NewNodeList().Cons(w.after))
+		if err != nil {
+			return err
+		}
+	}
+
+	var entering []*winder
+	for w := to; w != common; w = w.next {
+		entering = append(entering, w)
+	}
+	for i := len(entering) - 1; i >= 0; i-- {
+		w := entering[i]
+		_, err := e.Apply(gol.NewNodeList().Cons(w.before))
+		if err != nil {
+			return err
+		}
+		e.winders = w
+	}
+	return nil
+}
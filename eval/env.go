@@ -48,3 +48,17 @@ type NodeProcedure struct {
 	*gol.NodeLambda
 	Env Environment
 }
+
+// nodeUnassigned is the value of a letrec binding until its init has
+// been evaluated
+type nodeUnassigned struct {
+	gol.NodeBase
+}
+
+func (nu *nodeUnassigned) String() string {
+	return "#<unassigned>"
+}
+
+func (nu *nodeUnassigned) Pos() gol.Position {
+	return gol.Position{File: "<builtin>"}
+}
@@ -0,0 +1,211 @@
+package eval
+
+import (
+	"strings"
+
+	"github.com/jbert/gol"
+)
+
+func environmentBuiltins() gol.Frame {
+	return gol.Frame{
+		"eval":                      &nodeControl{f: evalDatum, description: "eval"},
+		"interaction-environment":   &NodeBuiltin{f: interactionEnvironment, description: "interaction-environment"},
+		"scheme-report-environment": &NodeBuiltin{f: schemeReportEnvironment, description: "scheme-report-environment"},
+		"environment":               &NodeBuiltin{f: environment, description: "environment"},
+		"environment?":              &NodeBuiltin{f: isEnvironment, description: "environment?"},
+	}
+}
+
+// NodeEnvironment is an Environment as a value, for eval
+type NodeEnvironment struct {
+	gol.NodeBase
+	Env Environment
+}
+
+func (ne *NodeEnvironment) Pos() gol.Position {
+	return gol.Position{File: "<environment>"}
+}
+
+func (ne *NodeEnvironment) String() string {
+	return "#<environment>"
+}
+
+// topLevel is the environment of the program, in which its defines are
+// made
+func (e *Evaluator) topLevel() Environment {
+	return e.Env[len(e.Env)-1:]
+}
+
+// standardEnvironment is a new environment with the builtins and the
+// standard library, and nothing the program has defined
+func standardEnvironment(e *Evaluator) (Environment, error) {
+	env := MakeDefaultEnvironment()
+	_, err := evalReader(e.withEnv(env), "<stdlib>", strings.NewReader(gol.STDLIB))
+	return env, err
+}
+
+// evalDatum evaluates a datum in an environment, which defaults to the
+// interaction environment, as a tail call
+func evalDatum(e *Evaluator, nodes *gol.NodeList, k *continuation) (step, error) {
+	if nodes.Len() < 1 || nodes.Len() > 2 {
+		return step{}, gol.NodeErrorf(nodes, "Arity-error: expected 1 or 2 args")
+	}
+	env := e.topLevel()
+	if nodes.Len() == 2 {
+		ne, ok := nodes.Nth(1).(*NodeEnvironment)
+		if !ok {
+			return step{}, gol.NodeErrorf(nodes, "Non-environment passed to eval")
+		}
+		env = ne.Env
+	}
+	tree, err := gol.TransformDatum(nodes.First(), newMacroEvaluator(e.withEnv(env)))
+	if err != nil {
+		return step{}, err
+	}
+	return step{node: tree, env: env, k: k}, nil
+}
+
+func interactionEnvironment(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 0 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 0 args")
+	}
+	return &NodeEnvironment{Env: e.topLevel()}, nil
+}
+
+func schemeReportEnvironment(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	version, ok := nodes.First().(*gol.NodeInt)
+	if !ok || version.String() != "5" {
+		return nil, gol.NodeErrorf(nodes, "Unsupported version passed to scheme-report-environment: %s", nodes.First())
+	}
+	env, err := standardEnvironment(e)
+	if err != nil {
+		return nil, err
+	}
+	return &NodeEnvironment{Env: env}, nil
+}
+
+// standardLibraries are the libraries which environment can import. We
+// don't split the builtins up, so each has all of them.
+var standardLibraries = map[string]bool{
+	"(scheme base)":    true,
+	"(scheme char)":    true,
+	"(scheme cxr)":     true,
+	"(scheme eval)":    true,
+	"(scheme file)":    true,
+	"(scheme inexact)": true,
+	"(scheme read)":    true,
+	"(scheme write)":   true,
+	"(scheme r5rs)":    true,
+}
+
+// environment is a new environment with the bindings of the import sets
+func environment(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	var std gol.Frame
+	f := gol.Frame{}
+	err := nodes.Foreach(func(spec gol.Node) error {
+		imported, err := importSet(e, nodes, spec, &std)
+		if err != nil {
+			return err
+		}
+		for name, value := range imported {
+			f[name] = value
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &NodeEnvironment{Env: Environment{f}}, nil
+}
+
+// importSet is the bindings of an import set: a library name, or only,
+// except, prefix or rename applied to an import set. std is the
+// standard environment, which is only made if a library is imported.
+func importSet(e *Evaluator, nodes *gol.NodeList, spec gol.Node, std *gol.Frame) (gol.Frame, error) {
+	nl, ok := spec.(*gol.NodeList)
+	if !ok || nl.Len() < 2 {
+		return nil, gol.NodeErrorf(nodes, "Bad import set passed to environment: %s", spec)
+	}
+	switch nl.First().String() {
+	case "only", "except":
+		f, err := importSet(e, nodes, nl.Nth(1), std)
+		if err != nil {
+			return nil, err
+		}
+		ids := map[string]bool{}
+		nl.Rest().Rest().Foreach(func(n gol.Node) error {
+			ids[n.String()] = true
+			return nil
+		})
+		only := nl.First().String() == "only"
+		for name := range f {
+			if ids[name] != only {
+				delete(f, name)
+			}
+		}
+		return f, nil
+	case "prefix":
+		if nl.Len() != 3 {
+			return nil, gol.NodeErrorf(nodes, "Bad import set passed to environment: %s", spec)
+		}
+		f, err := importSet(e, nodes, nl.Nth(1), std)
+		if err != nil {
+			return nil, err
+		}
+		prefixed := gol.Frame{}
+		for name, value := range f {
+			prefixed[nl.Nth(2).String()+name] = value
+		}
+		return prefixed, nil
+	case "rename":
+		f, err := importSet(e, nodes, nl.Nth(1), std)
+		if err != nil {
+			return nil, err
+		}
+		renamed := gol.Frame{}
+		for name, value := range f {
+			renamed[name] = value
+		}
+		err = nl.Rest().Rest().Foreach(func(n gol.Node) error {
+			pair, ok := n.(*gol.NodeList)
+			if !ok || pair.Len() != 2 {
+				return gol.NodeErrorf(nodes, "Bad rename passed to environment: %s", n)
+			}
+			value, ok := f[pair.First().String()]
+			if !ok {
+				return gol.NodeErrorf(nodes, "Can't rename [%s], it isn't imported", pair.First())
+			}
+			delete(renamed, pair.First().String())
+			renamed[pair.Nth(1).String()] = value
+			return nil
+		})
+		return renamed, err
+	}
+
+	if !standardLibraries[nl.String()] {
+		return nil, gol.NodeErrorf(nodes, "Unknown library passed to environment: %s", spec)
+	}
+	if *std == nil {
+		env, err := standardEnvironment(e)
+		if err != nil {
+			return nil, err
+		}
+		*std = env[0]
+	}
+	f := gol.Frame{}
+	for name, value := range *std {
+		f[name] = value
+	}
+	return f, nil
+}
+
+func isEnvironment(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	_, ok := nodes.First().(*NodeEnvironment)
+	return gol.NewNodeBool(ok), nil
+}
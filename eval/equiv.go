@@ -0,0 +1,93 @@
+package eval
+
+import (
+	"github.com/jbert/gol"
+)
+
+func equivBuiltins() gol.Frame {
+	return gol.Frame{
+		"eqv?":   &NodeBuiltin{f: equivalence(isEqv), description: "eqv?"},
+		"eq?":    &NodeBuiltin{f: equivalence(isEqv), description: "eq?"},
+		"equal?": &NodeBuiltin{f: equivalence(isEqual), description: "equal?"},
+		"not":    &NodeBuiltin{f: not, description: "not"},
+	}
+}
+
+func equivalence(f func(a, b gol.Node) bool) func(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	return func(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+		if nodes.Len() != 2 {
+			return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 2 args")
+		}
+		return gol.NewNodeBool(f(nodes.First(), nodes.Nth(1))), nil
+	}
+}
+
+// isEqv compares atoms by value and everything else by identity
+func isEqv(a, b gol.Node) bool {
+	switch av := a.(type) {
+	case *gol.NodeInt:
+		bv, ok := b.(*gol.NodeInt)
+		return ok && av.BigValue().Cmp(bv.BigValue()) == 0
+	case *gol.NodeReal:
+		bv, ok := b.(*gol.NodeReal)
+		return ok && av.Value() == bv.Value()
+	case *gol.NodeRational:
+		bv, ok := b.(*gol.NodeRational)
+		return ok && av.Value().Cmp(bv.Value()) == 0
+	case *gol.NodeChar:
+		bv, ok := b.(*gol.NodeChar)
+		return ok && av.Value() == bv.Value()
+	case *gol.NodeBool:
+		bv, ok := b.(*gol.NodeBool)
+		return ok && av.IsTrue() == bv.IsTrue()
+	case *gol.NodeIdentifier, *gol.NodeSymbol:
+		switch b.(type) {
+		case *gol.NodeIdentifier, *gol.NodeSymbol:
+			return a.String() == b.String()
+		}
+		return false
+	}
+	if gol.IsNull(a) || gol.IsNull(b) {
+		return gol.IsNull(a) && gol.IsNull(b)
+	}
+	if gol.IsPair(a) {
+		return gol.IsSamePair(a, b)
+	}
+	return a == b
+}
+
+// isEqual recurses into pairs and vectors, and compares strings by value
+func isEqual(a, b gol.Node) bool {
+	if gol.IsPair(a) && gol.IsPair(b) {
+		aCar, _ := gol.Car(a)
+		bCar, _ := gol.Car(b)
+		aCdr, _ := gol.Cdr(a)
+		bCdr, _ := gol.Cdr(b)
+		return isEqual(aCar, bCar) && isEqual(aCdr, bCdr)
+	}
+	switch av := a.(type) {
+	case *gol.NodeString:
+		bv, ok := b.(*gol.NodeString)
+		return ok && av.Value() == bv.Value()
+	case *gol.NodeVector:
+		bv, ok := b.(*gol.NodeVector)
+		if !ok || av.Len() != bv.Len() {
+			return false
+		}
+		for i := 0; i < av.Len(); i++ {
+			if !isEqual(av.Ref(i), bv.Ref(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	return isEqv(a, b)
+}
+
+func not(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	nb, ok := nodes.First().(*gol.NodeBool)
+	return gol.NewNodeBool(ok && !nb.IsTrue()), nil
+}
@@ -7,172 +7,342 @@ import (
 )
 
 type Evaluator struct {
-	Env     Environment
-	in      io.Reader
-	out     io.Writer
-	err     io.Writer
+	Env Environment
+	// The current ports
+	in      *NodePort
+	out     *NodePort
+	err     *NodePort
 	nesting int
+
+	// The run of the machine we are in, and the dynamic-wind extents
+	// and exception handlers we are inside, for continuations to capture
+	run      *runMark
+	winders  *winder
+	handlers *handler
 }
 
 func NewEvaluator(env Environment, out io.Writer, in io.Reader, err io.Writer) *Evaluator {
 	return &Evaluator{
 		Env: env,
-		in:  in,
-		out: out,
-		err: err,
+		in:  NewInputPort("stdin", in),
+		out: NewOutputPort("stdout", out),
+		err: NewOutputPort("stderr", err),
 	}
 }
 
+// withEnv is an evaluator for env which shares our ports
+func (e *Evaluator) withEnv(env Environment) *Evaluator {
+	return &Evaluator{Env: env, in: e.in, out: e.out, err: e.err}
+}
+
 func (e Evaluator) Quoting() bool {
 	return e.nesting > 0
 }
 
+// The evaluator is a machine with an explicit stack, rather than a
+// recursive walk over the tree, so that the rest of a computation is a
+// value which call/cc can capture. Each step either evaluates a node,
+// applies a procedure or returns a value to the continuation.
+
+// step is the state of the machine
+type step struct {
+	// Evaluate node in env...
+	node gol.Node
+	env  Environment
+	// ...or apply the head of apply to the rest...
+	apply *gol.NodeList
+	// ...or return value
+	value gol.Node
+	// and pass the result to k
+	k *continuation
+}
+
+// continuation is the rest of a computation, as a stack of frames each
+// waiting for a value. Frames are never modified once pushed, so a
+// continuation can be resumed any number of times.
+type continuation struct {
+	resume func(e *Evaluator, value gol.Node, k *continuation) (step, error)
+	next   *continuation
+}
+
+func push(k *continuation, resume func(e *Evaluator, value gol.Node, k *continuation) (step, error)) *continuation {
+	return &continuation{resume: resume, next: k}
+}
+
+// runMark identifies one run of the machine. The continuations it
+// captures end where it was started, so only it can resume them.
+type runMark struct {
+	done bool
+}
+
 func (e *Evaluator) Eval(node gol.Node) (gol.Node, error) {
+	if e.Quoting() {
+		return e.evalQuoted(node)
+	}
+	return e.runMachine(step{node: node, env: e.Env})
+}
+
+// Apply applies the head of nl to the rest
+func (e *Evaluator) Apply(nl *gol.NodeList) (gol.Node, error) {
+	return e.runMachine(step{apply: nl, env: e.Env})
+}
+
+func (e *Evaluator) runMachine(s step) (gol.Node, error) {
+	mark := &runMark{}
+	oldRun, oldEnv := e.run, e.Env
+	oldWinders, oldHandlers := e.winders, e.handlers
+	defer func() {
+		mark.done = true
+		e.run, e.Env = oldRun, oldEnv
+	}()
+	e.run = mark
+
+	for {
+		var err error
+		cur := s
+		switch {
+		case s.node != nil:
+			e.Env = s.env
+			s, err = e.evalStep(s.node, s.env, s.k)
+		case s.apply != nil:
+			s, err = e.applyStep(s.apply, s.k)
+		case s.k == nil:
+			return s.value, nil
+		default:
+			s, err = s.k.resume(e, s.value, s.k.next)
+		}
+		if err == nil {
+			continue
+		}
+		esc, ok := err.(*escape)
+		switch {
+		case ok && esc.c.run == mark:
+			// A continuation captured by this run was invoked
+			err = e.rewind(esc.c.winders)
+			if err != nil {
+				return nil, err
+			}
+			e.handlers = esc.c.handlers
+			s = step{value: esc.value, k: esc.c.k}
+		case ok:
+			// Leave it to the run which captured it
+			return nil, err
+		case e.handlers != nil:
+			// Failures are conditions, which handlers may catch
+			s, err = e.raiseStep(errorObjectFrom(err, cur.source()), false, cur.source(), cur.k)
+			if err != nil {
+				e.winders, e.handlers = oldWinders, oldHandlers
+				return nil, err
+			}
+		default:
+			e.winders, e.handlers = oldWinders, oldHandlers
+			return nil, err
+		}
+	}
+}
+
+// source is the node a failing step was working on
+func (s step) source() gol.Node {
+	switch {
+	case s.node != nil:
+		return s.node
+	case s.apply != nil:
+		return s.apply
+	default:
+		return s.value
+	}
+}
+
+func (e *Evaluator) evalStep(node gol.Node, env Environment, k *continuation) (step, error) {
 	switch n := node.(type) {
 	case *gol.NodeError:
-		return nil, n
+		return step{}, n
 	case *gol.NodeIdentifier:
-		value, err := e.Env.Lookup(n.String())
+		value, err := env.Lookup(n.String())
 		if err != nil {
-			return nil, gol.NodeErrorf(node, "Failed to find [%s]: %s", n.String(), err.Error())
+			return step{}, gol.NodeErrorf(node, "Failed to find [%s]: %s", n.String(), err.Error())
 		}
-		return value, nil
-	case *gol.NodeInt:
-		return n, nil
-	case *gol.NodeSymbol:
-		return n, nil
-	case *gol.NodeString:
-		return n, nil
-	case *gol.NodeBool:
-		return n, nil
-	case *gol.NodeQuote:
-		if n.Quasi {
-			e.nesting++
-			value, err := e.Eval(n.Arg)
-			e.nesting--
-			return value, err
+		if _, ok := value.(*nodeUnassigned); ok {
+			return step{}, gol.NodeErrorf(node, "Identifier [%s] used before it was assigned", n.String())
 		}
-		return n.Arg, nil
-	case *gol.NodeUnQuote:
-		e.nesting--
-		value, err := e.Eval(n.Arg)
-		e.nesting++
-		return value, err
+		return step{value: value, k: k}, nil
+	case *gol.NodeInt, *gol.NodeReal, *gol.NodeRational, *gol.NodeSymbol, *gol.NodeString,
+		*gol.NodeBool, *gol.NodeChar, *gol.NodeVector:
+		return step{value: n, k: k}, nil
+	case *gol.NodeQuote, *gol.NodeUnQuote:
+		value, err := e.evalQuoted(n)
+		return step{value: value, k: k}, err
+	case *gol.NodePair:
+		return step{}, gol.NodeErrorf(n, "Can't evaluate improper list: %s", n)
 	case *gol.NodeLambda:
-		if e.Quoting() {
-			return e.evalList(n.NodeList)
-		}
-		return e.evalLambda(n)
+		return step{value: &NodeProcedure{NodeLambda: n, Env: env}, k: k}, nil
 	case *gol.NodeList:
-		if e.Quoting() {
-			return e.evalList(n)
+		if n.Len() == 0 {
+			return step{}, gol.NodeErrorf(n, "Empty application")
 		}
-		return e.evalList(n)
+		return e.evalArgs(n, n, gol.NewNodeList(), env, k)
 	case *gol.NodeIf:
-		if e.Quoting() {
-			return e.evalList(n.NodeList)
-		}
-		return e.evalIf(n)
+		return e.evalIf(n, env, k)
 	case *gol.NodeSet:
-		if e.Quoting() {
-			return e.evalList(n.NodeList)
-		}
-		return e.evalSet(n)
+		return e.evalSet(n, env, k)
 	case *gol.NodeLet:
-		if e.Quoting() {
-			return e.evalList(n.NodeList)
+		if n.Rec {
+			return e.evalLetRec(n, env, k)
 		}
-		return e.evalLet(n)
+		return e.evalLet(n, n.Bindings, gol.Frame{}, env, k)
 	case *gol.NodeProgn:
-		if e.Quoting() {
-			return e.evalList(n.NodeList)
+		body := n.Rest()
+		if body.Len() == 0 {
+			// Value if no children
+			return step{value: gol.NewNodeList(), k: k}, nil
 		}
-		return e.evalProgn(n)
+		return e.evalSequence(body, env, k)
 	case *gol.NodeDefine:
-		if e.Quoting() {
-			return e.evalList(n.NodeList)
-		}
-		return e.evalDefine(n)
+		return e.evalDefine(n, env, k)
+	case *nodeControl, NodeApplicable, *NodeEnvironment, *NodePort:
+		// Values are only found in code built as data and passed to eval
+		return step{value: n, k: k}, nil
 	default:
-		return nil, gol.NodeErrorf(n, "Unrecognised node type %T", node)
-
+		return step{}, gol.NodeErrorf(n, "Unrecognised node type %T", node)
 	}
 }
 
-func (e *Evaluator) evalDefine(nd *gol.NodeDefine) (gol.Node, error) {
-	evalValue, err := e.Eval(nd.Value)
-	if err != nil {
-		return nd, err
-	}
-	err = e.Env.AddDefine(nd.Symbol.String(), evalValue)
-	if err != nil {
-		return nd, err
-	}
-	return nd.Value, nil
+func (e *Evaluator) evalDefine(nd *gol.NodeDefine, env Environment, k *continuation) (step, error) {
+	return step{node: nd.Value, env: env, k: push(k, func(e *Evaluator, value gol.Node, k *continuation) (step, error) {
+		err := env.AddDefine(nd.Symbol.String(), value)
+		if err != nil {
+			return step{}, err
+		}
+		return step{value: nd.Value, k: k}, nil
+	})}, nil
 }
 
-func (e *Evaluator) evalSet(ns *gol.NodeSet) (gol.Node, error) {
-	value, err := e.Eval(ns.Value)
-	if err != nil {
-		return nil, err
-	}
-	e.Env.Set(ns.Id.String(), value)
+func (e *Evaluator) evalSet(ns *gol.NodeSet, env Environment, k *continuation) (step, error) {
+	return step{node: ns.Value, env: env, k: push(k, func(e *Evaluator, value gol.Node, k *continuation) (step, error) {
+		env.Set(ns.Id.String(), value)
+		return step{value: value, k: k}, nil
+	})}, nil
+}
 
-	return value, nil
+func (e *Evaluator) evalIf(ni *gol.NodeIf, env Environment, k *continuation) (step, error) {
+	return step{node: ni.Condition, env: env, k: push(k, func(e *Evaluator, condition gol.Node, k *continuation) (step, error) {
+		// Everything other than #f counts as true
+		conditionBool, ok := condition.(*gol.NodeBool)
+		if ok && !conditionBool.IsTrue() {
+			return step{node: ni.FBranch, env: env, k: k}, nil
+		}
+		return step{node: ni.TBranch, env: env, k: k}, nil
+	})}, nil
 }
 
-func (e *Evaluator) evalIf(ni *gol.NodeIf) (gol.Node, error) {
-	condition, err := e.Eval(ni.Condition)
-	if err != nil {
-		return nil, err
+// evalLet evaluates the remaining inits in order in the outer scope,
+// adding their values to a copy of f
+func (e *Evaluator) evalLet(nl *gol.NodeLet, bindings []gol.LetBinding, f gol.Frame, env Environment, k *continuation) (step, error) {
+	if len(bindings) == 0 {
+		return step{node: nl.Body, env: env.WithFrame(f), k: k}, nil
 	}
-	conditionBool, ok := condition.(*gol.NodeBool)
-	if !ok {
-		return nil, gol.NodeErrorf(ni, "Non-boolean in 'if' condition")
+	b := bindings[0]
+	return step{node: b.Value, env: env, k: push(k, func(e *Evaluator, value gol.Node, k *continuation) (step, error) {
+		// Copy, since a continuation may resume here more than once
+		newFrame := gol.Frame{b.Id.String(): value}
+		for id, v := range f {
+			newFrame[id] = v
+		}
+		return e.evalLet(nl, bindings[1:], newFrame, env, k)
+	})}, nil
+}
+
+// evalLetRec evaluates the inits in order, in a scope where all the
+// bindings are visible but unassigned until their init has run
+func (e *Evaluator) evalLetRec(nl *gol.NodeLet, env Environment, k *continuation) (step, error) {
+	f := gol.Frame{}
+	for _, b := range nl.Bindings {
+		f[b.Id.String()] = &nodeUnassigned{}
 	}
-	if conditionBool.IsTrue() {
-		return e.Eval(ni.TBranch)
-	} else {
-		return e.Eval(ni.FBranch)
+	inner := env.WithFrame(f)
+
+	var assign func(i int, k *continuation) (step, error)
+	assign = func(i int, k *continuation) (step, error) {
+		if i == len(nl.Bindings) {
+			return step{node: nl.Body, env: inner, k: k}, nil
+		}
+		b := nl.Bindings[i]
+		return step{node: b.Value, env: inner, k: push(k, func(e *Evaluator, value gol.Node, k *continuation) (step, error) {
+			f[b.Id.String()] = value
+			return assign(i+1, k)
+		})}, nil
 	}
+	return assign(0, k)
 }
 
-func (e *Evaluator) evalLet(nl *gol.NodeLet) (gol.Node, error) {
+// evalSequence evaluates body in order, the last in tail position
+func (e *Evaluator) evalSequence(body *gol.NodeList, env Environment, k *continuation) (step, error) {
+	rest := body.Rest()
+	if rest.IsEmpty() {
+		return step{node: body.First(), env: env, k: k}, nil
+	}
+	return step{node: body.First(), env: env, k: push(k, func(e *Evaluator, value gol.Node, k *continuation) (step, error) {
+		return e.evalSequence(rest, env, k)
+	})}, nil
+}
 
-	f := gol.Frame{}
-	oldEnv := e.Env
-	defer func() {
-		e.Env = oldEnv
-	}()
-	e.Env = e.Env.WithFrame(f)
+// evalArgs evaluates the remaining elements of an application in order,
+// then applies the values. The values so far are consed on in reverse,
+// which leaves them as they were if a continuation resumes here again.
+func (e *Evaluator) evalArgs(nl *gol.NodeList, rest *gol.NodeList, revVals *gol.NodeList, env Environment, k *continuation) (step, error) {
+	if rest.IsEmpty() {
+		nodes := revVals.ReverseCopy()
+		// So that errors from the application point at the source
+		nodes.SetSpan(nl.Span())
+		return step{apply: nodes, k: k}, nil
+	}
+	return step{node: rest.First(), env: env, k: push(k, func(e *Evaluator, value gol.Node, k *continuation) (step, error) {
+		return e.evalArgs(nl, rest.Rest(), revVals.Cons(value), env, k)
+	})}, nil
+}
 
-	for k, _ := range nl.Bindings {
-		f[k] = gol.Nil()
+func (e *Evaluator) applyStep(nl *gol.NodeList, k *continuation) (step, error) {
+	if nl.Len() == 0 {
+		return step{}, gol.NodeErrorf(nl, "Empty application")
 	}
-	for k, v := range nl.Bindings {
-		var err error
-		f[k], err = e.Eval(v)
+	args := nl.Rest()
+	args.SetSpan(nl.Span())
+
+	switch f := nl.First().(type) {
+	case *NodeProcedure:
+		env, err := f.bind(args)
 		if err != nil {
-			return nil, err
+			return step{}, err
+		}
+		return step{node: f.Body, env: env, k: k}, nil
+	case *nodeControl:
+		return f.f(e, args, k)
+	case NodeApplicable:
+		value, err := f.Apply(e, args)
+		if err != nil {
+			return step{}, err
 		}
+		return step{value: value, k: k}, nil
+	default:
+		return step{}, gol.NodeErrorf(nl, "Can't evaluate list with non-applicable head: %T [%s]", nl.First(), nl)
 	}
-	value, err := e.Eval(nl.Body)
-	return value, err
 }
 
-func (e *Evaluator) evalLambda(nl *gol.NodeLambda) (gol.Node, error) {
-	return &NodeProcedure{
-		NodeLambda: nl,
-		Env:        e.Env,
-	}, nil
-}
-
-func (np NodeProcedure) Apply(e *Evaluator, argVals *gol.NodeList) (gol.Node, error) {
-	if argVals.Len() != np.Args.Len() {
+// bind makes the environment for a call of np
+func (np NodeProcedure) bind(argVals *gol.NodeList) (Environment, error) {
+	if argVals.Len() != np.Args.Len() && (np.Rest == nil || argVals.Len() < np.Args.Len()) {
 		return nil, gol.NodeErrorf(argVals, "Arg mismatch")
 	}
 
 	f := gol.Frame{}
+	if np.Rest != nil {
+		// Anything left after the required args is collected in a list
+		restVals := argVals
+		for i := 0; i < np.Args.Len(); i++ {
+			restVals = restVals.Rest()
+		}
+		f[np.Rest.String()] = restVals
+	}
 	z := np.Args.Zip(argVals)
 	err := z.Foreach(func(n gol.Node) error {
 		pair, ok := n.(*gol.NodePair)
@@ -189,70 +359,74 @@ func (np NodeProcedure) Apply(e *Evaluator, argVals *gol.NodeList) (gol.Node, er
 	if err != nil {
 		return nil, err
 	}
-
-	oldEnv := e.Env
-	defer func() {
-		e.Env = oldEnv
-	}()
-	e.Env = np.Env.WithFrame(f)
-	value, err := e.Eval(np.Body)
-	return value, err
+	return np.Env.WithFrame(f), nil
 }
 
-func (e *Evaluator) evalProgn(np *gol.NodeProgn) (gol.Node, error) {
-	// Value if no children
-	var lastVal gol.Node
-	lastVal = gol.NewNodeList()
+func (np NodeProcedure) Apply(e *Evaluator, argVals *gol.NodeList) (gol.Node, error) {
+	return e.Apply(argVals.Cons(&np))
+}
 
-	body := np.Rest()
-	err := body.Foreach(func(child gol.Node) error {
-		v, err := e.Eval(child)
-		if err != nil {
-			return err
+// evalQuoted evaluates under quasiquote, where only the unquoted parts
+// are evaluated
+func (e *Evaluator) evalQuoted(node gol.Node) (gol.Node, error) {
+	switch n := node.(type) {
+	case *gol.NodeQuote:
+		if n.Quasi {
+			e.nesting++
+			value, err := e.Eval(n.Arg)
+			e.nesting--
+			return value, err
 		}
-		lastVal = v
-		return nil
-	})
-	if err != nil {
-		return nil, err
+		return n.Arg, nil
+	case *gol.NodeUnQuote:
+		e.nesting--
+		value, err := e.Eval(n.Arg)
+		e.nesting++
+		return value, err
+	case *gol.NodePair:
+		return e.evalPair(n)
+	case *gol.NodeLambda:
+		return e.evalList(n.NodeList)
+	case *gol.NodeList:
+		return e.evalList(n)
+	case *gol.NodeIf:
+		return e.evalList(n.NodeList)
+	case *gol.NodeSet:
+		return e.evalList(n.NodeList)
+	case *gol.NodeLet:
+		return e.evalList(n.NodeList)
+	case *gol.NodeProgn:
+		return e.evalList(n.NodeList)
+	case *gol.NodeDefine:
+		return e.evalList(n.NodeList)
+	default:
+		return n, nil
 	}
-
-	// Return last
-	return lastVal, nil
 }
 
+// evalList is only used under quasiquote, to evaluate any unquotes in
+// a list
 func (e *Evaluator) evalList(nl *gol.NodeList) (gol.Node, error) {
 	nodes, err := nl.Map(func(child gol.Node) (gol.Node, error) {
-		newVal, err := e.Eval(child)
-		if err != nil {
-			return nil, err
-		}
-		return newVal, nil
+		return e.Eval(child)
 	})
 	if err != nil {
 		return nil, err
 	}
-
-	if e.Quoting() {
-		return nodes, nil
-	}
-
-	return e.Apply(nodes)
+	nodes.SetSpan(nl.Span())
+	return nodes, nil
 }
 
-func (e *Evaluator) Apply(nl *gol.NodeList) (gol.Node, error) {
-	if nl.Len() == 0 {
-		return nil, gol.NodeErrorf(nl, "Empty application")
-	}
-	applicable, ok := nl.First().(NodeApplicable)
-	if !ok {
-		return nil, gol.NodeErrorf(nl, "Can't evaluate list with non-applicable head: %T [%s]", nl.First(), nl)
+// evalPair is only used under quasiquote, to evaluate any unquotes in
+// an improper list
+func (e *Evaluator) evalPair(np *gol.NodePair) (gol.Node, error) {
+	car, err := e.Eval(np.Car)
+	if err != nil {
+		return nil, err
 	}
-
-	node, err := applicable.Apply(e, nl.Rest())
+	cdr, err := e.Eval(np.Cdr)
 	if err != nil {
 		return nil, err
 	}
-
-	return node, nil
+	return gol.Cons(car, cdr), nil
 }
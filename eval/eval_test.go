@@ -1,10 +1,16 @@
 package eval
 
 import (
+	"bytes"
+	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"runtime/debug"
 	"strings"
 	"testing"
 
+	"github.com/jbert/gol"
 	"github.com/jbert/gol/test"
 )
 
@@ -12,6 +18,16 @@ func TestGolFunc(t *testing.T) {
 	runCases(t, test.FuncTestCases())
 }
 
+func TestGolException(t *testing.T) {
+	runCases(t, test.ExceptionTestCases())
+	runCases(t, test.EvalExceptionTestCases())
+}
+
+func TestGolValues(t *testing.T) {
+	runCases(t, test.ValuesTestCases())
+	runCases(t, test.EvalValuesTestCases())
+}
+
 func TestGolError(t *testing.T) {
 	runCases(t, test.ErrorTestCases())
 }
@@ -19,6 +35,60 @@ func TestGolQuote(t *testing.T) {
 	runCases(t, test.QuoteTestCases())
 }
 
+func TestGolChar(t *testing.T) {
+	runCases(t, test.CharTestCases())
+}
+
+func TestGolNumber(t *testing.T) {
+	runCases(t, test.NumberTestCases())
+	runCases(t, test.BigIntTestCases())
+	runCases(t, test.NumericTowerTestCases())
+}
+
+func TestGolPair(t *testing.T) {
+	runCases(t, test.PairTestCases())
+}
+
+func TestGolVector(t *testing.T) {
+	runCases(t, test.VectorTestCases())
+	runCases(t, test.EvalVectorTestCases())
+}
+
+func TestGolString(t *testing.T) {
+	runCases(t, test.StringTestCases())
+	runCases(t, test.EvalStringTestCases())
+}
+
+func TestGolDerived(t *testing.T) {
+	runCases(t, test.DerivedTestCases())
+	runCases(t, test.EvalDerivedTestCases())
+}
+
+func TestGolMacro(t *testing.T) {
+	runCases(t, test.MacroTestCases())
+	runCases(t, test.EvalMacroTestCases())
+}
+
+func TestGolVariadic(t *testing.T) {
+	runCases(t, test.VariadicTestCases())
+	runCases(t, test.EvalVariadicTestCases())
+}
+
+func TestGolLet(t *testing.T) {
+	runCases(t, test.LetTestCases())
+	runCases(t, test.EvalLetTestCases())
+}
+
+func TestGolContinuation(t *testing.T) {
+	runCases(t, test.ContinuationTestCases())
+}
+
+func TestGolTailCall(t *testing.T) {
+	// A small stack, which a Go call per iteration would overflow
+	defer debug.SetMaxStack(debug.SetMaxStack(1 << 20))
+	runCases(t, test.EvalTailCallTestCases())
+}
+
 func TestGolBasicTestCases(t *testing.T) {
 	runCases(t, test.BasicTestCases())
 }
@@ -51,3 +121,98 @@ func evaluateProgram(prog string) (string, string) {
 	}
 	return value.String(), ""
 }
+
+func TestGolOutput(t *testing.T) {
+	var out bytes.Buffer
+	g := New(WithOutput(&out))
+	_, err := g.EvalProgram("<internal>", `(display "hello") (newline) (write 42)`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if out.String() != "hello\n42" {
+		t.Errorf("Wrong output [%s] != [hello\n42]", out.String())
+	}
+}
+
+func TestGolPort(t *testing.T) {
+	runCases(t, test.EvalPortTestCases())
+}
+
+func TestGolRead(t *testing.T) {
+	runCases(t, test.EvalReadTestCases())
+}
+
+func TestGolEnvironment(t *testing.T) {
+	runCases(t, test.EvalEnvironmentTestCases())
+}
+
+func TestGolRegistered(t *testing.T) {
+	err := test.RegisterFunctions()
+	if err != nil {
+		t.Fatalf("Can't register functions: %s", err)
+	}
+	err = gol.Register("car", test.Repeat)
+	if err == nil || err.Error() != "Can't register [car]: it is a builtin" {
+		t.Errorf("Wrong error registering a builtin's name: %v", err)
+	}
+	runCases(t, test.RegisteredTestCases())
+	runCases(t, test.EvalRegisteredTestCases())
+}
+
+func TestGolInput(t *testing.T) {
+	g := New(WithInput(strings.NewReader("first\nsecond\n")))
+	value, err := g.EvalProgram("<internal>", `(read-line) (read-line)`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if value.String() != "second" {
+		t.Errorf("Wrong result [%s] != [second]", value)
+	}
+}
+
+// A read which lexes ahead mustn't lose the rest of the input
+func TestGolReadInput(t *testing.T) {
+	g := New(WithInput(strings.NewReader("(a b) 12\nnext\n")))
+	value, err := g.EvalProgram("<internal>", `(list (read) (read) (read-line) (read-line))`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if value.String() != "((a b) 12  next)" {
+		t.Errorf("Wrong result [%s] != [((a b) 12  next)]", value)
+	}
+}
+
+func TestGolFilePort(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "data.txt")
+	prog := fmt.Sprintf(`
+		(call-with-output-file %q
+		  (lambda (p) (write-string "one" p) (newline p) (display 2 p)))
+		(define in (open-input-file %q))
+		(define lines (list (read-line in) (read-line in) (eof-object? (read-line in))))
+		(close-port in)
+		lines`, fname, fname)
+	value, errStr := evaluateProgram(prog)
+	if errStr != "" {
+		t.Fatalf("Unexpected error: %s", errStr)
+	}
+	if value != "(one 2 #t)" {
+		t.Errorf("Wrong result [%s] != [(one 2 #t)]", value)
+	}
+
+	_, errStr = evaluateProgram(fmt.Sprintf(`(define out (open-output-file %q)) (write-string "x" out) (close-port out)`, fname))
+	if errStr != "" {
+		t.Fatalf("Unexpected error: %s", errStr)
+	}
+	data, err := os.ReadFile(fname)
+	if err != nil || string(data) != "x" {
+		t.Errorf("Wrong file contents [%s] (%v)", data, err)
+	}
+}
+
+func TestGolErrorSpan(t *testing.T) {
+	_, errStr := evaluateProgram("(define (f x)\n  (car x))\n(f 1)")
+	expected := "Non-pair passed to car: <internal> line 2:3-2:10"
+	if !strings.HasPrefix(errStr, expected) {
+		t.Errorf("Wrong error [%s] != [%s]", errStr, expected)
+	}
+}
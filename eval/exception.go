@@ -0,0 +1,225 @@
+package eval
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jbert/gol"
+)
+
+func exceptionBuiltins() gol.Frame {
+	return gol.Frame{
+		"raise":                  &nodeControl{f: raise, description: "raise"},
+		"raise-continuable":      &nodeControl{f: raiseContinuable, description: "raise-continuable"},
+		"error":                  &nodeControl{f: raiseError, description: "error"},
+		"with-exception-handler": &nodeControl{f: withExceptionHandler, description: "with-exception-handler"},
+		"%guard":                 &nodeControl{f: guard, description: "%guard"},
+		"error-object?":          &NodeBuiltin{f: isErrorObject, description: "error-object?"},
+		"error-object-message":   &NodeBuiltin{f: errorObjectMessage, description: "error-object-message"},
+		"error-object-irritants": &NodeBuiltin{f: errorObjectIrritants, description: "error-object-irritants"},
+	}
+}
+
+// handler is an exception handler installed by with-exception-handler,
+// innermost first
+type handler struct {
+	proc gol.Node
+	next *handler
+}
+
+// NodeErrorObject is the condition raised by error, and by builtins
+// which fail while a handler is installed
+type NodeErrorObject struct {
+	gol.NodeBase
+	source    gol.Node
+	message   string
+	irritants *gol.NodeList
+	// err is the failure of a builtin, reported as it was if nothing
+	// handles it
+	err error
+}
+
+func (eo *NodeErrorObject) Pos() gol.Position {
+	return eo.source.Pos()
+}
+
+func (eo *NodeErrorObject) String() string {
+	return fmt.Sprintf("#<error-object %s>", eo.describe())
+}
+
+// describe is the message followed by the irritants
+func (eo *NodeErrorObject) describe() string {
+	parts := []string{eo.message}
+	eo.irritants.Foreach(func(n gol.Node) error {
+		parts = append(parts, n.String())
+		return nil
+	})
+	return strings.Join(parts, " ")
+}
+
+// errorObjectFrom makes a condition from the failure of a step
+func errorObjectFrom(err error, source gol.Node) *NodeErrorObject {
+	eo := &NodeErrorObject{source: source, message: err.Error(), irritants: gol.NewNodeList(), err: err}
+	if ne, ok := err.(*gol.NodeError); ok {
+		eo.source = ne
+		eo.message = ne.Message()
+	}
+	return eo
+}
+
+// uncaught is the error for a condition which no handler took
+func uncaught(obj gol.Node, source gol.Node) error {
+	eo, ok := obj.(*NodeErrorObject)
+	if !ok {
+		return gol.NodeErrorf(source, "Uncaught exception: %s", obj)
+	}
+	if eo.err != nil {
+		return eo.err
+	}
+	return gol.NodeErrorf(eo.source, "%s", eo.describe())
+}
+
+// raiseStep applies the innermost handler to obj, in the dynamic
+// environment of the raise minus that handler
+func (e *Evaluator) raiseStep(obj gol.Node, continuable bool, source gol.Node, k *continuation) (step, error) {
+	h := e.handlers
+	if h == nil {
+		return step{}, uncaught(obj, source)
+	}
+	e.handlers = h.next
+	k = push(k, func(e *Evaluator, value gol.Node, k *continuation) (step, error) {
+		if !continuable {
+			// A secondary exception, in the handler's environment
+			secondary := &NodeErrorObject{
+				source:    source,
+				message:   "Exception handler returned from non-continuable raise",
+				irritants: gol.NewNodeList().Cons(obj),
+			}
+			return e.raiseStep(secondary, false, source, k)
+		}
+		e.handlers = h
+		return step{value: value, k: k}, nil
+	})
+	return step{apply: gol.NewNodeList().Cons(obj).Cons(h.proc), k: k}, nil
+}
+
+func raise(e *Evaluator, nodes *gol.NodeList, k *continuation) (step, error) {
+	if nodes.Len() != 1 {
+		return step{}, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	return e.raiseStep(nodes.First(), false, nodes, k)
+}
+
+func raiseContinuable(e *Evaluator, nodes *gol.NodeList, k *continuation) (step, error) {
+	if nodes.Len() != 1 {
+		return step{}, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	return e.raiseStep(nodes.First(), true, nodes, k)
+}
+
+func raiseError(e *Evaluator, nodes *gol.NodeList, k *continuation) (step, error) {
+	if nodes.Len() < 1 {
+		return step{}, gol.NodeErrorf(nodes, "Arity-error: expected >= 1 args")
+	}
+	message, err := stringArg(nodes, nodes.First(), "error")
+	if err != nil {
+		return step{}, err
+	}
+	eo := &NodeErrorObject{source: nodes, message: message, irritants: nodes.Rest()}
+	return e.raiseStep(eo, false, nodes, k)
+}
+
+func withExceptionHandler(e *Evaluator, nodes *gol.NodeList, k *continuation) (step, error) {
+	if nodes.Len() != 2 {
+		return step{}, gol.NodeErrorf(nodes, "Arity-error: expected == 2 args")
+	}
+	outer := e.handlers
+	e.handlers = &handler{proc: nodes.First(), next: outer}
+	k = push(k, func(e *Evaluator, value gol.Node, k *continuation) (step, error) {
+		e.handlers = outer
+		return step{value: value, k: k}, nil
+	})
+	return step{apply: gol.NewNodeList().Cons(nodes.Nth(1)), k: k}, nil
+}
+
+// guardCondition carries a condition from a guard's handler back to the
+// guard, to tell it apart from the value of the body
+type guardCondition struct {
+	gol.NodeBase
+	obj gol.Node
+}
+
+func (gc *guardCondition) Pos() gol.Position {
+	return gc.obj.Pos()
+}
+
+func (gc *guardCondition) String() string {
+	return gc.obj.String()
+}
+
+// guard applies body with a handler which escapes back to the guard,
+// and then applies onRaise to the condition. It is what the guard form
+// expands to.
+func guard(e *Evaluator, nodes *gol.NodeList, k *continuation) (step, error) {
+	if nodes.Len() != 2 {
+		return step{}, gol.NodeErrorf(nodes, "Arity-error: expected == 2 args")
+	}
+	body, onRaise := nodes.First(), nodes.Nth(1)
+
+	outer := e.handlers
+	k = push(k, func(e *Evaluator, value gol.Node, k *continuation) (step, error) {
+		if gc, ok := value.(*guardCondition); ok {
+			return step{apply: gol.NewNodeList().Cons(gc.obj).Cons(onRaise), k: k}, nil
+		}
+		return step{value: value, k: k}, nil
+	})
+	c := &NodeContinuation{k: k, winders: e.winders, handlers: outer, run: e.run}
+	escapeToGuard := &NodeBuiltin{
+		f: func(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+			return nil, &escape{c: c, value: &guardCondition{obj: nodes.First()}}
+		},
+		description: "guard",
+	}
+
+	e.handlers = &handler{proc: escapeToGuard, next: outer}
+	k = push(k, func(e *Evaluator, value gol.Node, k *continuation) (step, error) {
+		e.handlers = outer
+		return step{value: value, k: k}, nil
+	})
+	return step{apply: gol.NewNodeList().Cons(body), k: k}, nil
+}
+
+func errorObjectArg(nodes *gol.NodeList, name string) (*NodeErrorObject, error) {
+	if nodes.Len() != 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	eo, ok := nodes.First().(*NodeErrorObject)
+	if !ok {
+		return nil, gol.NodeErrorf(nodes, "Non-error-object passed to %s", name)
+	}
+	return eo, nil
+}
+
+func isErrorObject(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	_, ok := nodes.First().(*NodeErrorObject)
+	return gol.NewNodeBool(ok), nil
+}
+
+func errorObjectMessage(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	eo, err := errorObjectArg(nodes, "error-object-message")
+	if err != nil {
+		return nil, err
+	}
+	return gol.NewNodeString(eo.message), nil
+}
+
+func errorObjectIrritants(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	eo, err := errorObjectArg(nodes, "error-object-irritants")
+	if err != nil {
+		return nil, err
+	}
+	return eo.irritants, nil
+}
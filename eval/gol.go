@@ -11,13 +11,55 @@ import (
 
 type Gol struct {
 	eval *Evaluator
+	// Shared by every evaluator, so that none reads ahead into what
+	// another should read
+	in  *NodePort
+	out *NodePort
+	err *NodePort
 }
 
-func New() *Gol {
-	g := Gol{}
+// Option configures a Gol
+type Option func(g *Gol)
+
+// WithOutput sends the output of programs, such as display, to w
+// rather than stdout
+func WithOutput(w io.Writer) Option {
+	return func(g *Gol) {
+		g.out = NewOutputPort("stdout", w)
+	}
+}
+
+// WithInput has programs read from r rather than stdin
+func WithInput(r io.Reader) Option {
+	return func(g *Gol) {
+		g.in = NewInputPort("stdin", r)
+	}
+}
+
+// WithError sends the error output of programs to w rather than stderr
+func WithError(w io.Writer) Option {
+	return func(g *Gol) {
+		g.err = NewOutputPort("stderr", w)
+	}
+}
+
+func New(opts ...Option) *Gol {
+	g := Gol{
+		in:  NewInputPort("stdin", os.Stdin),
+		out: NewOutputPort("stdout", os.Stdout),
+		err: NewOutputPort("stderr", os.Stderr),
+	}
+	for _, opt := range opts {
+		opt(&g)
+	}
 	return &g
 }
 
+// newEvaluator makes an evaluator which uses our ports
+func (g *Gol) newEvaluator(env Environment) *Evaluator {
+	return &Evaluator{Env: env, in: g.in, out: g.out, err: g.err}
+}
+
 func (g *Gol) EvalFile(fname string) (gol.Node, error) {
 	f, err := os.Open(fname)
 	if err != nil {
@@ -51,37 +93,18 @@ func (g *Gol) EvalReader(srcName string, r io.Reader) (gol.Node, error) {
 }
 
 func (g *Gol) evalReaderWithEnv(srcName string, r io.Reader, env *Environment) (gol.Node, error) {
-	l := gol.NewLexer(srcName, r)
-
-	// Run the lexer until EOF or error
-	var lexErr error
-	lexDone := make(chan struct{})
-	go func() {
-		lexErr = l.Run()
-		close(lexDone)
-	}()
-
-	// Run the parser until the lexer finishes
-	p := gol.NewParser(l.Tokens)
-	nodeTree, parseErr := p.Parse()
-	if parseErr != nil {
-		return nil, parseErr
-	}
+	return evalReader(g.newEvaluator(*env), srcName, r)
+}
 
-	nodeTree, parseErr = gol.Transform(nodeTree)
-	if parseErr != nil {
-		return nil, parseErr
+// evalReader runs the program in r with e. Macro transformers are run
+// with an evaluator of their own, which shares e's ports.
+func evalReader(e *Evaluator, srcName string, r io.Reader) (gol.Node, error) {
+	nodeTree, err := gol.ParseReaderWithEvaluator(srcName, r, newMacroEvaluator(e.withEnv(e.Env)))
+	if err != nil {
+		return nil, err
 	}
 
-	e := NewEvaluator(*env, os.Stdout, os.Stdin, os.Stderr)
 	value, err := e.Eval(nodeTree)
-
-	// Hoover up any lexing errors
-	<-lexDone
-	if lexErr != nil {
-		return nil, lexErr
-	}
-
 	if err != nil {
 		switch e := err.(type) {
 		case *gol.NodeError:
@@ -0,0 +1,98 @@
+package eval
+
+import (
+	"github.com/jbert/gol"
+)
+
+func macroBuiltins() gol.Frame {
+	return gol.Frame{
+		"make-syntactic-closure": &NodeBuiltin{f: makeSyntacticClosure, description: "make-syntactic-closure"},
+		"identifier?":            &NodeBuiltin{f: isIdentifier, description: "identifier?"},
+	}
+}
+
+// macroEvaluator runs procedural macro transformers for the expander
+type macroEvaluator struct {
+	e *Evaluator
+}
+
+// NewMacroEvaluator returns a gol.MacroEvaluator with the default
+// environment and standard library, for backends which can't run code
+// at expansion time themselves
+func NewMacroEvaluator() (gol.MacroEvaluator, error) {
+	g := New()
+	env := MakeDefaultEnvironment()
+	err := g.loadStandardLib(&env)
+	if err != nil {
+		return nil, err
+	}
+	return newMacroEvaluator(g.newEvaluator(env)), nil
+}
+
+// newMacroEvaluator runs transformers with e, so that they share its
+// streams
+func newMacroEvaluator(e *Evaluator) *macroEvaluator {
+	return &macroEvaluator{e: e}
+}
+
+func (me *macroEvaluator) Eval(n gol.Node) (gol.Node, error) {
+	return me.e.Eval(n)
+}
+
+func (me *macroEvaluator) Call(proc gol.Node, args []gol.Node) (gol.Node, error) {
+	nl := gol.NewNodeList()
+	nl = nl.Append(proc)
+	for _, arg := range args {
+		nl = nl.Append(arg)
+	}
+	return me.e.Apply(nl)
+}
+
+func (me *macroEvaluator) Procedure(name string, f func(args []gol.Node) (gol.Node, error)) gol.Node {
+	return &NodeBuiltin{
+		f: func(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+			var args []gol.Node
+			nodes.Foreach(func(n gol.Node) error {
+				args = append(args, n)
+				return nil
+			})
+			return f(args)
+		},
+		description: name,
+	}
+}
+
+func makeSyntacticClosure(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 3 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 3 args")
+	}
+	env, ok := nodes.First().(*gol.NodeSyntaxEnv)
+	if !ok {
+		return nil, gol.NodeErrorf(nodes, "Non-environment passed to make-syntactic-closure")
+	}
+	freeList, ok := nodes.Nth(1).(*gol.NodeList)
+	if !ok {
+		return nil, gol.NodeErrorf(nodes, "Non-list of free names passed to make-syntactic-closure")
+	}
+	var free []string
+	err := freeList.Foreach(func(n gol.Node) error {
+		id, ok := n.(*gol.NodeIdentifier)
+		if !ok {
+			return gol.NodeErrorf(nodes, "Non-identifier free name passed to make-syntactic-closure")
+		}
+		free = append(free, id.String())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return gol.NewNodeSyntacticClosure(env, free, nodes.Nth(2)), nil
+}
+
+func isIdentifier(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	_, ok := nodes.First().(*gol.NodeIdentifier)
+	return gol.NewNodeBool(ok), nil
+}
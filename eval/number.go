@@ -0,0 +1,562 @@
+package eval
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/jbert/gol"
+)
+
+func numberBuiltins() gol.Frame {
+	return gol.Frame{
+		"+":  &NodeBuiltin{f: addNum, description: "+"},
+		"-":  &NodeBuiltin{f: subNum, description: "-"},
+		"*":  &NodeBuiltin{f: mulNum, description: "*"},
+		"/":  &NodeBuiltin{f: divNum, description: "/"},
+		"=":  &NodeBuiltin{f: numCompare(func(c int) bool { return c == 0 }), description: "="},
+		"<":  &NodeBuiltin{f: numCompare(func(c int) bool { return c < 0 }), description: "<"},
+		">":  &NodeBuiltin{f: numCompare(func(c int) bool { return c > 0 }), description: ">"},
+		"<=": &NodeBuiltin{f: numCompare(func(c int) bool { return c <= 0 }), description: "<="},
+		">=": &NodeBuiltin{f: numCompare(func(c int) bool { return c >= 0 }), description: ">="},
+
+		"zero?":     &NodeBuiltin{f: zerop, description: "zerop"},
+		"number?":   &NodeBuiltin{f: numPredicate(isNumber), description: "number?"},
+		"real?":     &NodeBuiltin{f: numPredicate(isNumber), description: "real?"},
+		"rational?": &NodeBuiltin{f: numPredicate(isRational), description: "rational?"},
+		"integer?":  &NodeBuiltin{f: numPredicate(isInteger), description: "integer?"},
+		"exact?":    &NodeBuiltin{f: numPredicate(isExact), description: "exact?"},
+		"inexact?":  &NodeBuiltin{f: numPredicate(isInexact), description: "inexact?"},
+
+		"exact->inexact": &NodeBuiltin{f: toInexact, description: "exact->inexact"},
+		"inexact":        &NodeBuiltin{f: toInexact, description: "inexact"},
+		"inexact->exact": &NodeBuiltin{f: toExact, description: "inexact->exact"},
+		"exact":          &NodeBuiltin{f: toExact, description: "exact"},
+
+		"floor":    &NodeBuiltin{f: rounder(floorRat, math.Floor), description: "floor"},
+		"ceiling":  &NodeBuiltin{f: rounder(ceilingRat, math.Ceil), description: "ceiling"},
+		"truncate": &NodeBuiltin{f: rounder(truncateRat, math.Trunc), description: "truncate"},
+		"round":    &NodeBuiltin{f: rounder(roundRat, math.RoundToEven), description: "round"},
+
+		"numerator":   &NodeBuiltin{f: numerator, description: "numerator"},
+		"denominator": &NodeBuiltin{f: denominator, description: "denominator"},
+		"sqrt":        &NodeBuiltin{f: sqrt, description: "sqrt"},
+		"expt":        &NodeBuiltin{f: expt, description: "expt"},
+	}
+}
+
+// The numeric tower. Operations on mixed levels are carried out at the
+// highest level involved, so exactness is lost as soon as a real is seen.
+// Ints are a single level, but are only held in a big.Int when they
+// overflow an int64.
+type numLevel int
+
+const (
+	levelInt numLevel = iota
+	levelRational
+	levelReal
+)
+
+func numberLevel(n gol.Node) (numLevel, bool) {
+	switch n.(type) {
+	case *gol.NodeInt:
+		return levelInt, true
+	case *gol.NodeRational:
+		return levelRational, true
+	case *gol.NodeReal:
+		return levelReal, true
+	default:
+		return 0, false
+	}
+}
+
+func toRat(n gol.Node) *big.Rat {
+	switch num := n.(type) {
+	case *gol.NodeInt:
+		return new(big.Rat).SetInt(num.BigValue())
+	case *gol.NodeRational:
+		return num.Value()
+	case *gol.NodeReal:
+		return new(big.Rat).SetFloat64(num.Value())
+	default:
+		panic("toRat on non-number")
+	}
+}
+
+func toFloat(n gol.Node) float64 {
+	switch num := n.(type) {
+	case *gol.NodeInt:
+		if num.IsBig() {
+			f, _ := new(big.Float).SetInt(num.BigValue()).Float64()
+			return f
+		}
+		return float64(num.Value())
+	case *gol.NodeRational:
+		f, _ := num.Value().Float64()
+		return f
+	case *gol.NodeReal:
+		return num.Value()
+	default:
+		panic("toFloat on non-number")
+	}
+}
+
+// numArgs checks that all of nodes are numbers
+func numArgs(nodes *gol.NodeList, name string) ([]gol.Node, error) {
+	var nums []gol.Node
+	err := nodes.Foreach(func(n gol.Node) error {
+		if _, ok := numberLevel(n); !ok {
+			return gol.NodeErrorf(nodes, "Non-number passed to %s", name)
+		}
+		nums = append(nums, n)
+		return nil
+	})
+	return nums, err
+}
+
+func singleNum(nodes *gol.NodeList, name string) (gol.Node, error) {
+	if nodes.Len() != 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	nums, err := numArgs(nodes, name)
+	if err != nil {
+		return nil, err
+	}
+	return nums[0], nil
+}
+
+// numOp is a binary operation, with an implementation for each level.
+// The ints implementation reports overflow with ok == false, in which
+// case bigs is used instead.
+type numOp struct {
+	ints  func(a, b int64) (result gol.Node, ok bool)
+	bigs  func(a, b *big.Int) gol.Node
+	rats  func(a, b *big.Rat) gol.Node
+	reals func(a, b float64) gol.Node
+}
+
+func (op numOp) apply(a, b gol.Node) gol.Node {
+	la, _ := numberLevel(a)
+	lb, _ := numberLevel(b)
+	level := la
+	if lb > level {
+		level = lb
+	}
+	switch level {
+	case levelInt:
+		ia, ib := a.(*gol.NodeInt), b.(*gol.NodeInt)
+		if !ia.IsBig() && !ib.IsBig() {
+			result, ok := op.ints(ia.Value(), ib.Value())
+			if ok {
+				return result
+			}
+		}
+		return op.bigs(ia.BigValue(), ib.BigValue())
+	case levelRational:
+		return op.rats(toRat(a), toRat(b))
+	default:
+		return op.reals(toFloat(a), toFloat(b))
+	}
+}
+
+var addOp = numOp{
+	ints: func(a, b int64) (gol.Node, bool) {
+		c := a + b
+		// Overflow iff the result's sign differs from both args
+		return gol.NewNodeInt(c), (a^c)&(b^c) >= 0
+	},
+	bigs: func(a, b *big.Int) gol.Node {
+		return gol.NewNodeBigInt(new(big.Int).Add(a, b))
+	},
+	rats: func(a, b *big.Rat) gol.Node {
+		return gol.NewNodeExact(new(big.Rat).Add(a, b))
+	},
+	reals: func(a, b float64) gol.Node {
+		return gol.NewNodeReal(a + b)
+	},
+}
+
+var subOp = numOp{
+	ints: func(a, b int64) (gol.Node, bool) {
+		c := a - b
+		// Overflow iff the args differ in sign and the result's sign
+		// differs from a
+		return gol.NewNodeInt(c), (a^b)&(a^c) >= 0
+	},
+	bigs: func(a, b *big.Int) gol.Node {
+		return gol.NewNodeBigInt(new(big.Int).Sub(a, b))
+	},
+	rats: func(a, b *big.Rat) gol.Node {
+		return gol.NewNodeExact(new(big.Rat).Sub(a, b))
+	},
+	reals: func(a, b float64) gol.Node {
+		return gol.NewNodeReal(a - b)
+	},
+}
+
+var mulOp = numOp{
+	ints: func(a, b int64) (gol.Node, bool) {
+		if a == 0 || b == 0 {
+			return gol.NewNodeInt(0), true
+		}
+		c := a * b
+		if c/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+			return nil, false
+		}
+		return gol.NewNodeInt(c), true
+	},
+	bigs: func(a, b *big.Int) gol.Node {
+		return gol.NewNodeBigInt(new(big.Int).Mul(a, b))
+	},
+	rats: func(a, b *big.Rat) gol.Node {
+		return gol.NewNodeExact(new(big.Rat).Mul(a, b))
+	},
+	reals: func(a, b float64) gol.Node {
+		return gol.NewNodeReal(a * b)
+	},
+}
+
+// Callers must check for an exact zero divisor
+var divOp = numOp{
+	ints: func(a, b int64) (gol.Node, bool) {
+		return gol.NewNodeExact(big.NewRat(a, b)), true
+	},
+	bigs: func(a, b *big.Int) gol.Node {
+		return gol.NewNodeExact(new(big.Rat).SetFrac(a, b))
+	},
+	rats: func(a, b *big.Rat) gol.Node {
+		return gol.NewNodeExact(new(big.Rat).Quo(a, b))
+	},
+	reals: func(a, b float64) gol.Node {
+		return gol.NewNodeReal(a / b)
+	},
+}
+
+func foldNums(nums []gol.Node, acc gol.Node, op numOp) gol.Node {
+	for _, n := range nums {
+		acc = op.apply(acc, n)
+	}
+	return acc
+}
+
+func addNum(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	nums, err := numArgs(nodes, "+")
+	if err != nil {
+		return nil, err
+	}
+	return foldNums(nums, gol.NewNodeInt(0), addOp), nil
+}
+
+func mulNum(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	nums, err := numArgs(nodes, "*")
+	if err != nil {
+		return nil, err
+	}
+	return foldNums(nums, gol.NewNodeInt(1), mulOp), nil
+}
+
+func subNum(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() == 0 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected > 0 args")
+	}
+	nums, err := numArgs(nodes, "-")
+	if err != nil {
+		return nil, err
+	}
+	if len(nums) == 1 {
+		return subOp.apply(gol.NewNodeInt(0), nums[0]), nil
+	}
+	return foldNums(nums[1:], nums[0], subOp), nil
+}
+
+func divNum(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() == 0 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected > 0 args")
+	}
+	nums, err := numArgs(nodes, "/")
+	if err != nil {
+		return nil, err
+	}
+	if len(nums) == 1 {
+		nums = append([]gol.Node{gol.NewNodeInt(1)}, nums...)
+	}
+	for _, n := range nums[1:] {
+		if isExactZero(n) {
+			return nil, gol.NodeErrorf(nodes, "Division by zero")
+		}
+	}
+	return foldNums(nums[1:], nums[0], divOp), nil
+}
+
+func isExactZero(n gol.Node) bool {
+	level, _ := numberLevel(n)
+	return level != levelReal && toRat(n).Sign() == 0
+}
+
+// compareNums returns -1, 0 or 1 as a < b, a == b or a > b
+func compareNums(a, b gol.Node) int {
+	la, _ := numberLevel(a)
+	lb, _ := numberLevel(b)
+	switch {
+	case la == levelInt && lb == levelInt:
+		ia, ib := a.(*gol.NodeInt), b.(*gol.NodeInt)
+		if ia.IsBig() || ib.IsBig() {
+			return ia.BigValue().Cmp(ib.BigValue())
+		}
+		x, y := ia.Value(), ib.Value()
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		default:
+			return 0
+		}
+	case la == levelReal || lb == levelReal:
+		x, y := toFloat(a), toFloat(b)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		default:
+			return 0
+		}
+	default:
+		return toRat(a).Cmp(toRat(b))
+	}
+}
+
+// numCompare checks that f holds for the comparison of each adjacent
+// pair of args
+func numCompare(f func(c int) bool) func(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	return func(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+		if nodes.Len() < 2 {
+			return nil, gol.NodeErrorf(nodes, "At least two arguments required")
+		}
+		nums, err := numArgs(nodes, "numeric comparison")
+		if err != nil {
+			return nil, err
+		}
+		for i := 1; i < len(nums); i++ {
+			if !f(compareNums(nums[i-1], nums[i])) {
+				return gol.NODE_FALSE, nil
+			}
+		}
+		return gol.NODE_TRUE, nil
+	}
+}
+
+func zerop(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	n, err := singleNum(nodes, "zero?")
+	if err != nil {
+		return nil, err
+	}
+	return gol.NewNodeBool(compareNums(n, gol.NewNodeInt(0)) == 0), nil
+}
+
+func isNumber(n gol.Node) bool {
+	_, ok := numberLevel(n)
+	return ok
+}
+
+func isRational(n gol.Node) bool {
+	if nr, ok := n.(*gol.NodeReal); ok {
+		return !math.IsInf(nr.Value(), 0) && !math.IsNaN(nr.Value())
+	}
+	return isNumber(n)
+}
+
+func isInteger(n gol.Node) bool {
+	switch num := n.(type) {
+	case *gol.NodeInt:
+		return true
+	case *gol.NodeReal:
+		return isRational(n) && num.Value() == math.Trunc(num.Value())
+	default:
+		return false
+	}
+}
+
+func isExact(n gol.Node) bool {
+	level, ok := numberLevel(n)
+	return ok && level != levelReal
+}
+
+func isInexact(n gol.Node) bool {
+	level, ok := numberLevel(n)
+	return ok && level == levelReal
+}
+
+func numPredicate(f func(n gol.Node) bool) func(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	return func(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+		if nodes.Len() != 1 {
+			return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+		}
+		return gol.NewNodeBool(f(nodes.First())), nil
+	}
+}
+
+func toInexact(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	n, err := singleNum(nodes, "exact->inexact")
+	if err != nil {
+		return nil, err
+	}
+	return gol.NewNodeReal(toFloat(n)), nil
+}
+
+func toExact(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	n, err := singleNum(nodes, "inexact->exact")
+	if err != nil {
+		return nil, err
+	}
+	if !isRational(n) {
+		return nil, gol.NodeErrorf(nodes, "No exact equivalent for %s", n)
+	}
+	return gol.NewNodeExact(toRat(n)), nil
+}
+
+func floorRat(r *big.Rat) *big.Int {
+	// Euclidean division rounds down for the positive denominators
+	// big.Rat keeps
+	return new(big.Int).Div(r.Num(), r.Denom())
+}
+
+func ceilingRat(r *big.Rat) *big.Int {
+	q := floorRat(new(big.Rat).Neg(r))
+	return q.Neg(q)
+}
+
+func truncateRat(r *big.Rat) *big.Int {
+	return new(big.Int).Quo(r.Num(), r.Denom())
+}
+
+// roundRat rounds to nearest, with ties going to the even neighbour
+func roundRat(r *big.Rat) *big.Int {
+	half := big.NewRat(1, 2)
+	shifted := new(big.Rat).Add(r, half)
+	q := floorRat(shifted)
+	if shifted.IsInt() && q.Bit(0) == 1 {
+		q.Sub(q, big.NewInt(1))
+	}
+	return q
+}
+
+func rounder(exact func(r *big.Rat) *big.Int, inexact func(f float64) float64) func(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	return func(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+		n, err := singleNum(nodes, "rounding")
+		if err != nil {
+			return nil, err
+		}
+		switch num := n.(type) {
+		case *gol.NodeInt:
+			return num, nil
+		case *gol.NodeReal:
+			return gol.NewNodeReal(inexact(num.Value())), nil
+		default:
+			return gol.NewNodeExact(new(big.Rat).SetInt(exact(toRat(num)))), nil
+		}
+	}
+}
+
+func numerator(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	n, err := singleNum(nodes, "numerator")
+	if err != nil {
+		return nil, err
+	}
+	if !isRational(n) {
+		return nil, gol.NodeErrorf(nodes, "Non-rational passed to numerator")
+	}
+	num := gol.NewNodeExact(new(big.Rat).SetInt(toRat(n).Num()))
+	if isInexact(n) {
+		return gol.NewNodeReal(toFloat(num)), nil
+	}
+	return num, nil
+}
+
+func denominator(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	n, err := singleNum(nodes, "denominator")
+	if err != nil {
+		return nil, err
+	}
+	if !isRational(n) {
+		return nil, gol.NodeErrorf(nodes, "Non-rational passed to denominator")
+	}
+	den := gol.NewNodeExact(new(big.Rat).SetInt(toRat(n).Denom()))
+	if isInexact(n) {
+		return gol.NewNodeReal(toFloat(den)), nil
+	}
+	return den, nil
+}
+
+// exactSqrt returns the root of a perfect square, or nil
+func exactSqrt(i *big.Int) *big.Int {
+	root := new(big.Int).Sqrt(i)
+	if new(big.Int).Mul(root, root).Cmp(i) != 0 {
+		return nil
+	}
+	return root
+}
+
+// sqrt stays exact when given the square of an exact number
+func sqrt(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	n, err := singleNum(nodes, "sqrt")
+	if err != nil {
+		return nil, err
+	}
+	if compareNums(n, gol.NewNodeInt(0)) < 0 {
+		return nil, gol.NodeErrorf(nodes, "sqrt of negative number %s", n)
+	}
+	if isExact(n) {
+		r := toRat(n)
+		num := exactSqrt(r.Num())
+		den := exactSqrt(r.Denom())
+		if num != nil && den != nil {
+			return gol.NewNodeExact(new(big.Rat).SetFrac(num, den)), nil
+		}
+	}
+	return gol.NewNodeReal(math.Sqrt(toFloat(n))), nil
+}
+
+// expt is exact for an exact base raised to an exact integer power
+func expt(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 2 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 2 args")
+	}
+	nums, err := numArgs(nodes, "expt")
+	if err != nil {
+		return nil, err
+	}
+	base, power := nums[0], nums[1]
+	p, powerIsInt := power.(*gol.NodeInt)
+	if !isExact(base) || !powerIsInt {
+		return gol.NewNodeReal(math.Pow(toFloat(base), toFloat(power))), nil
+	}
+	if p.IsBig() {
+		return nil, gol.NodeErrorf(nodes, "Power too large for expt: %s", p)
+	}
+
+	exp := p.Value()
+	if exp < 0 && isExactZero(base) {
+		return nil, gol.NodeErrorf(nodes, "Division by zero")
+	}
+	negative := exp < 0
+	if negative {
+		exp = -exp
+	}
+	// Exponentiation by squaring
+	var result gol.Node = gol.NewNodeInt(1)
+	square := base
+	for exp > 0 {
+		if exp&1 == 1 {
+			result = mulOp.apply(result, square)
+		}
+		exp >>= 1
+		if exp > 0 {
+			square = mulOp.apply(square, square)
+		}
+	}
+	if negative {
+		result = divOp.apply(gol.NewNodeInt(1), result)
+	}
+	return result, nil
+}
@@ -0,0 +1,67 @@
+package eval
+
+import (
+	"github.com/jbert/gol"
+)
+
+func pairBuiltins() gol.Frame {
+	return gol.Frame{
+		"cons":     &NodeBuiltin{f: cons, description: "cons"},
+		"car":      &NodeBuiltin{f: pairAccessor("car", gol.Car), description: "car"},
+		"cdr":      &NodeBuiltin{f: pairAccessor("cdr", gol.Cdr), description: "cdr"},
+		"caar":     &NodeBuiltin{f: pairAccessor("caar", gol.Car, gol.Car), description: "caar"},
+		"cadr":     &NodeBuiltin{f: pairAccessor("cadr", gol.Cdr, gol.Car), description: "cadr"},
+		"cdar":     &NodeBuiltin{f: pairAccessor("cdar", gol.Car, gol.Cdr), description: "cdar"},
+		"cddr":     &NodeBuiltin{f: pairAccessor("cddr", gol.Cdr, gol.Cdr), description: "cddr"},
+		"pair?":    &NodeBuiltin{f: pairPredicate(gol.IsPair), description: "pair?"},
+		"null?":    &NodeBuiltin{f: pairPredicate(gol.IsNull), description: "null?"},
+		"set-car!": &NodeBuiltin{f: pairMutator("set-car!", gol.SetCar), description: "set-car!"},
+		"set-cdr!": &NodeBuiltin{f: pairMutator("set-cdr!", gol.SetCdr), description: "set-cdr!"},
+	}
+}
+
+func cons(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 2 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 2 args")
+	}
+	return gol.Cons(nodes.First(), nodes.Nth(1)), nil
+}
+
+// pairAccessor applies each of the steps in turn, so "cadr" is (cdr, car)
+func pairAccessor(name string, steps ...func(gol.Node) (gol.Node, bool)) func(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	return func(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+		if nodes.Len() != 1 {
+			return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+		}
+		n := nodes.First()
+		for _, step := range steps {
+			var ok bool
+			n, ok = step(n)
+			if !ok {
+				return nil, gol.NodeErrorf(nodes, "Non-pair passed to %s", name)
+			}
+		}
+		return n, nil
+	}
+}
+
+func pairPredicate(f func(gol.Node) bool) func(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	return func(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+		if nodes.Len() != 1 {
+			return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+		}
+		return gol.NewNodeBool(f(nodes.First())), nil
+	}
+}
+
+func pairMutator(name string, f func(n, v gol.Node) bool) func(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	return func(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+		if nodes.Len() != 2 {
+			return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 2 args")
+		}
+		if !f(nodes.First(), nodes.Nth(1)) {
+			return nil, gol.NodeErrorf(nodes, "Non-pair passed to %s", name)
+		}
+		return gol.Nil(), nil
+	}
+}
@@ -0,0 +1,444 @@
+package eval
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jbert/gol"
+)
+
+func portBuiltins() gol.Frame {
+	return gol.Frame{
+		"current-output-port":   &NodeBuiltin{f: currentOutputPort, description: "current-output-port"},
+		"current-input-port":    &NodeBuiltin{f: currentInputPort, description: "current-input-port"},
+		"current-error-port":    &NodeBuiltin{f: currentErrorPort, description: "current-error-port"},
+		"open-input-file":       &NodeBuiltin{f: openInputFile, description: "open-input-file"},
+		"open-output-file":      &NodeBuiltin{f: openOutputFile, description: "open-output-file"},
+		"open-input-string":     &NodeBuiltin{f: openInputString, description: "open-input-string"},
+		"open-output-string":    &NodeBuiltin{f: openOutputString, description: "open-output-string"},
+		"get-output-string":     &NodeBuiltin{f: getOutputString, description: "get-output-string"},
+		"read-line":             &NodeBuiltin{f: readLine, description: "read-line"},
+		"read-char":             &NodeBuiltin{f: readChar, description: "read-char"},
+		"peek-char":             &NodeBuiltin{f: peekChar, description: "peek-char"},
+		"write-string":          &NodeBuiltin{f: writeString, description: "write-string"},
+		"close-port":            &NodeBuiltin{f: closePort, description: "close-port"},
+		"close-input-port":      &NodeBuiltin{f: closePort, description: "close-input-port"},
+		"close-output-port":     &NodeBuiltin{f: closePort, description: "close-output-port"},
+		"port?":                 &NodeBuiltin{f: isPort, description: "port?"},
+		"input-port?":           &NodeBuiltin{f: isInputPort, description: "input-port?"},
+		"output-port?":          &NodeBuiltin{f: isOutputPort, description: "output-port?"},
+		"eof-object":            &NodeBuiltin{f: eofObject, description: "eof-object"},
+		"eof-object?":           &NodeBuiltin{f: isEOFObject, description: "eof-object?"},
+		"with-output-to-string": &nodeControl{f: withOutputToString, description: "with-output-to-string"},
+		"call-with-output-file": &nodeControl{f: callWithOutputFile, description: "call-with-output-file"},
+	}
+}
+
+// NodePort is a textual port, reading from an io.Reader or writing to
+// an io.Writer
+type NodePort struct {
+	gol.NodeBase
+	name   string
+	r      *bufio.Reader
+	src    *pushback
+	w      io.Writer
+	closer io.Closer
+	closed bool
+}
+
+// NewInputPort makes a port which reads from r
+func NewInputPort(name string, r io.Reader) *NodePort {
+	src := &pushback{r: r}
+	return &NodePort{name: name, r: bufio.NewReader(src), src: src}
+}
+
+// NewOutputPort makes a port which writes to w
+func NewOutputPort(name string, w io.Writer) *NodePort {
+	return &NodePort{name: name, w: w}
+}
+
+func (np *NodePort) Pos() gol.Position {
+	return gol.Position{File: "<port>"}
+}
+
+func (np *NodePort) String() string {
+	if np.r != nil {
+		return fmt.Sprintf("#<input-port %s>", np.name)
+	}
+	return fmt.Sprintf("#<output-port %s>", np.name)
+}
+
+// Close closes the port, and what it reads or writes if that is a
+// file. Closing a port again does nothing.
+func (np *NodePort) Close() error {
+	if np.closed {
+		return nil
+	}
+	np.closed = true
+	if np.closer != nil {
+		return np.closer.Close()
+	}
+	return nil
+}
+
+// pushback is the source of an input port, with any input which a read
+// took too much of put back in front
+type pushback struct {
+	pending []byte
+	r       io.Reader
+}
+
+func (pb *pushback) Read(p []byte) (int, error) {
+	if len(pb.pending) > 0 {
+		n := copy(p, pb.pending)
+		pb.pending = pb.pending[n:]
+		return n, nil
+	}
+	return pb.r.Read(p)
+}
+
+// unread puts b back, to be read before anything else
+func (np *NodePort) unread(b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	buffered, _ := np.r.Peek(np.r.Buffered())
+	pending := append([]byte{}, b...)
+	pending = append(pending, buffered...)
+	np.src.pending = append(pending, np.src.pending...)
+	np.r.Reset(np.src)
+}
+
+// nodeEOF is the end of file object
+type nodeEOF struct {
+	gol.NodeBase
+}
+
+var theEOF = &nodeEOF{}
+
+func (ne *nodeEOF) Pos() gol.Position {
+	return gol.Position{File: "<eof>"}
+}
+
+func (ne *nodeEOF) String() string {
+	return "#<eof>"
+}
+
+// portArg is the port at index i of nodes, which is optional and
+// defaults to def
+func portArg(nodes *gol.NodeList, i int, def *NodePort, name string) (*NodePort, error) {
+	if nodes.Len() > i+1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected <= %d args", i+1)
+	}
+	port := def
+	if nodes.Len() == i+1 {
+		var ok bool
+		port, ok = nodes.Nth(i).(*NodePort)
+		if !ok {
+			return nil, gol.NodeErrorf(nodes, "Non-port passed to %s", name)
+		}
+	}
+	if port.closed {
+		return nil, gol.NodeErrorf(nodes, "Closed port passed to %s", name)
+	}
+	return port, nil
+}
+
+func inputPortArg(e *Evaluator, nodes *gol.NodeList, i int, name string) (*NodePort, error) {
+	port, err := portArg(nodes, i, e.in, name)
+	if err != nil {
+		return nil, err
+	}
+	if port.r == nil {
+		return nil, gol.NodeErrorf(nodes, "Non-input-port passed to %s", name)
+	}
+	return port, nil
+}
+
+func outputPortArg(e *Evaluator, nodes *gol.NodeList, i int, name string) (*NodePort, error) {
+	port, err := portArg(nodes, i, e.out, name)
+	if err != nil {
+		return nil, err
+	}
+	if port.w == nil {
+		return nil, gol.NodeErrorf(nodes, "Non-output-port passed to %s", name)
+	}
+	return port, nil
+}
+
+func currentOutputPort(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 0 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 0 args")
+	}
+	return e.out, nil
+}
+
+func currentInputPort(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 0 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 0 args")
+	}
+	return e.in, nil
+}
+
+func currentErrorPort(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 0 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 0 args")
+	}
+	return e.err, nil
+}
+
+func openInputFile(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	fname, err := stringArg(nodes, nodes.First(), "open-input-file")
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, gol.NodeErrorf(nodes, "Can't open file: %s", err)
+	}
+	port := NewInputPort(fname, f)
+	port.closer = f
+	return port, nil
+}
+
+// createOutputFile is the port for a new (or truncated) file
+func createOutputFile(nodes *gol.NodeList, name string) (*NodePort, error) {
+	fname, err := stringArg(nodes, nodes.First(), name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Create(fname)
+	if err != nil {
+		return nil, gol.NodeErrorf(nodes, "Can't open file: %s", err)
+	}
+	port := NewOutputPort(fname, f)
+	port.closer = f
+	return port, nil
+}
+
+func openOutputFile(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	return createOutputFile(nodes, "open-output-file")
+}
+
+func openInputString(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	s, err := stringArg(nodes, nodes.First(), "open-input-string")
+	if err != nil {
+		return nil, err
+	}
+	return NewInputPort("string", strings.NewReader(s)), nil
+}
+
+func openOutputString(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 0 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 0 args")
+	}
+	return NewOutputPort("string", &strings.Builder{}), nil
+}
+
+func getOutputString(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	port, ok := nodes.First().(*NodePort)
+	if !ok {
+		return nil, gol.NodeErrorf(nodes, "Non-port passed to get-output-string")
+	}
+	sb, ok := port.w.(*strings.Builder)
+	if !ok {
+		return nil, gol.NodeErrorf(nodes, "Non-string-port passed to get-output-string")
+	}
+	return gol.NewNodeString(sb.String()), nil
+}
+
+// readErr is the result of a read which failed with err, which is the
+// eof object at the end of input
+func readErr(nodes *gol.NodeList, err error) (gol.Node, error) {
+	if err == io.EOF {
+		return theEOF, nil
+	}
+	return nil, gol.NodeErrorf(nodes, "Read failed: %s", err)
+}
+
+func readLine(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	port, err := inputPortArg(e, nodes, 0, "read-line")
+	if err != nil {
+		return nil, err
+	}
+	line, err := port.r.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return readErr(nodes, err)
+	}
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+	return gol.NewNodeString(line), nil
+}
+
+func readChar(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	port, err := inputPortArg(e, nodes, 0, "read-char")
+	if err != nil {
+		return nil, err
+	}
+	r, _, err := port.r.ReadRune()
+	if err != nil {
+		return readErr(nodes, err)
+	}
+	return gol.NewNodeChar(r), nil
+}
+
+func peekChar(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	port, err := inputPortArg(e, nodes, 0, "peek-char")
+	if err != nil {
+		return nil, err
+	}
+	r, _, err := port.r.ReadRune()
+	if err != nil {
+		return readErr(nodes, err)
+	}
+	err = port.r.UnreadRune()
+	if err != nil {
+		return nil, gol.NodeErrorf(nodes, "Read failed: %s", err)
+	}
+	return gol.NewNodeChar(r), nil
+}
+
+// writeTo writes s to port, for the builtin name
+func writeTo(nodes *gol.NodeList, port *NodePort, s string) error {
+	_, err := io.WriteString(port.w, s)
+	if err != nil {
+		return gol.NodeErrorf(nodes, "Write failed: %s", err)
+	}
+	return nil
+}
+
+func writeString(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() < 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected >= 1 args")
+	}
+	s, err := stringArg(nodes, nodes.First(), "write-string")
+	if err != nil {
+		return nil, err
+	}
+	port, err := outputPortArg(e, nodes, 1, "write-string")
+	if err != nil {
+		return nil, err
+	}
+	return gol.Nil(), writeTo(nodes, port, s)
+}
+
+func closePort(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	port, ok := nodes.First().(*NodePort)
+	if !ok {
+		return nil, gol.NodeErrorf(nodes, "Non-port passed to close-port")
+	}
+	err := port.Close()
+	if err != nil {
+		return nil, gol.NodeErrorf(nodes, "Close failed: %s", err)
+	}
+	return gol.Nil(), nil
+}
+
+func isPort(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	_, ok := nodes.First().(*NodePort)
+	return gol.NewNodeBool(ok), nil
+}
+
+func isInputPort(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	port, ok := nodes.First().(*NodePort)
+	return gol.NewNodeBool(ok && port.r != nil), nil
+}
+
+func isOutputPort(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	port, ok := nodes.First().(*NodePort)
+	return gol.NewNodeBool(ok && port.w != nil), nil
+}
+
+func eofObject(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 0 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 0 args")
+	}
+	return theEOF, nil
+}
+
+func isEOFObject(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	_, ok := nodes.First().(*nodeEOF)
+	return gol.NewNodeBool(ok), nil
+}
+
+// withOutputToString applies thunk with a string port as the current
+// output port, and returns what it wrote. The port is swapped in and
+// out as for dynamic-wind, so escapes from the thunk restore the
+// original.
+func withOutputToString(e *Evaluator, nodes *gol.NodeList, k *continuation) (step, error) {
+	if nodes.Len() != 1 {
+		return step{}, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	var sb strings.Builder
+	port := NewOutputPort("string", &sb)
+	var outer *NodePort
+	before := &NodeBuiltin{
+		f: func(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+			outer, e.out = e.out, port
+			return gol.Nil(), nil
+		},
+		description: "with-output-to-string",
+	}
+	after := &NodeBuiltin{
+		f: func(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+			e.out = outer
+			return gol.Nil(), nil
+		},
+		description: "with-output-to-string",
+	}
+
+	k = push(k, func(e *Evaluator, value gol.Node, k *continuation) (step, error) {
+		return step{value: gol.NewNodeString(sb.String()), k: k}, nil
+	})
+	return dynamicWind(e, gol.NewNodeList().Cons(after).Cons(nodes.First()).Cons(before), k)
+}
+
+// callWithOutputFile applies proc to a port for the file, and closes it
+// when proc returns
+func callWithOutputFile(e *Evaluator, nodes *gol.NodeList, k *continuation) (step, error) {
+	if nodes.Len() != 2 {
+		return step{}, gol.NodeErrorf(nodes, "Arity-error: expected == 2 args")
+	}
+	port, err := createOutputFile(nodes, "call-with-output-file")
+	if err != nil {
+		return step{}, err
+	}
+	k = push(k, func(e *Evaluator, value gol.Node, k *continuation) (step, error) {
+		err := port.Close()
+		if err != nil {
+			return step{}, gol.NodeErrorf(nodes, "Close failed: %s", err)
+		}
+		return step{value: value, k: k}, nil
+	})
+	return step{apply: gol.NewNodeList().Cons(port).Cons(nodes.Nth(1)), k: k}, nil
+}
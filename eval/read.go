@@ -0,0 +1,58 @@
+package eval
+
+import (
+	"strings"
+
+	"github.com/jbert/gol"
+)
+
+func readBuiltins() gol.Frame {
+	return gol.Frame{
+		"read":             &NodeBuiltin{f: read, description: "read"},
+		"read-from-string": &NodeBuiltin{f: readFromString, description: "read-from-string"},
+		"write":            &NodeBuiltin{f: write, description: "write"},
+	}
+}
+
+// read parses the next datum from a port. The result is data, as if it
+// had been quoted.
+func read(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	port, err := inputPortArg(e, nodes, 0, "read")
+	if err != nil {
+		return nil, err
+	}
+	datum, unread, err := gol.ReadDatum(port.name, port.r)
+	port.unread(unread)
+	if err != nil {
+		return readErr(nodes, err)
+	}
+	return datum, nil
+}
+
+func readFromString(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	s, err := stringArg(nodes, nodes.First(), "read-from-string")
+	if err != nil {
+		return nil, err
+	}
+	datum, _, err := gol.ReadDatum("string", strings.NewReader(s))
+	if err != nil {
+		return readErr(nodes, err)
+	}
+	return datum, nil
+}
+
+// write writes the external representation of a value, which read
+// reads back as an equal datum
+func write(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() < 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected >= 1 args")
+	}
+	port, err := outputPortArg(e, nodes, 1, "write")
+	if err != nil {
+		return nil, err
+	}
+	return gol.Nil(), writeTo(nodes, port, gol.WriteString(nodes.First()))
+}
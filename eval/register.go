@@ -0,0 +1,61 @@
+package eval
+
+import (
+	"strings"
+
+	"github.com/jbert/gol"
+)
+
+func init() {
+	var names []string
+	for name := range builtinFrame() {
+		names = append(names, name)
+	}
+	// The standard library's defines hide registered functions too
+	stdlib, err := gol.ParseReader("<stdlib>", strings.NewReader(gol.STDLIB))
+	if err != nil {
+		panic(err)
+	}
+	stdlib.(*gol.NodeProgn).Foreach(func(n gol.Node) error {
+		if nd, ok := n.(*gol.NodeDefine); ok {
+			names = append(names, nd.Symbol.String())
+		}
+		return nil
+	})
+	gol.AddBuiltins(names...)
+}
+
+// registeredBuiltins are the Go functions added with gol.Register
+func registeredBuiltins() gol.Frame {
+	f := gol.Frame{}
+	for _, rf := range gol.Registered() {
+		f[rf.Name] = &NodeBuiltin{f: callRegistered(rf), description: rf.Name}
+	}
+	return f
+}
+
+func callRegistered(rf *gol.RegisteredFunc) func(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	return func(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+		var args []gol.Node
+		nodes.Foreach(func(n gol.Node) error {
+			args = append(args, n)
+			return nil
+		})
+		results, err := rf.Call(args)
+		if err != nil {
+			return nil, gol.NodeErrorf(nodes, "%s", err)
+		}
+		switch len(results) {
+		case 0:
+			return gol.Nil(), nil
+		case 1:
+			return results[0], nil
+		default:
+			nl := gol.NewNodeList()
+			for _, result := range results {
+				nl = nl.Append(result)
+			}
+			return values(e, nl)
+		}
+	}
+}
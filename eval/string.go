@@ -0,0 +1,290 @@
+package eval
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/jbert/gol"
+)
+
+func stringBuiltins() gol.Frame {
+	return gol.Frame{
+		"string?":        &NodeBuiltin{f: stringp, description: "string?"},
+		"string-length":  &NodeBuiltin{f: stringLength, description: "string-length"},
+		"string-ref":     &NodeBuiltin{f: stringRef, description: "string-ref"},
+		"substring":      &NodeBuiltin{f: substring, description: "substring"},
+		"string-append":  &NodeBuiltin{f: stringAppend, description: "string-append"},
+		"string->number": &NodeBuiltin{f: stringToNumber, description: "string->number"},
+		"number->string": &NodeBuiltin{f: numberToString, description: "number->string"},
+		"string->symbol": &NodeBuiltin{f: stringToSymbol, description: "string->symbol"},
+		"string-split":   &NodeBuiltin{f: stringSplit, description: "string-split"},
+		"string-join":    &NodeBuiltin{f: stringJoin, description: "string-join"},
+		"string-index":   &nodeControl{f: stringIndex, description: "string-index"},
+	}
+}
+
+func stringArg(nodes *gol.NodeList, n gol.Node, name string) (string, error) {
+	ns, ok := n.(*gol.NodeString)
+	if !ok {
+		return "", gol.NodeErrorf(nodes, "Non-string passed to %s", name)
+	}
+	return ns.Value(), nil
+}
+
+// indexArg checks that n is an int in [0, max]
+func indexArg(nodes *gol.NodeList, n gol.Node, max int, name string) (int, error) {
+	ni, ok := n.(*gol.NodeInt)
+	if !ok {
+		return 0, gol.NodeErrorf(nodes, "Non-int index passed to %s", name)
+	}
+	if ni.IsBig() || ni.Value() < 0 || ni.Value() > int64(max) {
+		return 0, gol.NodeErrorf(nodes, "Index out of range for %s: %s", name, ni)
+	}
+	return int(ni.Value()), nil
+}
+
+// radixArg returns the optional radix in position i, defaulting to 10
+func radixArg(nodes *gol.NodeList, i int, name string) (int, error) {
+	if nodes.Len() <= i {
+		return 10, nil
+	}
+	ni, ok := nodes.Nth(i).(*gol.NodeInt)
+	if !ok || ni.IsBig() || ni.Value() < 2 || ni.Value() > 36 {
+		return 0, gol.NodeErrorf(nodes, "Bad radix passed to %s: %s", name, nodes.Nth(i))
+	}
+	return int(ni.Value()), nil
+}
+
+func stringp(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	_, ok := nodes.First().(*gol.NodeString)
+	return gol.NewNodeBool(ok), nil
+}
+
+func stringLength(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	s, err := stringArg(nodes, nodes.First(), "string-length")
+	if err != nil {
+		return nil, err
+	}
+	return gol.NewNodeInt(int64(len([]rune(s)))), nil
+}
+
+func stringRef(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 2 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 2 args")
+	}
+	s, err := stringArg(nodes, nodes.First(), "string-ref")
+	if err != nil {
+		return nil, err
+	}
+	runes := []rune(s)
+	k, err := indexArg(nodes, nodes.Nth(1), len(runes)-1, "string-ref")
+	if err != nil {
+		return nil, err
+	}
+	return gol.NewNodeChar(runes[k]), nil
+}
+
+func substring(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 2 && nodes.Len() != 3 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected 2 or 3 args")
+	}
+	s, err := stringArg(nodes, nodes.First(), "substring")
+	if err != nil {
+		return nil, err
+	}
+	runes := []rune(s)
+	end := len(runes)
+	if nodes.Len() == 3 {
+		end, err = indexArg(nodes, nodes.Nth(2), len(runes), "substring")
+		if err != nil {
+			return nil, err
+		}
+	}
+	start, err := indexArg(nodes, nodes.Nth(1), end, "substring")
+	if err != nil {
+		return nil, err
+	}
+	return gol.NewNodeString(string(runes[start:end])), nil
+}
+
+func stringAppend(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	var sb strings.Builder
+	err := nodes.Foreach(func(n gol.Node) error {
+		s, err := stringArg(nodes, n, "string-append")
+		if err != nil {
+			return err
+		}
+		sb.WriteString(s)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return gol.NewNodeString(sb.String()), nil
+}
+
+func stringToNumber(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 && nodes.Len() != 2 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected 1 or 2 args")
+	}
+	s, err := stringArg(nodes, nodes.First(), "string->number")
+	if err != nil {
+		return nil, err
+	}
+	radix, err := radixArg(nodes, 1, "string->number")
+	if err != nil {
+		return nil, err
+	}
+	if radix != 10 {
+		b, ok := new(big.Int).SetString(s, radix)
+		if !ok {
+			return gol.NODE_FALSE, nil
+		}
+		return gol.NewNodeBigInt(b), nil
+	}
+	n, err := gol.ParseNumber(s)
+	if err != nil {
+		return gol.NODE_FALSE, nil
+	}
+	return n, nil
+}
+
+func numberToString(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 && nodes.Len() != 2 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected 1 or 2 args")
+	}
+	n := nodes.First()
+	if !isNumber(n) {
+		return nil, gol.NodeErrorf(nodes, "Non-number passed to number->string")
+	}
+	radix, err := radixArg(nodes, 1, "number->string")
+	if err != nil {
+		return nil, err
+	}
+	if radix == 10 {
+		return gol.NewNodeString(n.String()), nil
+	}
+	ni, ok := n.(*gol.NodeInt)
+	if !ok {
+		return nil, gol.NodeErrorf(nodes, "Radix only supported for integers in number->string")
+	}
+	return gol.NewNodeString(ni.BigValue().Text(radix)), nil
+}
+
+func stringToSymbol(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	s, err := stringArg(nodes, nodes.First(), "string->symbol")
+	if err != nil {
+		return nil, err
+	}
+	return gol.NewNodeIdentifier(s), nil
+}
+
+// stringSplit splits on a separator string, or on whitespace if none is given
+func stringSplit(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 && nodes.Len() != 2 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected 1 or 2 args")
+	}
+	s, err := stringArg(nodes, nodes.First(), "string-split")
+	if err != nil {
+		return nil, err
+	}
+	var parts []string
+	if nodes.Len() == 2 {
+		sep, err := stringArg(nodes, nodes.Nth(1), "string-split")
+		if err != nil {
+			return nil, err
+		}
+		parts = strings.Split(s, sep)
+	} else {
+		parts = strings.Fields(s)
+	}
+	ret := gol.NewNodeList()
+	for i := len(parts) - 1; i >= 0; i-- {
+		ret = ret.Cons(gol.NewNodeString(parts[i]))
+	}
+	return ret, nil
+}
+
+// stringJoin joins a list of strings, with a single space if no
+// separator is given
+func stringJoin(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 && nodes.Len() != 2 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected 1 or 2 args")
+	}
+	nl, ok := nodes.First().(*gol.NodeList)
+	if !ok {
+		return nil, gol.NodeErrorf(nodes, "Non-list passed to string-join")
+	}
+	sep := " "
+	if nodes.Len() == 2 {
+		var err error
+		sep, err = stringArg(nodes, nodes.Nth(1), "string-join")
+		if err != nil {
+			return nil, err
+		}
+	}
+	var parts []string
+	err := nl.Foreach(func(n gol.Node) error {
+		s, err := stringArg(nodes, n, "string-join")
+		if err != nil {
+			return err
+		}
+		parts = append(parts, s)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return gol.NewNodeString(strings.Join(parts, sep)), nil
+}
+
+// stringIndex returns the index of the first char in the string which
+// matches a char or satisfies a predicate, or #f if there is none
+func stringIndex(e *Evaluator, nodes *gol.NodeList, k *continuation) (step, error) {
+	if nodes.Len() != 2 {
+		return step{}, gol.NodeErrorf(nodes, "Arity-error: expected == 2 args")
+	}
+	s, err := stringArg(nodes, nodes.First(), "string-index")
+	if err != nil {
+		return step{}, err
+	}
+	runes := []rune(s)
+	switch m := nodes.Nth(1).(type) {
+	case *gol.NodeChar:
+		for i, r := range runes {
+			if r == m.Value() {
+				return step{value: gol.NewNodeInt(int64(i)), k: k}, nil
+			}
+		}
+		return step{value: gol.NODE_FALSE, k: k}, nil
+	case NodeApplicable:
+		// The predicate runs on our machine, as in vector-map
+		var indexFrom func(i int, k *continuation) (step, error)
+		indexFrom = func(i int, k *continuation) (step, error) {
+			if i == len(runes) {
+				return step{value: gol.NODE_FALSE, k: k}, nil
+			}
+			app := gol.NewNodeList().Cons(gol.NewNodeChar(runes[i])).Cons(m)
+			app.SetSpan(nodes.Span())
+			return step{apply: app, k: push(k, func(e *Evaluator, result gol.Node, k *continuation) (step, error) {
+				nb, isBool := result.(*gol.NodeBool)
+				if !isBool || nb.IsTrue() {
+					return step{value: gol.NewNodeInt(int64(i)), k: k}, nil
+				}
+				return indexFrom(i+1, k)
+			})}, nil
+		}
+		return indexFrom(0, k)
+	default:
+		return step{}, gol.NodeErrorf(nodes, "string-index needs a char or a predicate")
+	}
+}
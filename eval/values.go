@@ -0,0 +1,58 @@
+package eval
+
+import (
+	"strings"
+
+	"github.com/jbert/gol"
+)
+
+func valuesBuiltins() gol.Frame {
+	return gol.Frame{
+		"values":           &NodeBuiltin{f: values, description: "values"},
+		"call-with-values": &nodeControl{f: callWithValues, description: "call-with-values"},
+	}
+}
+
+// NodeValues is the result of values with other than one arg. A single
+// value is returned as itself.
+type NodeValues struct {
+	gol.NodeBase
+	values *gol.NodeList
+}
+
+func (nv *NodeValues) Pos() gol.Position {
+	return gol.Position{File: "<values>"}
+}
+
+func (nv *NodeValues) String() string {
+	var strs []string
+	nv.values.Foreach(func(n gol.Node) error {
+		strs = append(strs, n.String())
+		return nil
+	})
+	return strings.Join(strs, " ")
+}
+
+func values(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() == 1 {
+		return nodes.First(), nil
+	}
+	return &NodeValues{values: nodes}, nil
+}
+
+// callWithValues applies the consumer to the values of the producer, as
+// a tail call
+func callWithValues(e *Evaluator, nodes *gol.NodeList, k *continuation) (step, error) {
+	if nodes.Len() != 2 {
+		return step{}, gol.NodeErrorf(nodes, "Arity-error: expected == 2 args")
+	}
+	producer, consumer := nodes.First(), nodes.Nth(1)
+	k = push(k, func(e *Evaluator, value gol.Node, k *continuation) (step, error) {
+		args := gol.NewNodeList().Cons(value)
+		if nv, ok := value.(*NodeValues); ok {
+			args = nv.values
+		}
+		return step{apply: args.Cons(consumer), k: k}, nil
+	})
+	return step{apply: gol.NewNodeList().Cons(producer), k: k}, nil
+}
@@ -0,0 +1,234 @@
+package eval
+
+import (
+	"github.com/jbert/gol"
+)
+
+func vectorBuiltins() gol.Frame {
+	return gol.Frame{
+		"vector?":         &NodeBuiltin{f: vectorp, description: "vector?"},
+		"vector":          &NodeBuiltin{f: vector, description: "vector"},
+		"make-vector":     &NodeBuiltin{f: makeVector, description: "make-vector"},
+		"vector-length":   &NodeBuiltin{f: vectorLength, description: "vector-length"},
+		"vector-ref":      &NodeBuiltin{f: vectorRef, description: "vector-ref"},
+		"vector-set!":     &NodeBuiltin{f: vectorSet, description: "vector-set!"},
+		"vector->list":    &NodeBuiltin{f: vectorToList, description: "vector->list"},
+		"list->vector":    &NodeBuiltin{f: listToVector, description: "list->vector"},
+		"vector-map":      &nodeControl{f: vectorMap, description: "vector-map"},
+		"vector-for-each": &nodeControl{f: vectorForEach, description: "vector-for-each"},
+	}
+}
+
+func vectorArg(nodes *gol.NodeList, n gol.Node, name string) (*gol.NodeVector, error) {
+	nv, ok := n.(*gol.NodeVector)
+	if !ok {
+		return nil, gol.NodeErrorf(nodes, "Non-vector passed to %s", name)
+	}
+	return nv, nil
+}
+
+// vectorIndex checks that n is a valid index into nv
+func vectorIndex(nodes *gol.NodeList, nv *gol.NodeVector, n gol.Node, name string) (int, error) {
+	ni, ok := n.(*gol.NodeInt)
+	if !ok {
+		return 0, gol.NodeErrorf(nodes, "Non-int index passed to %s", name)
+	}
+	if ni.IsBig() || ni.Value() < 0 || ni.Value() >= int64(nv.Len()) {
+		return 0, gol.NodeErrorf(nodes, "Index out of range for %s: %s", name, ni)
+	}
+	return int(ni.Value()), nil
+}
+
+func vectorp(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	_, ok := nodes.First().(*gol.NodeVector)
+	return gol.NewNodeBool(ok), nil
+}
+
+func vector(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	return listToVector(e, gol.NewNodeList().Cons(nodes))
+}
+
+func makeVector(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 && nodes.Len() != 2 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected 1 or 2 args")
+	}
+	ni, ok := nodes.First().(*gol.NodeInt)
+	if !ok || ni.IsBig() || ni.Value() < 0 {
+		return nil, gol.NodeErrorf(nodes, "Bad length passed to make-vector: %s", nodes.First())
+	}
+	var fill gol.Node = gol.NewNodeInt(0)
+	if nodes.Len() == 2 {
+		fill = nodes.Nth(1)
+	}
+	elems := make([]gol.Node, ni.Value())
+	for i := range elems {
+		elems[i] = fill
+	}
+	return gol.NewNodeVector(elems), nil
+}
+
+func vectorLength(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	nv, err := vectorArg(nodes, nodes.First(), "vector-length")
+	if err != nil {
+		return nil, err
+	}
+	return gol.NewNodeInt(int64(nv.Len())), nil
+}
+
+func vectorRef(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 2 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 2 args")
+	}
+	nv, err := vectorArg(nodes, nodes.First(), "vector-ref")
+	if err != nil {
+		return nil, err
+	}
+	i, err := vectorIndex(nodes, nv, nodes.Nth(1), "vector-ref")
+	if err != nil {
+		return nil, err
+	}
+	return nv.Ref(i), nil
+}
+
+func vectorSet(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 3 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 3 args")
+	}
+	nv, err := vectorArg(nodes, nodes.First(), "vector-set!")
+	if err != nil {
+		return nil, err
+	}
+	i, err := vectorIndex(nodes, nv, nodes.Nth(1), "vector-set!")
+	if err != nil {
+		return nil, err
+	}
+	nv.Set(i, nodes.Nth(2))
+	return gol.Nil(), nil
+}
+
+func vectorToList(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	nv, err := vectorArg(nodes, nodes.First(), "vector->list")
+	if err != nil {
+		return nil, err
+	}
+	ret := gol.NewNodeList()
+	elems := nv.Elems()
+	for i := len(elems) - 1; i >= 0; i-- {
+		ret = ret.Cons(elems[i])
+	}
+	return ret, nil
+}
+
+func listToVector(e *Evaluator, nodes *gol.NodeList) (gol.Node, error) {
+	if nodes.Len() != 1 {
+		return nil, gol.NodeErrorf(nodes, "Arity-error: expected == 1 args")
+	}
+	nl, ok := nodes.First().(*gol.NodeList)
+	if !ok {
+		return nil, gol.NodeErrorf(nodes, "Non-list passed to list->vector")
+	}
+	elems := make([]gol.Node, 0, nl.Len())
+	err := nl.Foreach(func(n gol.Node) error {
+		elems = append(elems, n)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return gol.NewNodeVector(elems), nil
+}
+
+// vectorArgs checks for a procedure followed by one or more vectors, and
+// returns the length of the shortest vector
+func vectorArgs(nodes *gol.NodeList, name string) (NodeApplicable, []*gol.NodeVector, int, error) {
+	if nodes.Len() < 2 {
+		return nil, nil, 0, gol.NodeErrorf(nodes, "Arity-error: expected >= 2 args")
+	}
+	f, ok := nodes.First().(NodeApplicable)
+	if !ok {
+		return nil, nil, 0, gol.NodeErrorf(nodes, "Non-procedure passed to %s", name)
+	}
+	var vectors []*gol.NodeVector
+	shortest := -1
+	err := nodes.Rest().Foreach(func(n gol.Node) error {
+		nv, err := vectorArg(nodes, n, name)
+		if err != nil {
+			return err
+		}
+		if shortest < 0 || nv.Len() < shortest {
+			shortest = nv.Len()
+		}
+		vectors = append(vectors, nv)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return f, vectors, shortest, nil
+}
+
+// applicationAt is the application of f to the i'th element of each
+// vector
+func applicationAt(nodes *gol.NodeList, f NodeApplicable, vectors []*gol.NodeVector, i int) *gol.NodeList {
+	app := gol.NewNodeList()
+	for j := len(vectors) - 1; j >= 0; j-- {
+		app = app.Cons(vectors[j].Ref(i))
+	}
+	app = app.Cons(f)
+	// So that errors from the application point at the source
+	app.SetSpan(nodes.Span())
+	return app
+}
+
+// vectorMap applies f on our machine rather than a nested one, so that a
+// continuation captured by f can be resumed after we return
+func vectorMap(e *Evaluator, nodes *gol.NodeList, k *continuation) (step, error) {
+	f, vectors, n, err := vectorArgs(nodes, "vector-map")
+	if err != nil {
+		return step{}, err
+	}
+	// The results so far are consed on in reverse, which leaves them as
+	// they were if a continuation resumes here again
+	var mapFrom func(i int, revElems *gol.NodeList, k *continuation) (step, error)
+	mapFrom = func(i int, revElems *gol.NodeList, k *continuation) (step, error) {
+		if i == n {
+			elems := make([]gol.Node, n)
+			revElems.Foreach(func(elem gol.Node) error {
+				i--
+				elems[i] = elem
+				return nil
+			})
+			return step{value: gol.NewNodeVector(elems), k: k}, nil
+		}
+		return step{apply: applicationAt(nodes, f, vectors, i), k: push(k, func(e *Evaluator, value gol.Node, k *continuation) (step, error) {
+			return mapFrom(i+1, revElems.Cons(value), k)
+		})}, nil
+	}
+	return mapFrom(0, gol.NewNodeList(), k)
+}
+
+func vectorForEach(e *Evaluator, nodes *gol.NodeList, k *continuation) (step, error) {
+	f, vectors, n, err := vectorArgs(nodes, "vector-for-each")
+	if err != nil {
+		return step{}, err
+	}
+	var forEachFrom func(i int, k *continuation) (step, error)
+	forEachFrom = func(i int, k *continuation) (step, error) {
+		if i == n {
+			return step{value: gol.Nil(), k: k}, nil
+		}
+		return step{apply: applicationAt(nodes, f, vectors, i), k: push(k, func(e *Evaluator, value gol.Node, k *continuation) (step, error) {
+			return forEachFrom(i+1, k)
+		})}, nil
+	}
+	return forEachFrom(0, k)
+}
@@ -4,50 +4,32 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math"
 	"math/rand"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"text/template"
 
 	"github.com/jbert/gol"
+	"github.com/jbert/gol/eval"
 	"github.com/jbert/gol/typ"
 )
 
-// TODO: pull this out as ParseFile and call from Evaluatator too
 func CompileReader(filename string, r io.Reader, outFilename string) error {
-	l := gol.NewLexer(filename, r)
-
-	// Run the lexer until EOF or error
-	var lexErr error
-	lexDone := make(chan struct{})
-	go func() {
-		lexErr = l.Run()
-		close(lexDone)
-	}()
-
-	// Run the parser until the lexer finishes
-	p := gol.NewParser(l.Tokens)
-	nodeTree, parseErr := p.Parse()
-	if parseErr != nil {
-		return parseErr
-	}
-
-	// Hoover up any lexing errors
-	<-lexDone
-	if lexErr != nil {
-		return lexErr
+	// Procedural macros are run by the interpreter
+	ev, err := eval.NewMacroEvaluator()
+	if err != nil {
+		return err
 	}
-
-	// We have a basic parse tree, decorate it with additional
-	// node information
-	nodeTree, parseErr = gol.Transform(nodeTree)
-	if parseErr != nil {
-		return parseErr
+	nodeTree, err := gol.ParseReaderWithEvaluator(filename, r, ev)
+	if err != nil {
+		return err
 	}
 
 	gb := NewGolangBackend(nodeTree)
-	err := gb.InferTypes()
+	err = gb.InferTypes()
 	if err != nil {
 		return err
 	}
@@ -71,6 +53,10 @@ func CompileFile(filename string, outFilename string) error {
 type GolangBackend struct {
 	parseTree     gol.Node
 	topLevelDefns []string
+	// The registered functions compiled code can call, and those the
+	// program uses
+	registered     map[string]*gol.RegisteredFunc
+	usedRegistered map[string]*gol.RegisteredFunc
 }
 
 func NewGolangBackend(parseTree gol.Node) *GolangBackend {
@@ -95,7 +81,18 @@ func (gb *GolangBackend) CompileTo(outFilename string) error {
 	defer f.Close()
 	//defer os.Remove(tmpGoFilename)
 
-	preamble, err := gb.compilePreamble()
+	// Compiled first, to find the registered functions the preamble
+	// needs to import
+	code, err := gb.compileBody()
+	if err != nil {
+		return fmt.Errorf("Failed to compile to go code : %s", err)
+	}
+	imports, wrappers, err := gb.compileRegistered()
+	if err != nil {
+		return fmt.Errorf("Failed to compile to go code : %s", err)
+	}
+
+	preamble, err := gb.compilePreamble(imports)
 	if err != nil {
 		return fmt.Errorf("Failed to make preamble: %s", err)
 	}
@@ -104,10 +101,6 @@ func (gb *GolangBackend) CompileTo(outFilename string) error {
 		return fmt.Errorf("Failed to write preamble: %s", err)
 	}
 
-	code, err := gb.compileBody()
-	if err != nil {
-		return fmt.Errorf("Failed to compile to go code : %s", err)
-	}
 	_, err = io.WriteString(f, code)
 	if err != nil {
 		return fmt.Errorf("Failed to write go code: %s", err)
@@ -126,6 +119,10 @@ func (gb *GolangBackend) CompileTo(outFilename string) error {
 	if err != nil {
 		return fmt.Errorf("Failed to write standard lib: %s", err)
 	}
+	_, err = io.WriteString(f, wrappers)
+	if err != nil {
+		return fmt.Errorf("Failed to write registered functions: %s", err)
+	}
 
 	err = gb.buildGo(tmpGoFilename, outFilename)
 	if err != nil {
@@ -136,14 +133,17 @@ func (gb *GolangBackend) CompileTo(outFilename string) error {
 }
 
 func (gb *GolangBackend) neededPackages() []string {
-	return []string{"fmt"}
+	return []string{"fmt", "math", "math/big", "os", "reflect", "strconv", "strings", "unicode"}
 }
 
-func (gb *GolangBackend) compilePreamble() (string, error) {
+// compilePreamble imports the needed packages, and the named imports
+func (gb *GolangBackend) compilePreamble(imports []string) (string, error) {
 	info := struct {
 		Packages []string
+		Imports  []string
 	}{
 		Packages: gb.neededPackages(),
+		Imports:  imports,
 	}
 	tmpl := template.Must(template.New("preamble").Parse(templatePreamble))
 
@@ -159,10 +159,12 @@ func (gb *GolangBackend) compilePreamble() (string, error) {
 var templatePreamble = `package main
 
 import (
-	{{range .Packages}} "{{.}}" {{end}}
-) 
+{{range .Packages}}	"{{.}}"
+{{end}}{{range .Imports}}	{{.}}
+{{end}}) 
 
 func main() {
+	defer reportUncaught()
 `
 
 func (gb *GolangBackend) compileBody() (string, error) {
@@ -184,10 +186,18 @@ func (gb *GolangBackend) compile(node gol.Node) (string, error) {
 	switch n := node.(type) {
 	case *gol.NodeInt:
 		return gb.compileInt(n)
+	case *gol.NodeReal:
+		return gb.compileReal(n)
+	case *gol.NodeRational:
+		return gb.compileRational(n)
 	case *gol.NodeString:
 		return gb.compileString(n)
 	case *gol.NodeBool:
 		return gb.compileBool(n)
+	case *gol.NodeChar:
+		return gb.compileChar(n)
+	case *gol.NodeVector:
+		return gb.compileVector(n)
 
 	case *gol.NodeProgn:
 		return gb.compileProgn(n)
@@ -284,6 +294,10 @@ func (gb *GolangBackend) compileIf(ni *gol.NodeIf) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if isAny(ni.Condition) {
+		// Anything but #f is true
+		ifExpr = "(" + ifExpr + ") != false"
+	}
 	tExpr, err := gb.compile(ni.TBranch)
 	if err != nil {
 		return "", err
@@ -324,8 +338,12 @@ func (gb *GolangBackend) compileAnonOrNamedLambda(nl *gol.NodeLambda, name strin
 		return "", fmt.Errorf("Lambda doesn't have function type: %s [%T]\n", nl.Type(), nl.Type())
 	}
 
-	if nl.Args.Len() != len(funcType.Args) {
-		return "", fmt.Errorf("Arg/type mismatch: %d != %d\n", nl.Args.Len(), len(funcType.Args))
+	numArgs := nl.Args.Len()
+	if nl.Rest != nil {
+		numArgs++
+	}
+	if numArgs != len(funcType.Args) {
+		return "", fmt.Errorf("Arg/type mismatch: %d != %d\n", numArgs, len(funcType.Args))
 	}
 
 	i := 0
@@ -345,11 +363,28 @@ func (gb *GolangBackend) compileAnonOrNamedLambda(nl *gol.NodeLambda, name strin
 		return "", err
 	}
 
+	// A variadic golang func, whose trailing args become the rest list
+	restDecl := ""
+	if nl.Rest != nil {
+		restArg := mangleIdentifier(nl.Rest.String())
+		variadicType, err := golangStringForType(funcType.Args[i])
+		if err != nil {
+			return "", err
+		}
+		listType, err := golangStringForType(nl.Rest.Type())
+		if err != nil {
+			return "", err
+		}
+		strArgs[i] = fmt.Sprintf("%s__REST__ %s", restArg, variadicType)
+		restDecl = fmt.Sprintf("%s := %s(%s__REST__)\n_ = %s\n", restArg, listType, restArg, restArg)
+	}
+
 	golangRetType, err := golangStringForType(funcType.Result)
 	if err != nil {
 		return "", err
 	}
 	s := fmt.Sprintf("func %s(%s) %s {", mangleIdentifier(name), strings.Join(strArgs, ", "), golangRetType)
+	s += restDecl
 	body, err := gb.compile(nl.Body)
 	if err != nil {
 		return "", err
@@ -360,20 +395,25 @@ func (gb *GolangBackend) compileAnonOrNamedLambda(nl *gol.NodeLambda, name strin
 }
 
 func (gb *GolangBackend) compileIdentifier(ni *gol.NodeIdentifier) (string, error) {
+	gb.noteRegistered(ni.String())
 	return mangleIdentifier(ni.String()), nil
 }
 
 func (gb *GolangBackend) compileLet(nl *gol.NodeLet) (string, error) {
+	if nl.Rec {
+		return gb.compileLetRec(nl)
+	}
 	args := []string{}
 	vals := []string{}
 
-	for k, vNode := range nl.Bindings {
-		golangType, err := golangStringForType(vNode.Type())
+	// In source order, so the inits are evaluated in order
+	for _, b := range nl.Bindings {
+		golangType, err := golangStringForType(b.Value.Type())
 		if err != nil {
 			return "", err
 		}
-		args = append(args, fmt.Sprintf("%s %s", mangleIdentifier(k), golangType))
-		val, err := gb.compile(vNode)
+		args = append(args, fmt.Sprintf("%s %s", mangleIdentifier(b.Id.String()), golangType))
+		val, err := gb.compile(b.Value)
 		if err != nil {
 			return "", err
 		}
@@ -395,12 +435,50 @@ func (gb *GolangBackend) compileLet(nl *gol.NodeLet) (string, error) {
 	return s, nil
 }
 
+// compileLetRec declares the vars before assigning any of them, so the
+// values can refer to each other
+func (gb *GolangBackend) compileLetRec(nl *gol.NodeLet) (string, error) {
+	golangRetType, err := golangStringForType(nl.Type())
+	if err != nil {
+		return "", err
+	}
+	lines := []string{fmt.Sprintf("func() %s {", golangRetType)}
+	for _, b := range nl.Bindings {
+		golangType, err := golangStringForType(b.Value.Type())
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, gb.declareVar(mangleIdentifier(b.Id.String()), golangType))
+	}
+	for _, b := range nl.Bindings {
+		val, err := gb.compile(b.Value)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, fmt.Sprintf("%s = %s", mangleIdentifier(b.Id.String()), val))
+	}
+	body, err := gb.compile(nl.Body)
+	if err != nil {
+		return "", err
+	}
+	lines = append(lines, "return "+body, "}()")
+	return strings.Join(lines, "\n"), nil
+}
+
 func (gb *GolangBackend) compileList(nl *gol.NodeList) (string, error) {
 	if nl.Len() == 0 {
 		return "", gol.NodeErrorf(nl, "empty application")
 	}
 	switch fst := nl.First().(type) {
 	case *gol.NodeIdentifier:
+		switch fst.String() {
+		case "values":
+			return gb.compileValues(nl)
+		case "call-with-values", "%call-with-values":
+			return gb.compileCallWithValues(nl)
+		case "apply", "%apply":
+			return gb.compileApply(nl)
+		}
 		return gb.compileFuncCall(fst, nl.Rest())
 	case *gol.NodeLambda:
 		return gb.compileLambdaApplication(fst, nl.Rest())
@@ -409,47 +487,333 @@ func (gb *GolangBackend) compileList(nl *gol.NodeList) (string, error) {
 	}
 }
 
+// compileValues returns golang multiple values, from a func so that
+// they can be returned or passed on as they are
+func (gb *GolangBackend) compileValues(nl *gol.NodeList) (string, error) {
+	args := nl.Rest()
+	if args.Len() == 1 {
+		return gb.compile(args.First())
+	}
+	golangRetType, err := golangStringForType(nl.Type())
+	if err != nil {
+		return "", err
+	}
+	vals := []string{}
+	err = args.Foreach(func(n gol.Node) error {
+		val, err := gb.compile(n)
+		if err != nil {
+			return err
+		}
+		vals = append(vals, val)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("func() %s { return %s }()", golangRetType, strings.Join(vals, ", ")), nil
+}
+
+// compileCallWithValues passes the producer's multiple results straight
+// to the consumer, as golang allows for f(g())
+func (gb *GolangBackend) compileCallWithValues(nl *gol.NodeList) (string, error) {
+	if nl.Len() != 3 {
+		return "", gol.NodeErrorf(nl, "Arity-error: call-with-values expects 2 args")
+	}
+	producer, err := gb.compile(nl.Nth(1))
+	if err != nil {
+		return "", err
+	}
+	consumer, err := gb.compile(nl.Nth(2))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s(%s())", consumer, producer), nil
+}
+
+// compileApply passes the list as the rest args of the variadic golang
+// func, after any other args
+func (gb *GolangBackend) compileApply(nl *gol.NodeList) (string, error) {
+	if nl.Len() < 3 {
+		return "", gol.NodeErrorf(nl, "Arity-error: apply expects >= 2 args")
+	}
+	f, err := gb.compile(nl.Nth(1))
+	if err != nil {
+		return "", err
+	}
+	var args []string
+	for i := 2; i < nl.Len(); i++ {
+		arg, err := gb.compile(nl.Nth(i))
+		if err != nil {
+			return "", err
+		}
+		args = append(args, arg)
+	}
+	list := args[len(args)-1]
+	listType, err := golangStringForType(nl.Nth(nl.Len() - 1).Type())
+	if err != nil {
+		return "", err
+	}
+	if len(args) > 1 {
+		list = fmt.Sprintf("append(%s{%s}, %s...)", listType, strings.Join(args[:len(args)-1], ", "), list)
+	}
+
+	fType, err := typ.Resolve(nl.Nth(1).Type())
+	if err != nil {
+		return "", err
+	}
+	funcType, ok := fType.(typ.Func)
+	if !ok {
+		return "", gol.NodeErrorf(nl, "Non-procedure passed to apply")
+	}
+	if len(funcType.Args) == 1 && isVariadic(funcType.Args[0]) {
+		return fmt.Sprintf("%s(%s...)", f, list), nil
+	}
+
+	// Index the fixed args out of the list, which panics if it's too
+	// short, as a mismatch would in a call
+	var callArgs []string
+	for i, arg := range funcType.Args {
+		if isVariadic(arg) {
+			callArgs = append(callArgs, fmt.Sprintf("args[%d:]...", i))
+		} else {
+			callArgs = append(callArgs, fmt.Sprintf("args[%d]", i))
+		}
+	}
+	golangFuncType, err := golangStringForType(funcType)
+	if err != nil {
+		return "", err
+	}
+	golangRetType, err := golangStringForType(nl.Type())
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("func(f %s, args %s) %s { return f(%s) }(%s, %s)",
+		golangFuncType, listType, golangRetType, strings.Join(callArgs, ", "), f, list), nil
+}
+
 func mangleIdentifier(s string) string {
 	s = strings.Replace(s, "+", "__PLUS__", -1)
 	s = strings.Replace(s, "-", "__MINUS__", -1)
 	s = strings.Replace(s, "*", "__TIMES__", -1)
 	s = strings.Replace(s, "=", "__EQUAL__", -1)
-
+	s = strings.Replace(s, "<", "__LT__", -1)
+	s = strings.Replace(s, ">", "__GT__", -1)
+	s = strings.Replace(s, "?", "__P__", -1)
+	s = strings.Replace(s, "!", "__BANG__", -1)
+	s = strings.Replace(s, "/", "__SLASH__", -1)
+	s = strings.Replace(s, "%", "__PCT__", -1)
+
+	if goReserved[s] {
+		// So that error and friends don't shadow the golang names
+		s += "__"
+	}
 	return s
 }
 
+// goReserved are the golang keywords and predeclared identifiers which
+// are also valid scheme identifiers
+var goReserved = map[string]bool{
+	"break": true, "case": true, "chan": true, "const": true, "continue": true,
+	"default": true, "defer": true, "else": true, "fallthrough": true, "for": true,
+	"func": true, "go": true, "goto": true, "if": true, "import": true,
+	"interface": true, "map": true, "package": true, "range": true, "return": true,
+	"select": true, "struct": true, "switch": true, "type": true, "var": true,
+
+	"any": true, "append": true, "bool": true, "byte": true, "cap": true,
+	"close": true, "complex": true, "copy": true, "delete": true, "error": true,
+	"false": true, "float64": true, "imag": true, "int": true, "iota": true,
+	"len": true, "make": true, "new": true, "nil": true, "panic": true,
+	"print": true, "println": true, "real": true, "recover": true, "rune": true,
+	"string": true, "true": true,
+}
+
+// explicitResult are the runtime functions whose result type golang
+// can't infer from their args, so the call names it
+var explicitResult = map[string]bool{
+	"raise": true, "raise-continuable": true, "error": true,
+	"%raise-continuable": true, "%error": true,
+}
+
 func (gb *GolangBackend) compileFuncCall(funcNameNode *gol.NodeIdentifier, argNodes *gol.NodeList) (string, error) {
+	if funcNameNode.String() == "/" && numericArgType(funcNameNode) == typ.Int {
+		// We'd need a rational result, which doesn't fit the static type
+		return "", gol.NodeErrorf(funcNameNode, "Exact division of integers isn't supported in compiled code, use exact->inexact")
+	}
 
+	gb.noteRegistered(funcNameNode.String())
 	funcName := mangleIdentifier(funcNameNode.String())
+	args, err := gb.compileArgs(funcNameNode, argNodes)
+	if err != nil {
+		return "", err
+	}
+	if explicitResult[funcNameNode.String()] {
+		funcName += "[" + resultTypeString(funcNameNode) + "]"
+	}
+	s := funcName + "(" + strings.Join(args, ", ") + ")"
+	return s, nil
+}
+
+// compileArgs compiles the args of a call of f. An arg of type Any,
+// such as the result of string->number, is only checked where f needs
+// a concrete type, and a func which f calls with an Any is adapted.
+func (gb *GolangBackend) compileArgs(f gol.Node, argNodes *gol.NodeList) ([]string, error) {
 	args := []string{}
 	err := argNodes.Foreach(func(n gol.Node) error {
-		nStr, err := gb.compile(n)
+		arg, err := gb.compile(n)
 		if err != nil {
 			return err
 		}
-		args = append(args, nStr)
+		want := argType(f, len(args))
+		if s, err := golangStringForType(want); want != nil && err == nil && isAny(n) {
+			arg = "(" + arg + ").(" + s + ")"
+		}
+		wantFunc, wantIsFunc := want.(typ.Func)
+		have, err := typ.Resolve(n.Type())
+		if haveFunc, ok := have.(typ.Func); ok && err == nil && wantIsFunc {
+			arg = adaptFunc(arg, haveFunc, wantFunc)
+		}
+		args = append(args, arg)
 		return nil
 	})
+	return args, err
+}
+
+// isAny is true if n has type Any, so is an interface{} in golang
+func isAny(n gol.Node) bool {
+	t, err := typ.Resolve(n.Type())
+	return err == nil && t == typ.Any
+}
+
+// argType is the type of arg i of the function f, or nil if it takes
+// any value
+func argType(f gol.Node, i int) typ.Type {
+	t, err := typ.Resolve(f.Type())
+	if err != nil {
+		return nil
+	}
+	ft, ok := t.(typ.Func)
+	if !ok || len(ft.Args) == 0 {
+		return nil
+	}
+	var arg typ.Type
+	if i < len(ft.Args) {
+		arg = ft.Args[i]
+	}
+	if v, ok := ft.Args[len(ft.Args)-1].(typ.Variadic); ok && i >= len(ft.Args)-1 {
+		arg = v.X
+	}
+	if arg == nil {
+		return nil
+	}
+	arg, err = typ.Resolve(arg)
+	if err != nil || arg == typ.Any {
+		return nil
+	}
+	return arg
+}
+
+// adaptFunc wraps the func f, of type have, where a func of type want is
+// needed which is called with Any args that f needs to be concrete,
+// such as a guard handler. Otherwise f is returned as it is.
+func adaptFunc(f string, have, want typ.Func) string {
+	if len(have.Args) != len(want.Args) {
+		return f
+	}
+	result, err := golangStringForType(want.Result)
+	if err != nil {
+		return f
+	}
+	haveResult, err := golangStringForType(have.Result)
+	if err != nil || haveResult != result {
+		return f
+	}
+	adapted := false
+	params := make([]string, len(want.Args))
+	args := make([]string, len(want.Args))
+	for i := range want.Args {
+		wantArg, err := golangStringForType(want.Args[i])
+		if err != nil {
+			return f
+		}
+		haveArg, err := golangStringForType(have.Args[i])
+		if err != nil {
+			return f
+		}
+		params[i] = fmt.Sprintf("a%d %s", i, wantArg)
+		args[i] = fmt.Sprintf("a%d", i)
+		if haveArg != wantArg {
+			if wantArg != "interface{}" {
+				return f
+			}
+			args[i] += ".(" + haveArg + ")"
+			adapted = true
+		}
+	}
+	if !adapted {
+		return f
+	}
+	return fmt.Sprintf("func(%s) %s { return (%s)(%s) }", strings.Join(params, ", "), result, f, strings.Join(args, ", "))
+}
+
+// resultTypeString is the golang type of the result of calling the
+// function n, or interface{} if nothing constrains it
+func resultTypeString(n gol.Node) string {
+	t, err := typ.Resolve(n.Type())
+	if err != nil {
+		return "interface{}"
+	}
+	f, ok := t.(typ.Func)
+	if !ok {
+		return "interface{}"
+	}
+	s, err := golangStringForType(f.Result)
+	if err != nil {
+		return "interface{}"
+	}
+	return s
+}
+
+// compileFuncLiteralCall calls the compiled lambda where it stands
+func (gb *GolangBackend) compileFuncLiteralCall(nl *gol.NodeLambda, vals *gol.NodeList) (string, error) {
+	f, err := gb.compileLambda(nl)
 	if err != nil {
 		return "", err
 	}
-	s := funcName + "(" + strings.Join(args, ", ") + ")"
-	return s, nil
+	args, err := gb.compileArgs(nl, vals)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("(%s)(%s)", f, strings.Join(args, ", ")), nil
 }
 
 func (gb *GolangBackend) compileLambdaApplication(nl *gol.NodeLambda, vals *gol.NodeList) (string, error) {
-	bindings := make(map[string]gol.Node)
+	var bindings []gol.LetBinding
 
 	args := nl.Args
 
+	if nl.Rest != nil {
+		// A let can't bind the rest list, so call the variadic func
+		return gb.compileFuncLiteralCall(nl, vals)
+	}
+	checked := false
+	for i, rest := 0, vals; rest.Len() > 0; i, rest = i+1, rest.Rest() {
+		checked = checked || (isAny(rest.First()) && argType(nl, i) != nil)
+	}
+	if checked {
+		// A let would bind an Any arg as it is, rather than check it
+		return gb.compileFuncLiteralCall(nl, vals)
+	}
 	if args.Len() != vals.Len() {
 		return "", fmt.Errorf("Wrong number of args for lambda. [%s] != [%s]",
 			args.String(), vals.String())
 	}
 
 	for vals.Len() > 0 {
-		id := args.First().String()
-		bindings[id] = vals.First()
+		id, ok := args.First().(*gol.NodeIdentifier)
+		if !ok {
+			return "", gol.NodeErrorf(nl, "non-identifier in lambda args: %s", args.First())
+		}
+		bindings = append(bindings, gol.LetBinding{Id: id, Value: vals.First()})
 
 		vals = vals.Rest()
 		args = args.Rest()
@@ -479,7 +843,25 @@ func (gb *GolangBackend) compileLambdaApplication(nl *gol.NodeLambda, vals *gol.
 }
 
 func (gb *GolangBackend) compileInt(ni *gol.NodeInt) (string, error) {
-	return fmt.Sprintf("%d", ni.Value()), nil
+	// Typed, so that the numeric runtime functions can infer their type
+	if ni.IsBig() {
+		return fmt.Sprintf("bigIntFromString(%q)", ni.String()), nil
+	}
+	return fmt.Sprintf("schemeInt{small: %d}", ni.Value()), nil
+}
+
+func (gb *GolangBackend) compileReal(nr *gol.NodeReal) (string, error) {
+	f := nr.Value()
+	switch {
+	case math.IsInf(f, 0) || math.IsNaN(f):
+		return fmt.Sprintf("realFromString(%q)", nr.String()), nil
+	default:
+		return fmt.Sprintf("float64(%s)", strconv.FormatFloat(f, 'g', -1, 64)), nil
+	}
+}
+
+func (gb *GolangBackend) compileRational(nr *gol.NodeRational) (string, error) {
+	return fmt.Sprintf("ratFromString(%q)", nr.String()), nil
 }
 
 func (gb *GolangBackend) compileString(ns *gol.NodeString) (string, error) {
@@ -487,6 +869,26 @@ func (gb *GolangBackend) compileString(ns *gol.NodeString) (string, error) {
 	return fmt.Sprintf("%q", ns), nil
 }
 
+func (gb *GolangBackend) compileChar(nc *gol.NodeChar) (string, error) {
+	// %q emits a golang-syntax rune literal, including quotes
+	return fmt.Sprintf("%q", nc.Value()), nil
+}
+
+func (gb *GolangBackend) compileVector(nv *gol.NodeVector) (string, error) {
+	vecType, err := golangStringForType(nv.Type())
+	if err != nil {
+		return "", err
+	}
+	elems := make([]string, nv.Len())
+	for i, n := range nv.Elems() {
+		elems[i], err = gb.compile(n)
+		if err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("%s{%s}", vecType, strings.Join(elems, ", ")), nil
+}
+
 func (gb *GolangBackend) compileBool(nb *gol.NodeBool) (string, error) {
 	if nb.IsTrue() {
 		return "true", nil
@@ -559,48 +961,7 @@ func (gb *GolangBackend) buildGo(goFilename string, outFilename string) error {
 }
 
 func (gb *GolangBackend) standardLib() string {
-	return `
-func __TIMES__(args ...int64) int64 {
-	var prod int64
-	prod = 1
-	for _, n := range args {
-		prod *= n
-	}
-	return prod
-}
-
-func __PLUS__(args ...int64) int64 {
-	var sum int64
-	for _, n := range args {
-		sum += n
-	}
-	return sum
-}
-
-func __MINUS__(args ...int64) int64 {
-	if len(args) < 2 {
-		panic(fmt.Sprintf("Less than 2 args to numeric -"))
-	}
-	total := args[0]
-	for _, n := range args[1:] {
-		total -= n
-	}
-	return total
-}
-
-func __EQUAL__(args ...int64) bool {
-	if len(args) < 2 {
-		panic(fmt.Sprintf("Less than 2 args to numeric ="))
-	}
-	first := args[0]
-	for _, n := range args[1:] {
-		if n != first {
-			return false
-		}
-	}
-	return true
-}
-
+	return numberRuntime + vectorRuntime + stringRuntime + equivRuntime + exceptionRuntime + registeredRuntime + `
 func display(args ...interface{}) {
 	if len(args) < 1 {
 		panic(fmt.Sprintf("Less than 1 args to display"))
@@ -631,6 +992,71 @@ func display(args ...interface{}) {
 func void() {
 }
 
+func char__P__(x interface{}) bool {
+	_, ok := x.(rune)
+	return ok
+}
+
+func char__MINUS____GT__integer(c rune) schemeInt {
+	return schemeInt{small: int64(c)}
+}
+
+func integer__MINUS____GT__char(n schemeInt) rune {
+	return rune(n.Int64())
+}
+
+func char__MINUS__upcase(c rune) rune {
+	return unicode.ToUpper(c)
+}
+
+func char__MINUS__downcase(c rune) rune {
+	return unicode.ToLower(c)
+}
+
+func char__MINUS__alphabetic__P__(c rune) bool {
+	return unicode.IsLetter(c)
+}
+
+func char__MINUS__numeric__P__(c rune) bool {
+	return unicode.IsDigit(c)
+}
+
+func char__MINUS__whitespace__P__(c rune) bool {
+	return unicode.IsSpace(c)
+}
+
+func charCompare(f func(a, b rune) bool, args []rune) bool {
+	if len(args) < 2 {
+		panic(fmt.Sprintf("Less than 2 args to char comparison"))
+	}
+	for i := 1; i < len(args); i++ {
+		if !f(args[i-1], args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func char__EQUAL____P__(args ...rune) bool {
+	return charCompare(func(a, b rune) bool { return a == b }, args)
+}
+
+func char__LT____P__(args ...rune) bool {
+	return charCompare(func(a, b rune) bool { return a < b }, args)
+}
+
+func char__GT____P__(args ...rune) bool {
+	return charCompare(func(a, b rune) bool { return a > b }, args)
+}
+
+func char__LT____EQUAL____P__(args ...rune) bool {
+	return charCompare(func(a, b rune) bool { return a <= b }, args)
+}
+
+func char__GT____EQUAL____P__(args ...rune) bool {
+	return charCompare(func(a, b rune) bool { return a >= b }, args)
+}
+
 func schemeRepresentation(v interface{}) string {
 	vb, ok := v.(bool)
 	if ok {
@@ -641,23 +1067,209 @@ func schemeRepresentation(v interface{}) string {
 		}
 	}
 
+	vr, ok := v.(rune)
+	if ok {
+		return string(vr)
+	}
+
+	if isNumber(v) {
+		return numberRepresentation(v)
+	}
+
+	if r, ok := v.(interface{ schemeRepresentation() string }); ok {
+		return r.schemeRepresentation()
+	}
+
+	if s, ok := vectorRepresentation(v); ok {
+		return s
+	}
+
 	return fmt.Sprintf("%v", v)
 }
 
 `
 }
 
+// numericArgType gives the resolved type of the first arg of a numeric
+// function, or nil if it isn't known
+func numericArgType(n gol.Node) typ.Type {
+	t, err := typ.Resolve(n.Type())
+	if err != nil {
+		return nil
+	}
+	f, ok := t.(typ.Func)
+	if !ok || len(f.Args) == 0 {
+		return nil
+	}
+	arg := f.Args[0]
+	if v, ok := arg.(typ.Variadic); ok {
+		arg = v.X
+	}
+	argType, err := typ.Resolve(arg)
+	if err != nil {
+		return nil
+	}
+	return argType
+}
+
+// numericFunc is polymorphic over the numeric types, so that each use
+// is free to pick Int, Real or Rational. The runtime uses golang generics
+// to do the same.
+func numericFunc(name string, argsVariadic bool, numArgs int, result typ.Type) typ.Generic {
+	return typ.NewGeneric(name, func() typ.Type {
+		num := typ.NewVar()
+		args := make([]typ.Type, numArgs)
+		for i := range args {
+			args[i] = num
+		}
+		if argsVariadic {
+			args = []typ.Type{typ.NewVariadic(num)}
+		}
+		if result == nil {
+			return typ.NewFunc(args, num)
+		}
+		return typ.NewFunc(args, result)
+	})
+}
+
 func newDefaultTypeEnv() typ.Env {
-	e := typ.NewEnv()
-	ints := []typ.Type{typ.NewVariadic(typ.Int)}
+	f := builtinTypes()
+	for name, rf := range compiledRegistered(f) {
+		t, _ := rf.Type()
+		f[name] = t
+	}
+	return typ.NewEnv().WithFrame(f)
+}
+
+// builtinTypes are the types of the functions in the runtime
+func builtinTypes() typ.Frame {
 	anys := []typ.Type{typ.NewVariadic(typ.Any)}
+	char := []typ.Type{typ.Char}
+	chars := []typ.Type{typ.NewVariadic(typ.Char)}
 	f := typ.Frame{
-		"-":       typ.NewFunc(ints, typ.Int),
-		"+":       typ.NewFunc(ints, typ.Int),
-		"*":       typ.NewFunc(ints, typ.Int),
-		"=":       typ.NewFunc(ints, typ.Bool),
+		"-":       numericFunc("-", true, 0, nil),
+		"+":       numericFunc("+", true, 0, nil),
+		"*":       numericFunc("*", true, 0, nil),
+		"/":       numericFunc("/", true, 0, nil),
+		"=":       numericFunc("=", true, 0, typ.Bool),
+		"<":       numericFunc("<", true, 0, typ.Bool),
+		">":       numericFunc(">", true, 0, typ.Bool),
+		"<=":      numericFunc("<=", true, 0, typ.Bool),
+		">=":      numericFunc(">=", true, 0, typ.Bool),
+		"zero?":   numericFunc("zero?", false, 1, typ.Bool),
 		"display": typ.NewFunc(anys, typ.Void),
 		"void":    typ.NewFunc([]typ.Type{}, typ.Void),
+		"eqv?":    typ.NewFunc([]typ.Type{typ.Any, typ.Any}, typ.Bool),
+		"eq?":     typ.NewFunc([]typ.Type{typ.Any, typ.Any}, typ.Bool),
+		"equal?":  typ.NewFunc([]typ.Type{typ.Any, typ.Any}, typ.Bool),
+		"not":     typ.NewFunc([]typ.Type{typ.Any}, typ.Bool),
+
+		"number?":  typ.NewFunc([]typ.Type{typ.Any}, typ.Bool),
+		"real?":    typ.NewFunc([]typ.Type{typ.Any}, typ.Bool),
+		"integer?": typ.NewFunc([]typ.Type{typ.Any}, typ.Bool),
+		"string?":  typ.NewFunc([]typ.Type{typ.Any}, typ.Bool),
+
+		"char?":            typ.NewFunc([]typ.Type{typ.Any}, typ.Bool),
+		"char->integer":    typ.NewFunc(char, typ.Int),
+		"integer->char":    typ.NewFunc([]typ.Type{typ.Int}, typ.Char),
+		"char-upcase":      typ.NewFunc(char, typ.Char),
+		"char-downcase":    typ.NewFunc(char, typ.Char),
+		"char-alphabetic?": typ.NewFunc(char, typ.Bool),
+		"char-numeric?":    typ.NewFunc(char, typ.Bool),
+		"char-whitespace?": typ.NewFunc(char, typ.Bool),
+		"char=?":           typ.NewFunc(chars, typ.Bool),
+		"char<?":           typ.NewFunc(chars, typ.Bool),
+		"char>?":           typ.NewFunc(chars, typ.Bool),
+		"char<=?":          typ.NewFunc(chars, typ.Bool),
+		"char>=?":          typ.NewFunc(chars, typ.Bool),
+
+		"exact->inexact": numericFunc("exact->inexact", false, 1, typ.Real),
+		"floor":          numericFunc("floor", false, 1, nil),
+		"ceiling":        numericFunc("ceiling", false, 1, nil),
+		"truncate":       numericFunc("truncate", false, 1, nil),
+		"round":          numericFunc("round", false, 1, nil),
+		"sqrt":           numericFunc("sqrt", false, 1, typ.Real),
+		"string-length":  typ.NewFunc([]typ.Type{typ.String}, typ.Int),
+		"string-ref":     typ.NewFunc([]typ.Type{typ.String, typ.Int}, typ.Char),
+		"substring":      typ.NewFunc([]typ.Type{typ.String, typ.Int, typ.Int}, typ.String),
+		"string-append":  typ.NewFunc([]typ.Type{typ.NewVariadic(typ.String)}, typ.String),
+		"string->number": typ.NewFunc([]typ.Type{typ.String}, typ.Any),
+		"number->string": numericFunc("number->string", false, 1, typ.String),
+		"string->symbol": typ.NewFunc([]typ.Type{typ.String}, typ.Symbol),
+		"string-split":   typ.NewFunc([]typ.Type{typ.String, typ.String}, typ.NewList(typ.String)),
+		"string-join":    typ.NewFunc([]typ.Type{typ.NewList(typ.String), typ.String}, typ.String),
+		"string-index":   typ.NewFunc([]typ.Type{typ.String, typ.Char}, typ.Any),
+
+		"length": typ.NewGeneric("length", func() typ.Type {
+			return typ.NewFunc([]typ.Type{typ.NewList(typ.NewVar())}, typ.Int)
+		}),
+
+		"vector?": typ.NewFunc([]typ.Type{typ.Any}, typ.Bool),
+		"vector": typ.NewGeneric("vector", func() typ.Type {
+			elem := typ.NewVar()
+			return typ.NewFunc([]typ.Type{typ.NewVariadic(elem)}, typ.NewVector(elem))
+		}),
+		"make-vector": typ.NewGeneric("make-vector", func() typ.Type {
+			elem := typ.NewVar()
+			return typ.NewFunc([]typ.Type{typ.Int, elem}, typ.NewVector(elem))
+		}),
+		"vector-length": typ.NewGeneric("vector-length", func() typ.Type {
+			return typ.NewFunc([]typ.Type{typ.NewVector(typ.NewVar())}, typ.Int)
+		}),
+		"vector-ref": typ.NewGeneric("vector-ref", func() typ.Type {
+			elem := typ.NewVar()
+			return typ.NewFunc([]typ.Type{typ.NewVector(elem), typ.Int}, elem)
+		}),
+		"vector-set!": typ.NewGeneric("vector-set!", func() typ.Type {
+			elem := typ.NewVar()
+			return typ.NewFunc([]typ.Type{typ.NewVector(elem), typ.Int, elem}, typ.Void)
+		}),
+		"vector-map": typ.NewGeneric("vector-map", func() typ.Type {
+			from, to := typ.NewVar(), typ.NewVar()
+			f := typ.NewFunc([]typ.Type{from}, to)
+			return typ.NewFunc([]typ.Type{f, typ.NewVector(from)}, typ.NewVector(to))
+		}),
+		"vector-for-each": typ.NewGeneric("vector-for-each", func() typ.Type {
+			elem := typ.NewVar()
+			f := typ.NewFunc([]typ.Type{elem}, typ.NewVar())
+			return typ.NewFunc([]typ.Type{f, typ.NewVector(elem)}, typ.Void)
+		}),
+
+		"raise": typ.NewGeneric("raise", func() typ.Type {
+			return typ.NewFunc([]typ.Type{typ.Any}, typ.NewVar())
+		}),
+		"raise-continuable": typ.NewGeneric("raise-continuable", func() typ.Type {
+			return typ.NewFunc([]typ.Type{typ.Any}, typ.NewVar())
+		}),
+		"error": typ.NewGeneric("error", func() typ.Type {
+			return typ.NewFunc([]typ.Type{typ.String, typ.NewVariadic(typ.Any)}, typ.NewVar())
+		}),
+		"with-exception-handler": typ.NewGeneric("with-exception-handler", func() typ.Type {
+			result := typ.NewVar()
+			handler := typ.NewFunc([]typ.Type{typ.Any}, result)
+			thunk := typ.NewFunc([]typ.Type{}, result)
+			return typ.NewFunc([]typ.Type{handler, thunk}, result)
+		}),
+		"%guard": typ.NewGeneric("%guard", func() typ.Type {
+			result := typ.NewVar()
+			body := typ.NewFunc([]typ.Type{}, result)
+			onRaise := typ.NewFunc([]typ.Type{typ.Any}, result)
+			return typ.NewFunc([]typ.Type{body, onRaise}, result)
+		}),
+		"error-object?":          typ.NewFunc([]typ.Type{typ.Any}, typ.Bool),
+		"error-object-message":   typ.NewFunc([]typ.Type{typ.Any}, typ.String),
+		"error-object-irritants": typ.NewFunc([]typ.Type{typ.Any}, typ.NewList(typ.Any)),
+
+		"expt": typ.NewGeneric("expt", func() typ.Type {
+			// The power needn't have the same type as the base
+			base := typ.NewVar()
+			return typ.NewFunc([]typ.Type{base, typ.NewVar()}, base)
+		}),
+	}
+	for _, name := range gol.DerivedBuiltins {
+		if t, ok := f[name]; ok {
+			f["%"+name] = t
+		}
 	}
-	return e.WithFrame(f)
+	return f
 }
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/jbert/gol"
 	"github.com/jbert/gol/test"
 )
 
@@ -16,9 +17,67 @@ func TestGolFunc(t *testing.T) {
 	runCases(t, test.FuncTestCases())
 }
 
+func TestGolException(t *testing.T) {
+	runCases(t, test.ExceptionTestCases())
+}
+
+func TestGolValues(t *testing.T) {
+	runCases(t, test.ValuesTestCases())
+}
+
 func TestGolError(t *testing.T) {
 	runCases(t, test.ErrorTestCases())
 }
+func TestGolChar(t *testing.T) {
+	runCases(t, test.CharTestCases())
+}
+
+func TestGolNumber(t *testing.T) {
+	runCases(t, test.NumberTestCases())
+	runCases(t, test.BigIntTestCases())
+}
+
+func TestGolVector(t *testing.T) {
+	runCases(t, test.VectorTestCases())
+}
+
+func TestGolString(t *testing.T) {
+	runCases(t, test.StringTestCases())
+}
+
+func TestGolDerived(t *testing.T) {
+	runCases(t, test.DerivedTestCases())
+}
+
+func TestGolMacro(t *testing.T) {
+	runCases(t, test.MacroTestCases())
+}
+
+func TestGolVariadic(t *testing.T) {
+	runCases(t, test.VariadicTestCases())
+	runCases(t, test.GolangVariadicTestCases())
+}
+
+func TestGolLet(t *testing.T) {
+	runCases(t, test.LetTestCases())
+}
+
+func TestGolRegistered(t *testing.T) {
+	err := test.RegisterFunctions()
+	if err != nil {
+		t.Fatalf("Can't register functions: %s", err)
+	}
+	err = gol.Register("string-append", test.Repeat)
+	if err == nil || err.Error() != "Can't register [string-append]: it is a builtin" {
+		t.Errorf("Wrong error registering a builtin's name: %v", err)
+	}
+	runCases(t, test.RegisteredTestCases())
+}
+
+func TestGolTopLevelDefineValues(t *testing.T) {
+	runCases(t, test.GolangValuesTestCases())
+}
+
 func TestGolQuote(t *testing.T) {
 	runCases(t, test.QuoteTestCases())
 }
@@ -92,7 +151,8 @@ func runProgram(prog string) (string, error) {
 	cmd := exec.Command(outputFilename)
 	value, err := cmd.CombinedOutput()
 	if err != nil {
-		return "", err
+		// The output says why, for uncaught exceptions
+		return "", fmt.Errorf("%s (%s)", bytes.TrimRight(value, "\n"), err)
 	}
 	value = bytes.TrimRight(value, "\n")
 	return string(value), nil
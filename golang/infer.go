@@ -127,7 +127,7 @@ func (gb *GolangBackend) inferUnwrappedError(n gol.Node, typeEnv typ.Env, depth
 		var head gol.Node
 		argTypes := make([]typ.Type, 0)
 		first := true
-		node.Foreach(func(child gol.Node) error {
+		err := node.Foreach(func(child gol.Node) error {
 			childChanges, err := gb.infer(child, typeEnv, depth+1)
 			if err != nil {
 				return err
@@ -142,19 +142,41 @@ func (gb *GolangBackend) inferUnwrappedError(n gol.Node, typeEnv typ.Env, depth
 			}
 			return nil
 		})
-
-		// What type of function would fit these (and return type)?
-		wantedType := typ.Func{
-			Args:   argTypes,
-			Result: node.Type(),
-		}
-
-		// Unify that what we have in head position
-		err := head.NodeUnify(wantedType, typeEnv)
 		if err != nil {
 			return 0, err
 		}
 
+		switch head.String() {
+		case "values":
+			// Multiple values are a tuple of their types
+			err := node.NodeUnify(typ.NewTuple(argTypes), typeEnv)
+			if err != nil {
+				return 0, err
+			}
+		case "call-with-values", "%call-with-values":
+			err := inferCallWithValues(node, argTypes, typeEnv)
+			if err != nil {
+				return 0, err
+			}
+		case "apply", "%apply":
+			err := inferApply(node, argTypes)
+			if err != nil {
+				return 0, err
+			}
+		default:
+			// What type of function would fit these (and return type)?
+			wantedType := typ.Func{
+				Args:   argTypes,
+				Result: node.Type(),
+			}
+
+			// Unify that what we have in head position
+			err := head.NodeUnify(wantedType, typeEnv)
+			if err != nil {
+				return 0, err
+			}
+		}
+
 	case *gol.NodeLambda:
 		iprintf("NodeLambda (%s): %s\n", n.String(), typ.ResolveStr(n.Type()))
 		argTypes := make([]typ.Type, node.Args.Len())
@@ -174,6 +196,9 @@ func (gb *GolangBackend) inferUnwrappedError(n gol.Node, typeEnv typ.Env, depth
 		if err != nil {
 			return 0, err
 		}
+		if node.Rest != nil {
+			frame[node.Rest.String()] = node.Rest.Type()
+		}
 
 		oldEnv := typeEnv
 		defer func() {
@@ -181,6 +206,16 @@ func (gb *GolangBackend) inferUnwrappedError(n gol.Node, typeEnv typ.Env, depth
 		}()
 		typeEnv = typeEnv.WithFrame(frame)
 
+		if node.Rest != nil {
+			// The rest args all share one type, and arrive as a list
+			elem := typ.NewVar()
+			err = node.Rest.NodeUnify(typ.NewList(elem), typeEnv)
+			if err != nil {
+				return 0, err
+			}
+			argTypes = append(argTypes, typ.NewVariadic(elem))
+		}
+
 		childChanges, err := gb.infer(node.Body, typeEnv, depth+1)
 		if err != nil {
 			return 0, err
@@ -208,22 +243,43 @@ func (gb *GolangBackend) inferUnwrappedError(n gol.Node, typeEnv typ.Env, depth
 
 	case *gol.NodeLet:
 		iprintf("NodeLet (%s)\n", n.String())
+		if node.Rec {
+			// golang would read a binding before its init as the zero
+			// value
+			for i, b := range node.Bindings {
+				unassigned := make(map[string]bool)
+				for _, later := range node.Bindings[i:] {
+					unassigned[later.Id.String()] = true
+				}
+				if id := unassignedRef(b.Value, unassigned); id != nil {
+					return 0, gol.NodeErrorf(id, "Identifier [%s] used before it was assigned", id)
+				}
+			}
+		}
 
 		frame := make(map[string]typ.Type)
-		for k, v := range node.Bindings {
-			frame[k] = v.Type()
-			childChanges, err := gb.infer(v, typeEnv, depth+1)
-			if err != nil {
-				return 0, err
-			}
-			numChanges += childChanges
+		for _, b := range node.Bindings {
+			frame[b.Id.String()] = b.Value.Type()
 		}
 
 		oldEnv := typeEnv
 		defer func() {
 			typeEnv = oldEnv
 		}()
+		// letrec bindings are inferred in their own scope
+		bindingEnv := typeEnv
 		typeEnv = typeEnv.WithFrame(frame)
+		if node.Rec {
+			bindingEnv = typeEnv
+		}
+
+		for _, b := range node.Bindings {
+			childChanges, err := gb.infer(b.Value, bindingEnv, depth+1)
+			if err != nil {
+				return 0, err
+			}
+			numChanges += childChanges
+		}
 
 		childChanges, err := gb.infer(node.Body, typeEnv, depth+1)
 		if err != nil {
@@ -241,6 +297,26 @@ func (gb *GolangBackend) inferUnwrappedError(n gol.Node, typeEnv typ.Env, depth
 		if err != nil {
 			return 0, err
 		}
+	case *gol.NodeReal:
+	case *gol.NodeRational:
+	case *gol.NodeVector:
+		iprintf("NodeVector (%s)\n", n.String())
+		elem := typ.NewVar()
+		for _, child := range node.Elems() {
+			childChanges, err := gb.infer(child, typeEnv, depth+1)
+			if err != nil {
+				return 0, err
+			}
+			numChanges += childChanges
+			err = child.NodeUnify(elem, typeEnv)
+			if err != nil {
+				return 0, err
+			}
+		}
+		err := node.NodeUnify(typ.NewVector(elem), typeEnv)
+		if err != nil {
+			return 0, err
+		}
 	case *gol.NodeIf:
 		iprintf("NodeIf (%s)\n", n.String())
 		childChanges, err := gb.infer(node.Condition, typeEnv, depth+1)
@@ -277,6 +353,7 @@ func (gb *GolangBackend) inferUnwrappedError(n gol.Node, typeEnv typ.Env, depth
 	case *gol.NodeSymbol:
 	case *gol.NodeString:
 	case *gol.NodeBool:
+	case *gol.NodeChar:
 
 	case *gol.NodeDefine:
 		// JB - hack into top level
@@ -298,7 +375,7 @@ func (gb *GolangBackend) inferUnwrappedError(n gol.Node, typeEnv typ.Env, depth
 	case *gol.NodeUnQuote:
 		panic("implement")
 	case *gol.NodeSet:
-		panic("implement")
+		return 0, gol.NodeErrorf(n, "set! isn't supported in compiled code, nor is a top-level define-values, which uses it")
 
 	default:
 		return 0, gol.NodeErrorf(n, "unrecognised/unhandled node type %T", n)
@@ -313,3 +390,140 @@ func (gb *GolangBackend) inferUnwrappedError(n gol.Node, typeEnv typ.Env, depth
 
 	return numChanges, nil
 }
+
+// inferApply types (apply f arg ... list) for an f which only has rest
+// args, since a golang variadic func can't take a slice for its fixed
+// args
+func inferApply(node *gol.NodeList, argTypes []typ.Type) error {
+	if len(argTypes) < 2 {
+		return gol.NodeErrorf(node, "Arity-error: apply expects >= 2 args")
+	}
+	elem := typ.NewVar()
+	fType := typ.NewFunc([]typ.Type{typ.NewVariadic(elem)}, node.Type())
+	if t, err := typ.Resolve(argTypes[0]); err == nil {
+		if f, ok := t.(typ.Func); ok {
+			// The fixed args come from the list too, so have its type
+			args := make([]typ.Type, len(f.Args))
+			for i, arg := range f.Args {
+				args[i] = elem
+				if isVariadic(arg) {
+					args[i] = typ.NewVariadic(elem)
+				}
+			}
+			fType = typ.NewFunc(args, node.Type())
+		}
+	}
+	err := argTypes[0].Unify(fType)
+	if err != nil {
+		return gol.NodeErrorf(node, "apply in compiled code needs a procedure whose args all have one type: %s", err)
+	}
+	for _, argType := range argTypes[1 : len(argTypes)-1] {
+		err = argType.Unify(elem)
+		if err != nil {
+			return err
+		}
+	}
+	return argTypes[len(argTypes)-1].Unify(typ.NewList(elem))
+}
+
+// unassignedRef finds a reference in n to one of the names, other than
+// in a lambda, which is run later. A call of a lambda which refers to
+// one isn't found.
+func unassignedRef(n gol.Node, names map[string]bool) *gol.NodeIdentifier {
+	switch node := n.(type) {
+	case *gol.NodeIdentifier:
+		if names[node.String()] {
+			return node
+		}
+	case *gol.NodeIf:
+		for _, child := range []gol.Node{node.Condition, node.TBranch, node.FBranch} {
+			if id := unassignedRef(child, names); id != nil {
+				return id
+			}
+		}
+	case *gol.NodeLet:
+		inner := make(map[string]bool)
+		for name := range names {
+			inner[name] = true
+		}
+		for _, b := range node.Bindings {
+			delete(inner, b.Id.String())
+		}
+		for _, b := range node.Bindings {
+			scope := names
+			if node.Rec {
+				scope = inner
+			}
+			if id := unassignedRef(b.Value, scope); id != nil {
+				return id
+			}
+		}
+		return unassignedRef(node.Body, inner)
+	case *gol.NodeProgn:
+		return unassignedRef(node.Rest(), names)
+	case *gol.NodeList:
+		var found *gol.NodeIdentifier
+		node.Foreach(func(child gol.Node) error {
+			if found == nil {
+				found = unassignedRef(child, names)
+			}
+			return nil
+		})
+		return found
+	}
+	return nil
+}
+
+func isVariadic(t typ.Type) bool {
+	_, ok := t.(typ.Variadic)
+	return ok
+}
+
+// inferCallWithValues unifies the result of the producer with a tuple
+// of the consumer's args, once the consumer's type is known
+func inferCallWithValues(node *gol.NodeList, argTypes []typ.Type, typeEnv typ.Env) error {
+	if len(argTypes) != 2 {
+		return gol.NodeErrorf(node, "Arity-error: call-with-values expects 2 args")
+	}
+	produced := typ.NewVar()
+	err := argTypes[0].Unify(typ.NewFunc([]typ.Type{}, produced))
+	if err != nil {
+		return err
+	}
+	consumerType, err := typ.Resolve(argTypes[1])
+	if err != nil {
+		// Not known yet, maybe on a later pass
+		return nil
+	}
+	consumer, ok := consumerType.(typ.Func)
+	if !ok {
+		return gol.NodeErrorf(node, "Non-function consumer passed to call-with-values")
+	}
+	args := consumer.Args
+	if len(args) > 0 {
+		if variadic, ok := args[len(args)-1].(typ.Variadic); ok {
+			// The rest arg takes however many values are left over
+			producedType, err := typ.Resolve(produced)
+			if err != nil {
+				return nil
+			}
+			numValues := 1
+			if tu, ok := producedType.(typ.Tuple); ok {
+				numValues = len(tu.Elems)
+			}
+			fixed := args[:len(args)-1]
+			if numValues < len(fixed) {
+				return gol.NodeErrorf(node, "Arity-error: call-with-values consumer expects >= %d values", len(fixed))
+			}
+			args = append([]typ.Type{}, fixed...)
+			for len(args) < numValues {
+				args = append(args, variadic.X)
+			}
+		}
+	}
+	err = produced.Unify(typ.NewTuple(args))
+	if err != nil {
+		return err
+	}
+	return node.Type().Unify(consumer.Result)
+}
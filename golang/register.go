@@ -0,0 +1,145 @@
+package golang
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jbert/gol"
+	"github.com/jbert/gol/typ"
+)
+
+func init() {
+	var names []string
+	for name := range builtinTypes() {
+		names = append(names, name)
+	}
+	gol.AddBuiltins(names...)
+}
+
+// compiledRegistered are the functions added with gol.Register which
+// compiled code can call, by name. As in the interpreter, the builtins
+// hide any of the same name which were registered before we added ours.
+func compiledRegistered(builtins typ.Frame) map[string]*gol.RegisteredFunc {
+	rfs := make(map[string]*gol.RegisteredFunc)
+	for _, rf := range gol.Registered() {
+		if _, ok := builtins[rf.Name]; ok {
+			continue
+		}
+		if _, err := rf.Type(); err != nil {
+			continue
+		}
+		rfs[rf.Name] = rf
+	}
+	return rfs
+}
+
+// noteRegistered records a use of name, so that we emit a wrapper for
+// it if it is a registered function
+func (gb *GolangBackend) noteRegistered(name string) {
+	if gb.registered == nil {
+		gb.registered = compiledRegistered(builtinTypes())
+		gb.usedRegistered = make(map[string]*gol.RegisteredFunc)
+	}
+	if rf, ok := gb.registered[name]; ok {
+		gb.usedRegistered[name] = rf
+	}
+}
+
+// compileRegistered is the imports and wrappers for the registered
+// functions the program uses. Each wrapper has the golang type of the
+// function's typ.Func, and calls the function by reflection.
+func (gb *GolangBackend) compileRegistered() ([]string, string, error) {
+	var names []string
+	for name := range gb.usedRegistered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	aliases := make(map[string]string)
+	var imports []string
+	var code strings.Builder
+	for _, name := range names {
+		rf := gb.usedRegistered[name]
+		pkgPath, goName, err := rf.GoName()
+		if err != nil {
+			return nil, "", fmt.Errorf("Registered function [%s] can't be called from compiled code: %s", name, err)
+		}
+		alias, ok := aliases[pkgPath]
+		if !ok {
+			alias = fmt.Sprintf("registered%d", len(aliases))
+			aliases[pkgPath] = alias
+			imports = append(imports, fmt.Sprintf("%s %q", alias, pkgPath))
+		}
+		wrapper, err := compileRegisteredWrapper(rf, alias+"."+goName)
+		if err != nil {
+			return nil, "", err
+		}
+		code.WriteString(wrapper)
+	}
+	return imports, code.String(), nil
+}
+
+func compileRegisteredWrapper(rf *gol.RegisteredFunc, goFunc string) (string, error) {
+	t, err := rf.Type()
+	if err != nil {
+		return "", err
+	}
+	f := t.(typ.Func)
+
+	var params, args []string
+	variadic := ""
+	for i, argType := range f.Args {
+		argStr, err := golangStringForType(argType)
+		if err != nil {
+			return "", err
+		}
+		params = append(params, fmt.Sprintf("a%d %s", i, argStr))
+		if _, ok := argType.(typ.Variadic); ok {
+			variadic = fmt.Sprintf("a%d", i)
+		} else {
+			args = append(args, fmt.Sprintf("a%d", i))
+		}
+	}
+
+	var results []typ.Type
+	switch r := f.Result.(type) {
+	case typ.Tuple:
+		results = r.Elems
+	default:
+		if r != typ.Void {
+			results = []typ.Type{r}
+		}
+	}
+	resultStrs := make([]string, len(results))
+	returns := make([]string, len(results))
+	for i := range results {
+		resultStrs[i], err = golangStringForType(results[i])
+		if err != nil {
+			return "", err
+		}
+		returns[i] = fmt.Sprintf("fromRegistered[%s](r[%d])", resultStrs[i], i)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n// %s calls the registered function %s\n", mangleIdentifier(rf.Name), goFunc)
+	fmt.Fprintf(&b, "func %s(%s) ", mangleIdentifier(rf.Name), strings.Join(params, ", "))
+	if len(resultStrs) > 1 {
+		fmt.Fprintf(&b, "(%s) ", strings.Join(resultStrs, ", "))
+	} else if len(resultStrs) == 1 {
+		fmt.Fprintf(&b, "%s ", resultStrs[0])
+	}
+	fmt.Fprintf(&b, "{\n\targs := []interface{}{%s}\n", strings.Join(args, ", "))
+	if variadic != "" {
+		fmt.Fprintf(&b, "\tfor _, arg := range %s {\n\t\targs = append(args, arg)\n\t}\n", variadic)
+	}
+	call := fmt.Sprintf("callRegistered(%s, %s, args...)", strconv.Quote(rf.Name), goFunc)
+	if len(returns) > 0 {
+		fmt.Fprintf(&b, "\tr := %s\n\treturn %s\n", call, strings.Join(returns, ", "))
+	} else {
+		fmt.Fprintf(&b, "\t%s\n", call)
+	}
+	b.WriteString("}\n")
+	return b.String(), nil
+}
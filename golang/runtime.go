@@ -0,0 +1,860 @@
+package golang
+
+// numberRuntime implements the numeric builtins for each of the golang
+// types we compile numbers to
+const numberRuntime = `
+// schemeInt is an exact integer, which only uses a big.Int once it
+// overflows an int64
+type schemeInt struct {
+	small int64
+	big   *big.Int
+}
+
+func bigIntFromString(s string) schemeInt {
+	b, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic(fmt.Sprintf("Bad integer [%s]", s))
+	}
+	return normalisedInt(b)
+}
+
+func normalisedInt(b *big.Int) schemeInt {
+	if b.IsInt64() {
+		return schemeInt{small: b.Int64()}
+	}
+	return schemeInt{big: b}
+}
+
+func (i schemeInt) toBig() *big.Int {
+	if i.big != nil {
+		return i.big
+	}
+	return big.NewInt(i.small)
+}
+
+func (i schemeInt) Int64() int64 {
+	if i.big != nil {
+		panic(fmt.Sprintf("Integer too large: %s", i.big))
+	}
+	return i.small
+}
+
+func (i schemeInt) String() string {
+	if i.big != nil {
+		return i.big.String()
+	}
+	return strconv.FormatInt(i.small, 10)
+}
+
+func intAdd(a, b schemeInt) schemeInt {
+	if a.big == nil && b.big == nil {
+		c := a.small + b.small
+		if (a.small^c)&(b.small^c) >= 0 {
+			return schemeInt{small: c}
+		}
+	}
+	return normalisedInt(new(big.Int).Add(a.toBig(), b.toBig()))
+}
+
+func intSub(a, b schemeInt) schemeInt {
+	if a.big == nil && b.big == nil {
+		c := a.small - b.small
+		if (a.small^b.small)&(a.small^c) >= 0 {
+			return schemeInt{small: c}
+		}
+	}
+	return normalisedInt(new(big.Int).Sub(a.toBig(), b.toBig()))
+}
+
+func intMul(a, b schemeInt) schemeInt {
+	if a.big == nil && b.big == nil {
+		x, y := a.small, b.small
+		if x == 0 || y == 0 {
+			return schemeInt{}
+		}
+		c := x * y
+		if c/y == x && !(x == -1 && y == math.MinInt64) && !(y == -1 && x == math.MinInt64) {
+			return schemeInt{small: c}
+		}
+	}
+	return normalisedInt(new(big.Int).Mul(a.toBig(), b.toBig()))
+}
+
+func intCmp(a, b schemeInt) int {
+	if a.big == nil && b.big == nil {
+		switch {
+		case a.small < b.small:
+			return -1
+		case a.small > b.small:
+			return 1
+		}
+		return 0
+	}
+	return a.toBig().Cmp(b.toBig())
+}
+
+type number interface {
+	schemeInt | float64 | *big.Rat
+}
+
+func isNumber(v interface{}) bool {
+	switch v.(type) {
+	case schemeInt, float64, *big.Rat:
+		return true
+	default:
+		return false
+	}
+}
+
+func numberRepresentation(v interface{}) string {
+	switch n := v.(type) {
+	case float64:
+		switch {
+		case math.IsInf(n, 1):
+			return "+inf.0"
+		case math.IsInf(n, -1):
+			return "-inf.0"
+		case math.IsNaN(n):
+			return "+nan.0"
+		}
+		s := strconv.FormatFloat(n, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s
+	case *big.Rat:
+		return n.RatString()
+	default:
+		return fmt.Sprintf("%v", n)
+	}
+}
+
+func realFromString(s string) float64 {
+	switch s {
+	case "+inf.0":
+		return math.Inf(1)
+	case "-inf.0":
+		return math.Inf(-1)
+	default:
+		return math.NaN()
+	}
+}
+
+func ratFromString(s string) *big.Rat {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		panic(fmt.Sprintf("Bad rational [%s]", s))
+	}
+	return r
+}
+
+func toFloat[T number](n T) float64 {
+	switch x := any(n).(type) {
+	case schemeInt:
+		if x.big != nil {
+			f, _ := new(big.Float).SetInt(x.big).Float64()
+			return f
+		}
+		return float64(x.small)
+	case float64:
+		return x
+	default:
+		f, _ := any(n).(*big.Rat).Float64()
+		return f
+	}
+}
+
+// numOp applies whichever of the operations matches the type of the args
+func numOp[T number](a, b T, ints func(x, y schemeInt) schemeInt, reals func(x, y float64) float64, rats func(z, x, y *big.Rat) *big.Rat) T {
+	switch x := any(a).(type) {
+	case schemeInt:
+		return any(ints(x, any(b).(schemeInt))).(T)
+	case float64:
+		return any(reals(x, any(b).(float64))).(T)
+	default:
+		return any(rats(new(big.Rat), any(a).(*big.Rat), any(b).(*big.Rat))).(T)
+	}
+}
+
+func numCmp[T number](a, b T) int {
+	switch x := any(a).(type) {
+	case schemeInt:
+		return intCmp(x, any(b).(schemeInt))
+	case float64:
+		y := any(b).(float64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	default:
+		return any(a).(*big.Rat).Cmp(any(b).(*big.Rat))
+	}
+}
+
+func numFold[T number](name string, args []T, ints func(x, y schemeInt) schemeInt, reals func(x, y float64) float64, rats func(z, x, y *big.Rat) *big.Rat) T {
+	if len(args) < 1 {
+		panic(fmt.Sprintf("No args to numeric %s", name))
+	}
+	acc := args[0]
+	for _, n := range args[1:] {
+		acc = numOp(acc, n, ints, reals, rats)
+	}
+	return acc
+}
+
+func __PLUS__[T number](args ...T) T {
+	return numFold("+", args,
+		intAdd,
+		func(x, y float64) float64 { return x + y },
+		(*big.Rat).Add)
+}
+
+func __TIMES__[T number](args ...T) T {
+	return numFold("*", args,
+		intMul,
+		func(x, y float64) float64 { return x * y },
+		(*big.Rat).Mul)
+}
+
+func __MINUS__[T number](args ...T) T {
+	if len(args) == 1 {
+		switch x := any(args[0]).(type) {
+		case schemeInt:
+			return any(intSub(schemeInt{}, x)).(T)
+		case float64:
+			return any(-x).(T)
+		default:
+			return any(new(big.Rat).Neg(any(args[0]).(*big.Rat))).(T)
+		}
+	}
+	return numFold("-", args,
+		intSub,
+		func(x, y float64) float64 { return x - y },
+		(*big.Rat).Sub)
+}
+
+func __SLASH__[T number](args ...T) T {
+	return numFold("/", args,
+		func(x, y schemeInt) schemeInt { panic("Exact integer division") },
+		func(x, y float64) float64 { return x / y },
+		(*big.Rat).Quo)
+}
+
+func numCompare[T number](name string, args []T, f func(c int) bool) bool {
+	if len(args) < 2 {
+		panic(fmt.Sprintf("Less than 2 args to numeric %s", name))
+	}
+	for i := 1; i < len(args); i++ {
+		if !f(numCmp(args[i-1], args[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+func __EQUAL__[T number](args ...T) bool {
+	return numCompare("=", args, func(c int) bool { return c == 0 })
+}
+
+func __LT__[T number](args ...T) bool {
+	return numCompare("<", args, func(c int) bool { return c < 0 })
+}
+
+func __GT__[T number](args ...T) bool {
+	return numCompare(">", args, func(c int) bool { return c > 0 })
+}
+
+func __LT____EQUAL__[T number](args ...T) bool {
+	return numCompare("<=", args, func(c int) bool { return c <= 0 })
+}
+
+func __GT____EQUAL__[T number](args ...T) bool {
+	return numCompare(">=", args, func(c int) bool { return c >= 0 })
+}
+
+func zero__P__[T number](n T) bool {
+	return toFloat(n) == 0
+}
+
+func number__P__(x interface{}) bool {
+	switch x.(type) {
+	case schemeInt, float64, *big.Rat:
+		return true
+	}
+	return false
+}
+
+func real__P__(x interface{}) bool {
+	return number__P__(x)
+}
+
+func integer__P__(x interface{}) bool {
+	switch n := x.(type) {
+	case schemeInt:
+		return true
+	case float64:
+		return !math.IsInf(n, 0) && n == math.Trunc(n)
+	case *big.Rat:
+		return n.IsInt()
+	}
+	return false
+}
+
+func exact__MINUS____GT__inexact[T number](n T) float64 {
+	return toFloat(n)
+}
+
+func sqrt[T number](n T) float64 {
+	return math.Sqrt(toFloat(n))
+}
+
+func numRound[T number](n T, reals func(float64) float64, rats func(num, den *big.Int) *big.Int) T {
+	switch x := any(n).(type) {
+	case float64:
+		return any(reals(x)).(T)
+	case *big.Rat:
+		q := rats(x.Num(), x.Denom())
+		return any(new(big.Rat).SetInt(q)).(T)
+	default:
+		return n
+	}
+}
+
+func floor[T number](n T) T {
+	return numRound(n, math.Floor, func(num, den *big.Int) *big.Int {
+		return new(big.Int).Div(num, den)
+	})
+}
+
+func ceiling[T number](n T) T {
+	return numRound(n, math.Ceil, func(num, den *big.Int) *big.Int {
+		q := new(big.Int).Div(new(big.Int).Neg(num), den)
+		return q.Neg(q)
+	})
+}
+
+func truncate[T number](n T) T {
+	return numRound(n, math.Trunc, func(num, den *big.Int) *big.Int {
+		return new(big.Int).Quo(num, den)
+	})
+}
+
+func round[T number](n T) T {
+	return numRound(n, math.RoundToEven, func(num, den *big.Int) *big.Int {
+		// floor(x + 1/2), with ties to even
+		twice := new(big.Int).Add(new(big.Int).Mul(num, big.NewInt(2)), den)
+		den2 := new(big.Int).Mul(den, big.NewInt(2))
+		q, m := new(big.Int).DivMod(twice, den2, new(big.Int))
+		if m.Sign() == 0 && q.Bit(0) == 1 {
+			q.Sub(q, big.NewInt(1))
+		}
+		return q
+	})
+}
+
+func expt[T number, P number](base T, power P) T {
+	switch x := any(base).(type) {
+	case float64:
+		return any(math.Pow(x, toFloat(power))).(T)
+	case schemeInt:
+		p, ok := any(power).(schemeInt)
+		if !ok || p.big != nil || p.small < 0 {
+			panic("Integer base needs a small non-negative integer power")
+		}
+		return any(normalisedInt(new(big.Int).Exp(x.toBig(), p.toBig(), nil))).(T)
+	default:
+		p := toFloat(power)
+		if p != math.Trunc(p) {
+			panic("Rational base needs an integer power")
+		}
+		exp := int64(p)
+		negative := exp < 0
+		if negative {
+			exp = -exp
+		}
+		result := big.NewRat(1, 1)
+		for ; exp > 0; exp-- {
+			result.Mul(result, any(base).(*big.Rat))
+		}
+		if negative {
+			result.Inv(result)
+		}
+		return any(result).(T)
+	}
+}
+`
+
+// vectorRuntime implements the vector builtins on golang slices
+const vectorRuntime = `
+func vectorRepresentation(v interface{}) (string, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return "", false
+	}
+	strs := make([]string, rv.Len())
+	for i := range strs {
+		strs[i] = schemeRepresentation(rv.Index(i).Interface())
+	}
+	return "#(" + strings.Join(strs, " ") + ")", true
+}
+
+func vector__P__(x interface{}) bool {
+	return reflect.ValueOf(x).Kind() == reflect.Slice
+}
+
+func vector[T any](args ...T) []T {
+	return args
+}
+
+func make__MINUS__vector[T any](k schemeInt, fill T) []T {
+	v := make([]T, k.Int64())
+	for i := range v {
+		v[i] = fill
+	}
+	return v
+}
+
+func vector__MINUS__length[T any](v []T) schemeInt {
+	return schemeInt{small: int64(len(v))}
+}
+
+func vector__MINUS__ref[T any](v []T, k schemeInt) T {
+	return v[k.Int64()]
+}
+
+func vector__MINUS__set__BANG__[T any](v []T, k schemeInt, x T) {
+	v[k.Int64()] = x
+}
+
+func vector__MINUS__map[T, U any](f func(T) U, v []T) []U {
+	ret := make([]U, len(v))
+	for i := range v {
+		ret[i] = f(v[i])
+	}
+	return ret
+}
+
+func vector__MINUS__for__MINUS__each[T, U any](f func(T) U, v []T) {
+	for i := range v {
+		f(v[i])
+	}
+}
+`
+
+// stringRuntime implements the string builtins. Lists only appear here
+// so far, as the results of string-split.
+const stringRuntime = `
+type schemeList[T any] []T
+
+func (l schemeList[T]) schemeRepresentation() string {
+	strs := make([]string, len(l))
+	for i := range l {
+		strs[i] = schemeRepresentation(l[i])
+	}
+	return "(" + strings.Join(strs, " ") + ")"
+}
+
+func length[T any](l schemeList[T]) schemeInt {
+	return schemeInt{small: int64(len(l))}
+}
+
+func string__MINUS__length(s string) schemeInt {
+	return schemeInt{small: int64(len([]rune(s)))}
+}
+
+func string__P__(x interface{}) bool {
+	_, ok := x.(string)
+	return ok
+}
+
+func string__MINUS__ref(s string, k schemeInt) rune {
+	return []rune(s)[k.Int64()]
+}
+
+func substring(s string, start, end schemeInt) string {
+	return string([]rune(s)[start.Int64():end.Int64()])
+}
+
+func string__MINUS__append(args ...string) string {
+	return strings.Join(args, "")
+}
+
+// string__MINUS____GT__number returns false if s isn't a number
+func string__MINUS____GT__number(s string) interface{} {
+	s = strings.TrimPrefix(s, "+")
+	switch {
+	case strings.Contains(s, "/"):
+		r, ok := new(big.Rat).SetString(s)
+		if !ok {
+			return false
+		}
+		if r.IsInt() {
+			return normalisedInt(r.Num())
+		}
+		return r
+	case strings.ContainsAny(s, ".eE"):
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return false
+		}
+		return f
+	default:
+		b, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return false
+		}
+		return normalisedInt(b)
+	}
+}
+
+func number__MINUS____GT__string[T number](n T) string {
+	return numberRepresentation(n)
+}
+
+func string__MINUS____GT__symbol(s string) string {
+	return s
+}
+
+func string__MINUS__split(s, sep string) schemeList[string] {
+	return strings.Split(s, sep)
+}
+
+func string__MINUS__join(l schemeList[string], sep string) string {
+	return strings.Join(l, sep)
+}
+
+// string__MINUS__index returns false if c isn't in s
+func string__MINUS__index(s string, c rune) interface{} {
+	for i, r := range []rune(s) {
+		if r == c {
+			return schemeInt{small: int64(i)}
+		}
+	}
+	return false
+}
+`
+
+const equivRuntime = `
+func eqv__P__(a, b interface{}) bool {
+	switch av := a.(type) {
+	case schemeInt:
+		bv, ok := b.(schemeInt)
+		return ok && intCmp(av, bv) == 0
+	case *big.Rat:
+		bv, ok := b.(*big.Rat)
+		return ok && av.Cmp(bv) == 0
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !va.IsValid() || !vb.IsValid() || va.Type() != vb.Type() {
+		return a == b
+	}
+	switch va.Kind() {
+	case reflect.Slice:
+		// Vectors are the same if they share storage
+		return va.Len() == vb.Len() && va.Pointer() == vb.Pointer()
+	case reflect.Func:
+		return va.Pointer() == vb.Pointer()
+	}
+	return a == b
+}
+
+func eq__P__(a, b interface{}) bool {
+	return eqv__P__(a, b)
+}
+
+func equal__P__(a, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.IsValid() && vb.IsValid() && va.Type() == vb.Type() && va.Kind() == reflect.Slice {
+		if va.Len() != vb.Len() {
+			return false
+		}
+		for i := 0; i < va.Len(); i++ {
+			if !equal__P__(va.Index(i).Interface(), vb.Index(i).Interface()) {
+				return false
+			}
+		}
+		return true
+	}
+	return eqv__P__(a, b)
+}
+
+func not(x interface{}) bool {
+	b, ok := x.(bool)
+	return ok && !b
+}
+`
+
+// exceptionRuntime implements raise and with-exception-handler with a
+// stack of handlers, which run where the exception was raised. A guard
+// panics back to its own frame, and recovers there.
+const exceptionRuntime = `
+type schemeHandler struct {
+	f    func(interface{}) interface{}
+	next *schemeHandler
+}
+
+var schemeHandlers *schemeHandler
+
+// schemeErrorObject is the condition raised by error, and by runtime
+// failures inside a guard
+type schemeErrorObject struct {
+	message   string
+	irritants schemeList[interface{}]
+}
+
+func (eo *schemeErrorObject) describe() string {
+	parts := []string{eo.message}
+	for _, x := range eo.irritants {
+		parts = append(parts, schemeRepresentation(x))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (eo *schemeErrorObject) schemeRepresentation() string {
+	return "#<error-object " + eo.describe() + ">"
+}
+
+// schemeUncaught is the panic for an exception which no handler took
+type schemeUncaught struct {
+	obj interface{}
+}
+
+func (u schemeUncaught) String() string {
+	if eo, ok := u.obj.(*schemeErrorObject); ok {
+		return eo.describe()
+	}
+	return "Uncaught exception: " + schemeRepresentation(u.obj)
+}
+
+// reportUncaught exits with the description of an exception which no
+// handler took
+func reportUncaught() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	u, ok := r.(schemeUncaught)
+	if !ok {
+		panic(r)
+	}
+	fmt.Fprintln(os.Stderr, u)
+	os.Exit(1)
+}
+
+// schemeRaise applies the innermost handler to obj, with the handlers
+// outside it installed
+func schemeRaise(obj interface{}, continuable bool) interface{} {
+	h := schemeHandlers
+	if h == nil {
+		panic(schemeUncaught{obj})
+	}
+	schemeHandlers = h.next
+	defer func() {
+		schemeHandlers = h
+	}()
+	value := h.f(obj)
+	if !continuable {
+		// A secondary exception, in the handler's environment
+		schemeRaise(&schemeErrorObject{
+			message:   "Exception handler returned from non-continuable raise",
+			irritants: schemeList[interface{}]{obj},
+		}, false)
+	}
+	return value
+}
+
+func raise[T any](obj interface{}) T {
+	schemeRaise(obj, false)
+	panic("Returned from raise")
+}
+
+func raise__MINUS__continuable[T any](obj interface{}) T {
+	value := schemeRaise(obj, true)
+	t, ok := value.(T)
+	if !ok && value != nil {
+		panic(fmt.Sprintf("Exception handler returned %T, expected %T", value, t))
+	}
+	return t
+}
+
+func error__[T any](message string, irritants ...interface{}) T {
+	return raise[T](&schemeErrorObject{message: message, irritants: irritants})
+}
+
+func with__MINUS__exception__MINUS__handler[T any](handler func(interface{}) T, thunk func() T) T {
+	outer := schemeHandlers
+	schemeHandlers = &schemeHandler{
+		f:    func(obj interface{}) interface{} { return handler(obj) },
+		next: outer,
+	}
+	defer func() {
+		schemeHandlers = outer
+	}()
+	return thunk()
+}
+
+// schemeGuardEscape takes a condition back to the guard which caught it
+type schemeGuardEscape struct {
+	guard *int
+	obj   interface{}
+}
+
+// __PCT__guard is what the guard form expands to
+func __PCT__guard[T any](body func() T, onRaise func(interface{}) T) (result T) {
+	guard := new(int)
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		var obj interface{}
+		switch p := r.(type) {
+		case schemeGuardEscape:
+			if p.guard != guard {
+				panic(r)
+			}
+			obj = p.obj
+		default:
+			// A failure in the runtime, such as an index out of range
+			obj = &schemeErrorObject{message: fmt.Sprint(r)}
+		}
+		result = onRaise(obj)
+	}()
+	return with__MINUS__exception__MINUS__handler(func(obj interface{}) T {
+		panic(schemeGuardEscape{guard: guard, obj: obj})
+	}, body)
+}
+
+// The derived forms call these by their reserved names, so that a
+// local of the same name doesn't shadow them
+
+func __PCT____EQUAL__[T number](args ...T) bool {
+	return __EQUAL__(args...)
+}
+
+func __PCT____GT____EQUAL__[T number](args ...T) bool {
+	return __GT____EQUAL__(args...)
+}
+
+func __PCT__eqv__P__(a, b interface{}) bool {
+	return eqv__P__(a, b)
+}
+
+func __PCT__error[T any](message string, irritants ...interface{}) T {
+	return error__[T](message, irritants...)
+}
+
+func __PCT__raise__MINUS__continuable[T any](obj interface{}) T {
+	return raise__MINUS__continuable[T](obj)
+}
+
+func __PCT__length[T any](l schemeList[T]) schemeInt {
+	return length(l)
+}
+
+func __PCT__void() {
+}
+
+func error__MINUS__object__P__(x interface{}) bool {
+	_, ok := x.(*schemeErrorObject)
+	return ok
+}
+
+func error__MINUS__object__MINUS__message(x interface{}) string {
+	return x.(*schemeErrorObject).message
+}
+
+func error__MINUS__object__MINUS__irritants(x interface{}) schemeList[interface{}] {
+	return x.(*schemeErrorObject).irritants
+}
+`
+
+// registeredRuntime calls the functions added with gol.Register, and
+// converts between their golang types and the types of compiled code
+const registeredRuntime = `
+var schemeIntType = reflect.TypeOf(schemeInt{})
+
+// callRegistered calls f with args, and returns its results other than
+// a final error, which is raised if it isn't nil
+func callRegistered(name string, f interface{}, args ...interface{}) []reflect.Value {
+	fv := reflect.ValueOf(f)
+	ft := fv.Type()
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		if ft.IsVariadic() && i >= ft.NumIn()-1 {
+			in[i] = toRegistered(name, arg, ft.In(ft.NumIn()-1).Elem())
+		} else {
+			in[i] = toRegistered(name, arg, ft.In(i))
+		}
+	}
+	out := fv.Call(in)
+	errorType := reflect.TypeOf((*error)(nil)).Elem()
+	if n := len(out); n > 0 && ft.Out(n-1) == errorType {
+		if !out[n-1].IsNil() {
+			raise[interface{}](&schemeErrorObject{message: name + ": " + out[n-1].Interface().(error).Error()})
+		}
+		out = out[:n-1]
+	}
+	return out
+}
+
+// toRegistered converts x to t, for an arg to a registered function
+func toRegistered(name string, x interface{}, t reflect.Type) reflect.Value {
+	v := reflect.ValueOf(x)
+	switch {
+	case v.Type() == schemeIntType:
+		i := x.(schemeInt).Int64()
+		out := reflect.New(t).Elem()
+		switch t.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if i < 0 || out.OverflowUint(uint64(i)) {
+				raise[interface{}](&schemeErrorObject{message: fmt.Sprintf("%s: %d is out of range for %s", name, i, t)})
+			}
+			out.SetUint(uint64(i))
+		default:
+			if out.OverflowInt(i) {
+				raise[interface{}](&schemeErrorObject{message: fmt.Sprintf("%s: %d is out of range for %s", name, i, t)})
+			}
+			out.SetInt(i)
+		}
+		return out
+	case v.Kind() == reflect.Slice:
+		s := reflect.MakeSlice(t, v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			s.Index(i).Set(toRegistered(name, v.Index(i).Interface(), t.Elem()))
+		}
+		return s
+	default:
+		return v.Convert(t)
+	}
+}
+
+// fromRegistered converts a result of a registered function to T
+func fromRegistered[T any](v reflect.Value) T {
+	var t T
+	setFromRegistered(reflect.ValueOf(&t).Elem(), v)
+	return t
+}
+
+func setFromRegistered(dst, v reflect.Value) {
+	switch {
+	case dst.Type() == schemeIntType:
+		if v.CanInt() {
+			dst.Set(reflect.ValueOf(schemeInt{small: v.Int()}))
+		} else {
+			dst.Set(reflect.ValueOf(normalisedInt(new(big.Int).SetUint64(v.Uint()))))
+		}
+	case dst.Kind() == reflect.Slice:
+		s := reflect.MakeSlice(dst.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			setFromRegistered(s.Index(i), v.Index(i))
+		}
+		dst.Set(s)
+	default:
+		dst.Set(v.Convert(dst.Type()))
+	}
+}
+`
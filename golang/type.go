@@ -15,6 +15,12 @@ func golangStringForType(t typ.Type) (string, error) {
 		return golangStringForFunc(ty)
 	case typ.Variadic:
 		return golangStringForVariadic(ty)
+	case typ.Vector:
+		return golangStringForVector(ty)
+	case typ.List:
+		return golangStringForList(ty)
+	case typ.Tuple:
+		return golangStringForTuple(ty)
 	case *typ.Var:
 		tyVal, err := ty.Lookup()
 		if err != nil {
@@ -31,13 +37,19 @@ func golangStringForPrimitive(p typ.Primitive) (string, error) {
 	case typ.Any:
 		return "interface{}", nil
 	case typ.Int:
-		return "int64", nil
+		return "schemeInt", nil
 	case typ.Bool:
 		return "bool", nil
 	case typ.Symbol:
 		return "string", nil
 	case typ.String:
 		return "string", nil
+	case typ.Char:
+		return "rune", nil
+	case typ.Real:
+		return "float64", nil
+	case typ.Rational:
+		return "*big.Rat", nil
 	default:
 		return "", fmt.Errorf("Can't get golang string of unknown primitive type: %s", p)
 	}
@@ -66,3 +78,35 @@ func golangStringForVariadic(v typ.Variadic) (string, error) {
 	}
 	return fmt.Sprintf("...%s", s), nil
 }
+
+func golangStringForVector(v typ.Vector) (string, error) {
+	s, err := golangStringForType(v.Elem)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("[]%s", s), nil
+}
+
+func golangStringForList(l typ.List) (string, error) {
+	s, err := golangStringForType(l.Elem)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("schemeList[%s]", s), nil
+}
+
+// golangStringForTuple is a multiple return type
+func golangStringForTuple(tu typ.Tuple) (string, error) {
+	if len(tu.Elems) == 0 {
+		return "", fmt.Errorf("No values isn't supported in compiled code")
+	}
+	var err error
+	elems := make([]string, len(tu.Elems))
+	for i := range tu.Elems {
+		elems[i], err = golangStringForType(tu.Elems[i])
+		if err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("(%s)", strings.Join(elems, ", ")), nil
+}
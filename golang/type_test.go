@@ -11,16 +11,19 @@ func TestGolangTypeString(t *testing.T) {
 		testType typ.Type
 		expected string
 	}{
-		{typ.Int, "int64"},
+		{typ.Int, "schemeInt"},
 		{typ.String, "string"},
 		{typ.Bool, "bool"},
 		{typ.Symbol, "string"},
+		{typ.Char, "rune"},
+		{typ.Real, "float64"},
+		{typ.Rational, "*big.Rat"},
 		{typ.NewFunc([]typ.Type{typ.String}, typ.String), "func(string) string"},
 		{typ.NewFunc([]typ.Type{
 			typ.String,
 			typ.Int,
 			typ.Bool,
-		}, typ.Int), "func(string,int64,bool) int64"},
+		}, typ.Int), "func(string,schemeInt,bool) schemeInt"},
 	}
 
 	for _, tc := range testCases {
@@ -13,7 +13,7 @@ const (
 	tokBug TokType = iota // Make the zero value something which causes an error
 	tokLParen
 	tokRParen
-	tokInt
+	tokNumber
 	tokIdentifier
 	tokSymbol
 	tokString
@@ -21,6 +21,10 @@ const (
 	tokQuote
 	tokBackQuote
 	tokComma
+	tokDatumComment
+	tokChar
+	tokVectorStart
+	tokBarIdentifier
 )
 
 func (tt TokType) String() string {
@@ -35,8 +39,8 @@ func (tt TokType) String() string {
 		return "tokLParen"
 	case tokRParen:
 		return "tokRParen"
-	case tokInt:
-		return "tokInt"
+	case tokNumber:
+		return "tokNumber"
 	case tokIdentifier:
 		return "tokIdentifier"
 	case tokSymbol:
@@ -45,24 +49,50 @@ func (tt TokType) String() string {
 		return "tokString"
 	case tokBool:
 		return "tokBool"
+	case tokDatumComment:
+		return "tokDatumComment"
+	case tokChar:
+		return "tokChar"
+	case tokVectorStart:
+		return "tokVectorStart"
+	case tokBarIdentifier:
+		return "tokBarIdentifier"
 	default:
 		return "<unknown>"
 	}
 }
 
+// Position is a point in the source. Offset counts bytes from 0.
 type Position struct {
 	File   string
 	Line   int
 	Column int
+	Offset int
+}
+
+// Span runs from the start of a source construct to just after its end
+type Span struct {
+	Start Position
+	End   Position
+}
+
+func (s Span) IsZero() bool {
+	return s == Span{}
 }
 
 type Token struct {
 	Type  TokType
 	Value string
 	Pos   Position
+	// Just after the last rune of the token
+	End Position
 }
 
-var TokBug = Token{tokBug, "error", Position{"error", 0, 0}}
+func (t Token) Span() Span {
+	return Span{Start: t.Pos, End: t.End}
+}
+
+var TokBug = Token{Type: tokBug, Value: "error", Pos: Position{File: "error"}}
 
 func (t Token) String() string {
 	return fmt.Sprintf("%s [%s]", t.Type, t.Value)
@@ -80,8 +110,15 @@ type Lexer struct {
 	// index of next rune in unlexed data
 	pos int
 
-	line int
-	col  int
+	line   int
+	col    int
+	offset int
+
+	// Where the token we are assembling started
+	start Position
+
+	// Set by emit, for Next to return
+	next *Token
 }
 
 func NewLexer(fname string, r io.Reader) *Lexer {
@@ -93,71 +130,196 @@ func NewLexer(fname string, r io.Reader) *Lexer {
 	}
 }
 
+// Run sends all the tokens to l.Tokens, closing it at EOF or on error.
+// It blocks until each token is received, so callers which stop
+// reading early should use Next instead.
 func (l *Lexer) Run() error {
-	for !l.isEOF() {
-		l.skipWhitespace()
-		r := l.peekNextRune()
-		switch {
-		case r == '\'':
-			l.stepRune()
-			l.emit(tokQuote)
-		case r == '`':
-			l.stepRune()
-			l.emit(tokBackQuote)
-		case r == ',':
+	defer close(l.Tokens)
+	for {
+		tok, err := l.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		l.Tokens <- tok
+	}
+}
+
+// Next lexes and returns the next token, or io.EOF when there are no more
+func (l *Lexer) Next() (Token, error) {
+	for l.next == nil {
+		if l.isEOF() {
+			return TokBug, io.EOF
+		}
+		err := l.lexOne()
+		if err != nil {
+			return TokBug, err
+		}
+	}
+	tok := *l.next
+	l.next = nil
+	return tok, nil
+}
+
+// lexOne consumes whitespace, a comment or a single token
+func (l *Lexer) lexOne() error {
+	l.skipWhitespace()
+	l.start = l.currentPosition()
+	r := l.peekNextRune()
+	switch {
+	case r == ';':
+		l.skipLineComment()
+	case r == '\'':
+		l.stepRune()
+		l.emit(tokQuote)
+	case r == '`':
+		l.stepRune()
+		l.emit(tokBackQuote)
+	case r == ',':
+		l.stepRune()
+		l.emit(tokComma)
+	case r == utf8.RuneError:
+		// EOF case
+		break
+	case r == '#':
+		l.stepRune()
+		switch l.peekNextRune() {
+		case '|':
+			err := l.skipBlockComment()
+			if err != nil {
+				return err
+			}
+		case ';':
 			l.stepRune()
-			l.emit(tokComma)
-		case r == utf8.RuneError:
-			// EOF case
-			break
-		case r == '#':
+			l.emit(tokDatumComment)
+		case '(':
 			l.stepRune()
+			l.emit(tokVectorStart)
+		case '\\':
 			l.stepRune()
-			l.emit(tokBool)
-		case r == '"':
-			l.skipRune()
-			escaped := true
-			l.emitMatching(tokString, func(r rune) bool {
-				if r == '\\' {
-					escaped = !escaped
-					return true
-				} else if escaped {
-					escaped = false
-					return true
-				} else {
-					escaped = false
-					return r != '"'
-				}
+			l.stepRune() // The char itself, which may be a delimiter
+			l.emitMatching(tokChar, func(r rune) bool {
+				return !isDelimiter(r)
 			})
-			l.skipRune()
-		case r == '(':
-			l.stepRune()
-			l.emit(tokLParen)
-		case r == ')':
-			l.stepRune()
-			l.emit(tokRParen)
-		case r == '+' || r == '-' || unicode.IsDigit(r):
-			l.stepRune() // Allow the leading sign
-			l.emitMatching(tokInt, unicode.IsDigit)
-		case unicode.IsSpace(r):
+		default:
 			l.stepRune()
-		case r == '\'':
-			l.emitMatching(tokSymbol, func(r rune) bool {
-				return !unicode.IsSpace(r) && r != '(' && r != ')'
+			l.emit(tokBool)
+		}
+	case r == '"':
+		l.skipRune()
+		escaped := false
+		l.emitMatching(tokString, func(r rune) bool {
+			if r == '\\' {
+				escaped = !escaped
+				return true
+			} else if escaped {
+				escaped = false
+				return true
+			} else {
+				escaped = false
+				return r != '"'
+			}
+		})
+		if l.isEOF() {
+			l.next = nil
+			return posErrorf(l.start, "Unterminated string")
+		}
+		l.skipRune()
+	case r == '|':
+		// An identifier which may have any characters, escaped as in
+		// strings
+		l.skipRune()
+		escaped := false
+		l.emitMatching(tokBarIdentifier, func(r rune) bool {
+			if r == '\\' {
+				escaped = !escaped
+				return true
+			} else if escaped {
+				escaped = false
+				return true
+			} else {
+				return r != '|'
+			}
+		})
+		if l.isEOF() {
+			l.next = nil
+			return posErrorf(l.start, "Unterminated identifier")
+		}
+		l.skipRune()
+	case r == '(':
+		l.stepRune()
+		l.emit(tokLParen)
+	case r == ')':
+		l.stepRune()
+		l.emit(tokRParen)
+	case r == '+' || r == '-' || unicode.IsDigit(r):
+		l.stepRune() // Allow the leading sign
+		next := l.peekNextRune()
+		if unicode.IsDigit(r) || unicode.IsDigit(next) || next == '.' {
+			l.emitMatching(tokNumber, func(r rune) bool {
+				return !isDelimiter(r)
 			})
-		case !unicode.IsSpace(r):
+		} else {
+			// A bare sign, or an identifier like '->x'
 			l.emitMatching(tokIdentifier, func(r rune) bool {
-				return !unicode.IsSpace(r) && r != '(' && r != ')'
+				return !isDelimiter(r)
 			})
-		default:
-			close(l.Tokens)
-			return posErrorf(l.currentPosition(), "Internal error: unrecognised rune [%c]", r)
 		}
+	case unicode.IsSpace(r):
+		l.stepRune()
+	case r == '\'':
+		l.emitMatching(tokSymbol, func(r rune) bool {
+			return !isDelimiter(r)
+		})
+	case !unicode.IsSpace(r):
+		l.emitMatching(tokIdentifier, func(r rune) bool {
+			return !isDelimiter(r)
+		})
+	default:
+		return posErrorf(l.currentPosition(), "Internal error: unrecognised rune [%c]", r)
 	}
-	close(l.Tokens)
 	return nil
 }
 
+func isDelimiter(r rune) bool {
+	return unicode.IsSpace(r) || r == '(' || r == ')' || r == '"' || r == ';' || r == '|'
+}
+
+// Line comments run from ';' to the end of the line
+func (l *Lexer) skipLineComment() {
+	for !l.isEOF() && l.peekNextRune() != '\n' {
+		l.stepRune()
+	}
+	l.discardToPos()
+}
+
+// Block comments run from '#|' to '|#' and may nest. We are called
+// with the '#' already consumed.
+func (l *Lexer) skipBlockComment() error {
+	depth := 0
+	prev := '#'
+	for !l.isEOF() {
+		r := l.peekNextRune()
+		l.stepRune()
+		switch {
+		case prev == '#' && r == '|':
+			depth++
+			r = 0 // '#|#' doesn't close
+		case prev == '|' && r == '#':
+			depth--
+			r = 0
+		}
+		prev = r
+		if depth == 0 {
+			l.discardToPos()
+			return nil
+		}
+	}
+	return posErrorf(l.start, "Unterminated block comment")
+}
+
 func (l *Lexer) isEOF() bool {
 	return l.seenEOF && l.pos == len(l.buf)
 }
@@ -166,10 +328,6 @@ func (l *Lexer) skipWhitespace() bool {
 PEEKING:
 	for !l.isEOF() {
 		next := l.peekNextRune()
-		if next == '\n' {
-			l.line++
-			l.col = 0
-		}
 		if unicode.IsSpace(next) {
 			l.stepRune()
 			l.discardToPos()
@@ -195,9 +353,18 @@ func (l *Lexer) peekNextRune() rune {
 
 func (l *Lexer) stepRune() {
 	l.fetchCheck()
-	_, size := utf8.DecodeRune(l.buf[l.pos:])
+	r, size := utf8.DecodeRune(l.buf[l.pos:])
+	if size == 0 {
+		return
+	}
 	l.pos += size
-	l.col += size
+	l.offset += size
+	if r == '\n' {
+		l.line++
+		l.col = 0
+	} else {
+		l.col++
+	}
 }
 
 func (l *Lexer) discardToPos() {
@@ -225,12 +392,13 @@ func (l *Lexer) emitMatching(tokType TokType, f func(r rune) bool) {
 	l.emit(tokType)
 }
 
+// Columns count runes from 1, like lines
 func (l Lexer) currentPosition() Position {
-	return Position{File: l.fname, Line: l.line, Column: l.col}
+	return Position{File: l.fname, Line: l.line, Column: l.col + 1, Offset: l.offset}
 }
 
 func (l *Lexer) emit(tokType TokType) {
-	tok := Token{Pos: l.currentPosition(), Type: tokType, Value: string(l.buf[:l.pos])}
-	l.Tokens <- tok
+	tok := Token{Pos: l.start, End: l.currentPosition(), Type: tokType, Value: string(l.buf[:l.pos])}
+	l.next = &tok
 	l.discardToPos()
 }
@@ -0,0 +1,181 @@
+package gol
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func lexString(t *testing.T, src string) ([]Token, error) {
+	l := NewLexer("<test>", strings.NewReader(src))
+	var lexErr error
+	lexDone := make(chan struct{})
+	go func() {
+		lexErr = l.Run()
+		close(lexDone)
+	}()
+
+	var toks []Token
+	for tok := range l.Tokens {
+		toks = append(toks, tok)
+	}
+	<-lexDone
+	return toks, lexErr
+}
+
+func TestLexComments(t *testing.T) {
+	testCases := []struct {
+		src    string
+		values []string
+	}{
+		{"; nothing here", nil},
+		{"1 ; one\n2", []string{"1", "2"}},
+		{"(foo;bar\n)", []string{"(", "foo", ")"}},
+		{"#| block |# 3", []string{"3"}},
+		{"#| outer #| inner |# still outer |# 4", []string{"4"}},
+		{"#|\n multi\n line\n|#5", []string{"5"}},
+		{"#|#|#|x|#|#|# 6", []string{"6"}},
+		{"#;(a b) 7", []string{"#;", "(", "a", "b", ")", "7"}},
+		{`"a ; string" x`, []string{"a ; string", "x"}},
+	}
+
+	for _, tc := range testCases {
+		toks, err := lexString(t, tc.src)
+		if err != nil {
+			t.Errorf("Error lexing [%s]: %s", tc.src, err)
+			continue
+		}
+		values := make([]string, len(toks))
+		for i := range toks {
+			values[i] = toks[i].Value
+		}
+		if strings.Join(values, "|") != strings.Join(tc.values, "|") {
+			t.Errorf("Wrong tokens for [%s]: %v != %v", tc.src, values, tc.values)
+		}
+	}
+}
+
+func TestLexUnterminatedBlockComment(t *testing.T) {
+	_, err := lexString(t, "1 #| #| |# 2")
+	if err == nil {
+		t.Fatalf("No error for unterminated block comment")
+	}
+	if !strings.HasPrefix(err.Error(), "Unterminated block comment: <test> line 1:3") {
+		t.Fatalf("Wrong error: %s", err)
+	}
+}
+
+func TestLexPositions(t *testing.T) {
+	src := `; header
+(define x ; the x
+  #| skip
+  me |# "str")
+#;(ignored
+   form) x`
+	toks, err := lexString(t, src)
+	if err != nil {
+		t.Fatalf("Error lexing: %s", err)
+	}
+	expected := []struct {
+		value string
+		line  int
+		col   int
+	}{
+		{"(", 2, 1},
+		{"define", 2, 2},
+		{"x", 2, 9},
+		{"str", 4, 9},
+		{")", 4, 14},
+		{"#;", 5, 1},
+		{"(", 5, 3},
+		{"ignored", 5, 4},
+		{"form", 6, 4},
+		{")", 6, 8},
+		{"x", 6, 10},
+	}
+	if len(toks) != len(expected) {
+		t.Fatalf("Wrong number of tokens: %d != %d: %v", len(toks), len(expected), toks)
+	}
+	for i, e := range expected {
+		tok := toks[i]
+		if tok.Value != e.value || tok.Pos.Line != e.line || tok.Pos.Column != e.col {
+			t.Errorf("%d: got [%s] at %d:%d, expected [%s] at %d:%d",
+				i, tok.Value, tok.Pos.Line, tok.Pos.Column, e.value, e.line, e.col)
+		}
+	}
+}
+
+func TestLexChars(t *testing.T) {
+	toks, err := lexString(t, `(#\a #\) #\space #\x41)`)
+	if err != nil {
+		t.Fatalf("Error lexing: %s", err)
+	}
+	expected := []string{"(", `#\a`, `#\)`, `#\space`, `#\x41`, ")"}
+	if len(toks) != len(expected) {
+		t.Fatalf("Wrong number of tokens: %v", toks)
+	}
+	for i := range expected {
+		if toks[i].Value != expected[i] {
+			t.Errorf("%d: [%s] != [%s]", i, toks[i].Value, expected[i])
+		}
+	}
+	if toks[2].Type != tokChar {
+		t.Errorf("Wrong type for char token: %s", toks[2].Type)
+	}
+}
+
+func TestLexStrings(t *testing.T) {
+	toks, err := lexString(t, `("" "a\"b" "\"q" x)`)
+	if err != nil {
+		t.Fatalf("Error lexing: %s", err)
+	}
+	expected := []string{"(", ``, `a\"b`, `\"q`, "x", ")"}
+	if len(toks) != len(expected) {
+		t.Fatalf("Wrong number of tokens: %v", toks)
+	}
+	for i := range expected {
+		if toks[i].Value != expected[i] {
+			t.Errorf("%d: [%s] != [%s]", i, toks[i].Value, expected[i])
+		}
+	}
+	if toks[1].Type != tokString {
+		t.Errorf("Wrong type for empty string token: %s", toks[1].Type)
+	}
+}
+
+func TestLexNext(t *testing.T) {
+	src := "(define x #| c |# '(1 . \"two\")) ; done\n#\\a"
+	want, err := lexString(t, src)
+	if err != nil {
+		t.Fatalf("Error lexing: %s", err)
+	}
+
+	l := NewLexer("<test>", strings.NewReader(src))
+	var got []Token
+	for {
+		tok, err := l.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Error from Next: %s", err)
+		}
+		got = append(got, tok)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Next and Run disagree: %v != %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%d: %v != %v", i, got[i], want[i])
+		}
+	}
+
+	l = NewLexer("<test>", strings.NewReader("1 #| unterminated"))
+	if _, err := l.Next(); err != nil {
+		t.Fatalf("Error before the bad comment: %s", err)
+	}
+	if _, err := l.Next(); err == nil || err == io.EOF {
+		t.Fatalf("No error for unterminated block comment: %v", err)
+	}
+}
@@ -7,7 +7,7 @@ func (nl *NodeList) ForeachLast(f func(n Node, last bool) error) error {
 	for !p.IsNil() {
 		pNext, ok := p.Cdr.(*NodePair)
 		if !ok {
-			panic("Foreach on improoper list")
+			return NodeErrorf(nl, "Foreach on improper list")
 		}
 
 		err := f(p.Car, pNext.IsNil())
@@ -41,7 +41,7 @@ func (nl *NodeList) Map(f func(n Node) (Node, error)) (*NodeList, error) {
 		var ok bool
 		p, ok = p.Cdr.(*NodePair)
 		if !ok {
-			panic("Map on improoper list")
+			return nil, NodeErrorf(nl, "Map on improper list")
 		}
 	}
 	rev := res.ReverseCopy()
@@ -57,6 +57,11 @@ func (nl *NodeList) Len() int {
 	return l
 }
 
+// IsEmpty is Len() == 0, without walking the list
+func (nl *NodeList) IsEmpty() bool {
+	return nl.children.IsNil()
+}
+
 func (nl *NodeList) Nth(n int) Node {
 	if n == 0 {
 		return nl.children.Car
@@ -80,31 +85,35 @@ func (nl *NodeList) Rest() *NodeList {
 }
 
 func (nl *NodeList) String() string {
+	if nl.children.IsNil() {
+		return "()"
+	}
+	return pairChainString(nl.children)
+}
+
+// pairChainString prints a chain of pairs in list notation, using a
+// dot before the final cdr only if the chain is improper.
+func pairChainString(p *NodePair) string {
 	s := []byte("(")
-	n := nl.children
-	first := true
+	s = append(s, p.Car.String()...)
+	rest := p.Cdr
 	for {
-		if n.IsNil() {
-			break
-		}
-
-		if !first {
+		switch r := rest.(type) {
+		case *NodePair:
+			if r.IsNil() {
+				return string(append(s, ')'))
+			}
 			s = append(s, ' ')
+			s = append(s, r.Car.String()...)
+			rest = r.Cdr
+		case *NodeList:
+			rest = r.children
+		default:
+			s = append(s, " . "...)
+			s = append(s, rest.String()...)
+			return string(append(s, ')'))
 		}
-		first = false
-
-		s = append(s, n.Car.String()...)
-		//		s = append(s, []byte(fmt.Sprintf(" [%T]", n.Car))...)
-
-		var ok bool
-		n, ok = n.Cdr.(*NodePair)
-		if !ok {
-			panic(fmt.Sprintf("NodeList with non-list children: %T\n", n))
-		}
-
 	}
-	s = append(s, ')')
-	return string(s)
 }
 
 /*
@@ -124,6 +133,110 @@ func (nl *NodeList) String() string {
 }
 */
 
+// Cons returns a proper list if cdr is a list, and a pair otherwise
+func Cons(car, cdr Node) Node {
+	switch c := cdr.(type) {
+	case *NodeList:
+		return c.Cons(car)
+	case *NodePair:
+		if c.IsNil() {
+			return NewNodeList().Cons(car)
+		}
+	}
+	return NewNodePair(car, cdr)
+}
+
+// Car returns false if n is not a pair or non-empty list
+func Car(n Node) (Node, bool) {
+	p, ok := pairOf(n)
+	if !ok {
+		return nil, false
+	}
+	return p.Car, true
+}
+
+// Cdr returns false if n is not a pair or non-empty list. The cdr of a
+// list is returned as a list.
+func Cdr(n Node) (Node, bool) {
+	p, ok := pairOf(n)
+	if !ok {
+		return nil, false
+	}
+	if next, ok := p.Cdr.(*NodePair); ok {
+		if next.IsNil() {
+			return NewNodeList(), true
+		}
+		if _, isList := n.(*NodeList); isList {
+			ret := NewNodeList()
+			ret.children = next
+			return ret, true
+		}
+	}
+	return p.Cdr, true
+}
+
+func SetCar(n Node, v Node) bool {
+	p, ok := pairOf(n)
+	if !ok {
+		return false
+	}
+	p.Car = v
+	return true
+}
+
+func SetCdr(n Node, v Node) bool {
+	p, ok := pairOf(n)
+	if !ok {
+		return false
+	}
+	if nl, ok := v.(*NodeList); ok {
+		p.Cdr = nl.children
+	} else {
+		p.Cdr = v
+	}
+	return true
+}
+
+func IsPair(n Node) bool {
+	_, ok := pairOf(n)
+	return ok
+}
+
+func IsNull(n Node) bool {
+	switch c := n.(type) {
+	case *NodeList:
+		return c.children.IsNil()
+	case *NodePair:
+		return c.IsNil()
+	}
+	return false
+}
+
+// IsSamePair is true if a and b are the same pair, even if they are
+// wrapped in different lists
+func IsSamePair(a, b Node) bool {
+	pa, ok := pairOf(a)
+	if !ok {
+		return false
+	}
+	pb, ok := pairOf(b)
+	return ok && pa == pb
+}
+
+func pairOf(n Node) (*NodePair, bool) {
+	switch c := n.(type) {
+	case *NodeList:
+		if !c.children.IsNil() {
+			return c.children, true
+		}
+	case *NodePair:
+		if !c.IsNil() {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
 func (nl *NodeList) Cons(n Node) *NodeList {
 	ret := NewNodeList()
 	ret.children = NewNodePair(n, nl.children)
@@ -117,6 +117,31 @@ func TestListCons(t *testing.T) {
 
 }
 
+func TestListImproper(t *testing.T) {
+	p := Cons(NewNodeInt(1), NewNodeInt(2))
+	if p.String() != "(1 . 2)" {
+		t.Fatalf("Wrong pair string: %s", p)
+	}
+	l := Cons(NewNodeInt(0), p)
+	if l.String() != "(0 1 . 2)" {
+		t.Fatalf("Wrong improper list string: %s", l)
+	}
+	if _, ok := Cons(NewNodeInt(0), makeListTo(2)).(*NodeList); !ok {
+		t.Fatalf("Cons onto a list isn't a list")
+	}
+
+	l2 := makeListTo(3)
+	tail, _ := Cdr(l2)
+	tail, _ = Cdr(tail)
+	SetCdr(tail, NewNodeInt(4))
+	if l2.String() != "(1 2 3 . 4)" {
+		t.Fatalf("SetCdr didn't make an improper list: %s", l2)
+	}
+	if err := l2.Foreach(func(n Node) error { return nil }); err == nil {
+		t.Fatalf("No error from Foreach on improper list")
+	}
+}
+
 func TestListMap(t *testing.T) {
 	l := makeListTo(5)
 	t.Logf("initial list: %s\n", l)
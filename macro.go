@@ -0,0 +1,754 @@
+package gol
+
+// Expand runs the macro expander over a parsed program, before
+// Transform. It replaces every macro use with its expansion, so later
+// phases (and both backends) only see the core forms.
+//
+// Hygiene comes from renaming. Each identifier a template introduces
+// becomes an alias, which refers to whatever its name meant where the
+// macro was defined. Variables bound by an alias are always given a
+// fresh name, and a variable bound by the program is renamed if an alias
+// reference would otherwise be captured by it.
+func Expand(node Node) (Node, error) {
+	return ExpandWith(node, nil)
+}
+
+// ExpandWith is Expand, using ev to run procedural macro transformers.
+// With a nil ev, only syntax-rules macros are available.
+func ExpandWith(node Node, ev MacroEvaluator) (Node, error) {
+	progn, ok := node.(*NodeList)
+	if !ok || !isIdentifier(progn.First(), "progn") {
+		return nil, NodeErrorf(node, "Expand needs a program progn, got %T", node)
+	}
+	x := &expander{aliases: make(map[*NodeIdentifier]*syntaxAlias), ev: ev}
+	body, err := x.expandBody(progn, progn.Rest(), newSyntaxScope(nil), true)
+	if err != nil {
+		return nil, err
+	}
+	x.renameBindings()
+	return synthList(progn, append([]Node{progn.First()}, body...)...), nil
+}
+
+type expander struct {
+	aliases  map[*NodeIdentifier]*syntaxAlias
+	bindings []*syntaxBinding
+	ev       MacroEvaluator
+}
+
+// syntaxAlias is the identity shared by all the occurrences of one
+// template identifier in one expansion
+type syntaxAlias struct {
+	orig *NodeIdentifier
+	env  *syntaxScope
+}
+
+type syntaxScope struct {
+	parent *syntaxScope
+	// Keyed by name for program identifiers, by *syntaxAlias for aliases
+	vars map[interface{}]*syntaxBinding
+}
+
+func newSyntaxScope(parent *syntaxScope) *syntaxScope {
+	return &syntaxScope{parent: parent, vars: make(map[interface{}]*syntaxBinding)}
+}
+
+// macroTransformer rewrites a use of a macro
+type macroTransformer interface {
+	expand(x *expander, form *NodeList, s *syntaxScope) (Node, error)
+}
+
+// syntaxBinding is either a local variable or a macro
+type syntaxBinding struct {
+	base   string
+	scope  *syntaxScope
+	macro  macroTransformer
+	rename bool
+	uses   []*NodeIdentifier
+}
+
+// coreForms are the keywords the expander understands itself, when
+// they aren't shadowed by a binding
+var coreForms = map[string]bool{
+	"quote": true, "quasiquote": true, "unquote": true,
+	"lambda": true, "define": true, "set!": true, "if": true, "progn": true, "begin": true,
+	"let": true, "letrec": true, "letrec*": true, "let*": true, "do": true,
+	"let-values": true, "let*-values": true, "define-values": true, "receive": true,
+	"cond": true, "case": true, "case-lambda": true, "guard": true, "and": true, "or": true, "when": true, "unless": true,
+	"define-syntax": true, "let-syntax": true, "letrec-syntax": true, "syntax-rules": true,
+	"er-macro-transformer": true, "sc-macro-transformer": true, "rsc-macro-transformer": true,
+}
+
+func (x *expander) key(id *NodeIdentifier) interface{} {
+	if a, ok := x.aliases[id]; ok {
+		return a
+	}
+	return id.String()
+}
+
+// resolve finds the binding for id, or nil if it is free
+func (x *expander) resolve(id *NodeIdentifier, s *syntaxScope) *syntaxBinding {
+	k := x.key(id)
+	for sc := s; sc != nil; sc = sc.parent {
+		if b, ok := sc.vars[k]; ok {
+			return b
+		}
+	}
+	if a, ok := x.aliases[id]; ok {
+		return x.resolve(a.orig, a.env)
+	}
+	return nil
+}
+
+// sameBinding is true if a in aEnv means the same as b in bEnv
+func (x *expander) sameBinding(a *NodeIdentifier, aEnv *syntaxScope, b *NodeIdentifier, bEnv *syntaxScope) bool {
+	ba, bb := x.resolve(a, aEnv), x.resolve(b, bEnv)
+	if ba == nil && bb == nil {
+		return a.String() == b.String()
+	}
+	return ba == bb
+}
+
+// coreForm returns the core keyword at the head of n, if any
+func (x *expander) coreForm(n Node, s *syntaxScope) string {
+	nl, ok := n.(*NodeList)
+	if !ok || nl.Len() == 0 {
+		return ""
+	}
+	id, ok := nl.First().(*NodeIdentifier)
+	if !ok || x.resolve(id, s) != nil || !coreForms[id.String()] {
+		return ""
+	}
+	return id.String()
+}
+
+// macroFor returns the macro at the head of n, if any
+func (x *expander) macroFor(n Node, s *syntaxScope) macroTransformer {
+	nl, ok := n.(*NodeList)
+	if !ok || nl.Len() == 0 {
+		return nil
+	}
+	id, ok := nl.First().(*NodeIdentifier)
+	if !ok {
+		return nil
+	}
+	b := x.resolve(id, s)
+	if b == nil {
+		return nil
+	}
+	return b.macro
+}
+
+// expandHead expands n until it is no longer a macro use
+func (x *expander) expandHead(n Node, s *syntaxScope) (Node, error) {
+	for {
+		m := x.macroFor(n, s)
+		if m == nil {
+			return n, nil
+		}
+		var err error
+		n, err = m.expand(x, n.(*NodeList), s)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func copyIdentifier(id *NodeIdentifier) *NodeIdentifier {
+	cp := *id
+	return &cp
+}
+
+func (x *expander) newBinding(id *NodeIdentifier, s *syntaxScope) *syntaxBinding {
+	_, isAlias := x.aliases[id]
+	b := &syntaxBinding{base: id.String(), scope: s, rename: isAlias}
+	s.vars[x.key(id)] = b
+	x.bindings = append(x.bindings, b)
+	return b
+}
+
+// bind makes a new variable for id in s, returning the occurrence to
+// use in the expansion
+func (x *expander) bind(id *NodeIdentifier, s *syntaxScope) *NodeIdentifier {
+	b := x.newBinding(id, s)
+	cp := copyIdentifier(id)
+	b.uses = append(b.uses, cp)
+	return cp
+}
+
+func (x *expander) reference(id *NodeIdentifier, s *syntaxScope) (Node, error) {
+	b := x.resolve(id, s)
+	if b != nil && b.macro != nil {
+		return nil, NodeErrorf(id, "Syntax keyword [%s] used as a variable", id)
+	}
+	if _, isAlias := x.aliases[id]; isAlias {
+		x.markCapturers(id.String(), b, s)
+	}
+	cp := copyIdentifier(id)
+	if b != nil {
+		b.uses = append(b.uses, cp)
+	}
+	return cp, nil
+}
+
+// markCapturers renames any variable called name which sits between
+// the use in s and the binding target (nil for a global) it refers to
+func (x *expander) markCapturers(name string, target *syntaxBinding, s *syntaxScope) {
+	for sc := s; sc != nil; sc = sc.parent {
+		if target != nil && target.scope == sc {
+			return
+		}
+		for _, b := range sc.vars {
+			if b != target && b.macro == nil && b.base == name {
+				b.rename = true
+			}
+		}
+	}
+}
+
+func (x *expander) renameBindings() {
+	for _, b := range x.bindings {
+		if !b.rename || len(b.uses) == 0 {
+			continue
+		}
+		name := gensym(b.base, b.uses[0]).String()
+		for _, id := range b.uses {
+			id.tok.Value = name
+		}
+	}
+}
+
+// expandBody expands a sequence of forms which may contain definitions.
+// Definitions are found first, so that every form in the body sees them.
+func (x *expander) expandBody(source Node, forms *NodeList, s *syntaxScope, top bool) ([]Node, error) {
+	var pending []Node
+	var scan func(forms *NodeList) error
+	scan = func(forms *NodeList) error {
+		return forms.Foreach(func(form Node) error {
+			form, err := x.expandHead(form, s)
+			if err != nil {
+				return err
+			}
+			switch x.coreForm(form, s) {
+			case "define-syntax":
+				return x.defineSyntax(form.(*NodeList), s)
+			case "progn", "begin":
+				// Transform reports the empty progn
+				if form.(*NodeList).Len() > 1 {
+					return scan(form.(*NodeList).Rest())
+				}
+			case "define":
+				if id := definedIdentifier(form.(*NodeList)); id != nil {
+					x.bindDefine(id, s, top)
+				}
+			case "define-values":
+				// The expansion reports bad formals
+				elems, tail, _ := valuesFormals(form, form.(*NodeList).Nth(1))
+				if tail != nil {
+					elems = append(elems, tail)
+				}
+				for _, elem := range elems {
+					x.bindDefine(elem.(*NodeIdentifier), s, top)
+				}
+			}
+			pending = append(pending, form)
+			return nil
+		})
+	}
+	err := scan(forms)
+	if err != nil {
+		return nil, err
+	}
+
+	body := make([]Node, len(pending))
+	for i, form := range pending {
+		body[i], err = x.expand(form, s)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(body) == 0 {
+		// Only macro definitions
+		body = append(body, synthVoid(source))
+	}
+	return body, nil
+}
+
+// definedIdentifier handles both (define id value) and (define (id args) body)
+func definedIdentifier(nl *NodeList) *NodeIdentifier {
+	target := nl.Nth(1)
+	if target == nil {
+		return nil
+	}
+	if elems, _, ok := listParts(target); ok && len(elems) > 0 {
+		target = elems[0]
+	}
+	id, _ := target.(*NodeIdentifier)
+	return id
+}
+
+// bindDefine records a definition. Top-level definitions are globals,
+// which only need to hide any macro of the same name.
+func (x *expander) bindDefine(id *NodeIdentifier, s *syntaxScope, top bool) {
+	k := x.key(id)
+	if top {
+		delete(s.vars, k)
+		return
+	}
+	if b, ok := s.vars[k]; ok && b.macro == nil {
+		return
+	}
+	x.newBinding(id, s)
+}
+
+func (x *expander) expand(n Node, s *syntaxScope) (Node, error) {
+	switch node := n.(type) {
+	case *NodeIdentifier:
+		return x.reference(node, s)
+	case *NodeQuote:
+		if node.Quasi {
+			return x.expandQuasi(node, s, 0)
+		}
+		return node, nil
+	case *NodeList:
+		return x.expandList(node, s)
+	default:
+		return n, nil
+	}
+}
+
+func (x *expander) expandList(nl *NodeList, s *syntaxScope) (Node, error) {
+	if nl.Len() == 0 {
+		return nl, nil
+	}
+	if m := x.macroFor(nl, s); m != nil {
+		expansion, err := m.expand(x, nl, s)
+		if err != nil {
+			return nil, err
+		}
+		return x.expand(expansion, s)
+	}
+
+	switch x.coreForm(nl, s) {
+	case "quote", "unquote":
+		return nl, nil
+	case "quasiquote":
+		return x.expandQuasi(nl, s, 0)
+	case "lambda":
+		return x.expandLambda(nl, s)
+	case "define":
+		return x.expandDefine(nl, s)
+	case "let":
+		if _, ok := nl.Nth(1).(*NodeIdentifier); ok {
+			return x.expandDerived(expandNamedLet, nl, s)
+		}
+		return x.expandLet(nl, s, false)
+	case "letrec", "letrec*":
+		return x.expandLet(nl, s, true)
+	case "let*":
+		return x.expandDerived(expandLetStar, nl, s)
+	case "let-values":
+		return x.expandDerived(expandLetValues, nl, s)
+	case "let*-values":
+		return x.expandDerived(expandLetStarValues, nl, s)
+	case "receive":
+		return x.expandDerived(expandReceive, nl, s)
+	case "define-values":
+		return x.expandDefineValues(nl, s)
+	case "do":
+		return x.expandDerived(expandDo, nl, s)
+	case "case-lambda":
+		return x.expandDerived(expandCaseLambda, nl, s)
+	case "guard":
+		return x.expandDerived(expandGuard, nl, s)
+	case "cond":
+		return x.expandDerived(expandCond, nl, s)
+	case "case":
+		return x.expandDerived(expandCase, nl, s)
+	case "and":
+		return x.expandDerived(expandAnd, nl, s)
+	case "or":
+		return x.expandDerived(expandOr, nl, s)
+	case "when":
+		return x.expandDerived(expandWhen, nl, s)
+	case "unless":
+		return x.expandDerived(expandUnless, nl, s)
+	case "let-syntax":
+		return x.expandLetSyntax(nl, s, false)
+	case "letrec-syntax":
+		return x.expandLetSyntax(nl, s, true)
+	case "define-syntax":
+		return nil, NodeErrorf(nl, "define-syntax is only allowed at top level or in a body")
+	case "syntax-rules", "er-macro-transformer", "sc-macro-transformer", "rsc-macro-transformer":
+		return nil, NodeErrorf(nl, "%s outside a macro definition", nl.First())
+	}
+
+	// An application, or a core form (if, set!, progn, begin) whose
+	// arguments are all expressions
+	return x.expandElements(nl, s)
+}
+
+func (x *expander) expandElements(nl *NodeList, s *syntaxScope) (*NodeList, error) {
+	ret, err := nl.Map(func(child Node) (Node, error) {
+		return x.expand(child, s)
+	})
+	if err != nil {
+		return nil, err
+	}
+	ret.SetSpan(nl.Span())
+	return ret, nil
+}
+
+func (x *expander) expandDerived(rewrite func(n *NodeList) (Node, error), nl *NodeList, s *syntaxScope) (Node, error) {
+	expansion, err := rewrite(nl)
+	if err != nil {
+		return nil, err
+	}
+	return x.expand(expansion, s)
+}
+
+// bindFormals binds lambda args, which may be an improper list
+func (x *expander) bindFormals(formals Node, s *syntaxScope) Node {
+	elems, tail, ok := listParts(formals)
+	if !ok {
+		if id, isId := formals.(*NodeIdentifier); isId {
+			return x.bind(id, s)
+		}
+		return formals
+	}
+	for i, elem := range elems {
+		if id, isId := elem.(*NodeIdentifier); isId {
+			elems[i] = x.bind(id, s)
+		}
+	}
+	if id, isId := tail.(*NodeIdentifier); isId {
+		tail = x.bind(id, s)
+	}
+	return buildList(formals, elems, tail)
+}
+
+// (lambda formals body ...)
+func (x *expander) expandLambda(nl *NodeList, s *syntaxScope) (Node, error) {
+	if nl.Len() < 3 {
+		return nil, NodeErrorf(nl, "Bad lambda expression - missing args or body")
+	}
+	inner := newSyntaxScope(s)
+	formals := x.bindFormals(nl.Nth(1), inner)
+	body, err := x.expandBody(nl, nl.Rest().Rest(), inner, false)
+	if err != nil {
+		return nil, err
+	}
+	return synthList(nl, append([]Node{nl.First(), formals}, body...)...), nil
+}
+
+// (define id value) or (define (id formals ...) body ...)
+func (x *expander) expandDefine(nl *NodeList, s *syntaxScope) (Node, error) {
+	if nl.Len() < 3 {
+		return nil, NodeErrorf(nl, "Bad define expression - wrong arity")
+	}
+	switch target := nl.Nth(1).(type) {
+	case *NodeIdentifier:
+		id, err := x.reference(target, s)
+		if err != nil {
+			return nil, err
+		}
+		rest, err := x.expandElements(nl.Rest().Rest(), s)
+		if err != nil {
+			return nil, err
+		}
+		return synthList(nl, append([]Node{nl.First(), id}, listNodes(rest)...)...), nil
+	default:
+		elems, tail, ok := listParts(target)
+		if !ok || len(elems) == 0 {
+			return nil, NodeErrorf(nl, "Bad define expression - invalid identifier type %T", target)
+		}
+		name, ok := elems[0].(*NodeIdentifier)
+		if !ok {
+			return nil, NodeErrorf(nl, "Bad define expression - invalid identifier type %T", elems[0])
+		}
+		id, err := x.reference(name, s)
+		if err != nil {
+			return nil, err
+		}
+		inner := newSyntaxScope(s)
+		formals := x.bindFormals(buildList(target, elems[1:], tail), inner)
+		body, err := x.expandBody(nl, nl.Rest().Rest(), inner, false)
+		if err != nil {
+			return nil, err
+		}
+		formalElems, formalTail, ok := listParts(formals)
+		if !ok {
+			// (f . rest)
+			formalTail = formals
+		}
+		signature := buildList(target, append([]Node{id}, formalElems...), formalTail)
+		return synthList(nl, append([]Node{nl.First(), signature}, body...)...), nil
+	}
+}
+
+// (define-values formals expr), whose formals expandBody has bound
+func (x *expander) expandDefineValues(nl *NodeList, s *syntaxScope) (Node, error) {
+	if nl.Len() != 3 {
+		return nil, NodeErrorf(nl, "Bad define-values expression - expected formals and an expression")
+	}
+	elems, tail, err := valuesFormals(nl, nl.Nth(1))
+	if err != nil {
+		return nil, err
+	}
+	for i, elem := range elems {
+		elems[i], err = x.reference(elem.(*NodeIdentifier), s)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if tail != nil {
+		tail, err = x.reference(tail.(*NodeIdentifier), s)
+		if err != nil {
+			return nil, err
+		}
+	}
+	expr, err := x.expand(nl.Nth(2), s)
+	if err != nil {
+		return nil, err
+	}
+	return synthList(nl, nl.First(), buildList(nl.Nth(1), elems, tail), expr), nil
+}
+
+// (let ((id init) ...) body ...), and letrec where the inits can see
+// the ids
+func (x *expander) expandLet(nl *NodeList, s *syntaxScope, rec bool) (Node, error) {
+	if nl.Len() < 3 {
+		return nil, NodeErrorf(nl, "Bad let expression - missing bindings or body")
+	}
+	bindings, ok := nl.Nth(1).(*NodeList)
+	if !ok {
+		return nil, NodeErrorf(nl, "Bad let expression - bindings must be a list")
+	}
+	inner := newSyntaxScope(s)
+	initScope := s
+	if rec {
+		initScope = inner
+	}
+
+	var ids []*NodeIdentifier
+	var inits []Node
+	err := bindings.Foreach(func(bindingNode Node) error {
+		binding, ok := bindingNode.(*NodeList)
+		if !ok || binding.Len() != 2 {
+			return NodeErrorf(nl, "Bad let expression - bindings must be pairs")
+		}
+		id, ok := binding.First().(*NodeIdentifier)
+		if !ok {
+			return NodeErrorf(nl, "Bad let expression - invalid identifier")
+		}
+		ids = append(ids, id)
+		inits = append(inits, binding.Nth(1))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	newBindings := make([]Node, len(ids))
+	boundIds := make([]Node, len(ids))
+	if rec {
+		for i, id := range ids {
+			boundIds[i] = x.bind(id, inner)
+		}
+	}
+	for i, init := range inits {
+		init, err = x.expand(init, initScope)
+		if err != nil {
+			return nil, err
+		}
+		if !rec {
+			boundIds[i] = x.bind(ids[i], inner)
+		}
+		newBindings[i] = synthList(bindings.Nth(i), boundIds[i], init)
+	}
+
+	body, err := x.expandBody(nl, nl.Rest().Rest(), inner, false)
+	if err != nil {
+		return nil, err
+	}
+	return synthList(nl, append([]Node{nl.First(), synthList(bindings, newBindings...)}, body...)...), nil
+}
+
+// expandQuasi expands the unquoted parts of a quasiquoted template
+func (x *expander) expandQuasi(n Node, s *syntaxScope, depth int) (Node, error) {
+	switch node := n.(type) {
+	case *NodeQuote:
+		inner := depth
+		if node.Quasi {
+			inner++
+		}
+		arg, err := x.expandQuasi(node.Arg, s, inner)
+		if err != nil {
+			return nil, err
+		}
+		nq := NewNodeQuote(arg, node.Quasi)
+		nq.SetSpan(node.Span())
+		return nq, nil
+	case *NodeUnQuote:
+		var arg Node
+		var err error
+		if depth == 1 {
+			arg, err = x.expand(node.Arg, s)
+		} else {
+			arg, err = x.expandQuasi(node.Arg, s, depth-1)
+		}
+		if err != nil {
+			return nil, err
+		}
+		nu := NewNodeUnQuote(arg)
+		nu.SetSpan(node.Span())
+		return nu, nil
+	case *NodeVector:
+		elems := make([]Node, node.Len())
+		for i, elem := range node.Elems() {
+			var err error
+			elems[i], err = x.expandQuasi(elem, s, depth)
+			if err != nil {
+				return nil, err
+			}
+		}
+		nv := NewNodeVector(elems)
+		nv.SetSpan(node.Span())
+		return nv, nil
+	}
+
+	elems, tail, ok := listParts(n)
+	if !ok || len(elems) == 0 {
+		return n, nil
+	}
+	if head, ok := elems[0].(*NodeIdentifier); ok && len(elems) == 2 && tail == nil {
+		switch head.String() {
+		case "quasiquote":
+			depth++
+		case "unquote":
+			if depth == 1 {
+				arg, err := x.expand(elems[1], s)
+				if err != nil {
+					return nil, err
+				}
+				return synthList(n, head, arg), nil
+			}
+			depth--
+		}
+	}
+	for i, elem := range elems {
+		var err error
+		elems[i], err = x.expandQuasi(elem, s, depth)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buildList(n, elems, tail), nil
+}
+
+// (define-syntax name spec)
+func (x *expander) defineSyntax(nl *NodeList, s *syntaxScope) error {
+	if nl.Len() != 3 {
+		return NodeErrorf(nl, "Bad define-syntax expression - wrong arity")
+	}
+	id, ok := nl.Nth(1).(*NodeIdentifier)
+	if !ok {
+		return NodeErrorf(nl, "Bad define-syntax expression - invalid identifier")
+	}
+	m, err := x.parseTransformer(nl.Nth(2), s)
+	if err != nil {
+		return err
+	}
+	x.bindMacro(id, m, s)
+	return nil
+}
+
+func (x *expander) bindMacro(id *NodeIdentifier, m macroTransformer, s *syntaxScope) {
+	s.vars[x.key(id)] = &syntaxBinding{base: id.String(), scope: s, macro: m}
+}
+
+// (let-syntax ((name spec) ...) body ...) => (let () body ...)
+func (x *expander) expandLetSyntax(nl *NodeList, s *syntaxScope, rec bool) (Node, error) {
+	if nl.Len() < 3 {
+		return nil, NodeErrorf(nl, "Bad %s expression - missing bindings or body", nl.First())
+	}
+	bindings, ok := nl.Nth(1).(*NodeList)
+	if !ok {
+		return nil, NodeErrorf(nl, "Bad %s expression - bindings must be a list", nl.First())
+	}
+	inner := newSyntaxScope(s)
+	specScope := s
+	if rec {
+		specScope = inner
+	}
+	err := bindings.Foreach(func(bindingNode Node) error {
+		binding, ok := bindingNode.(*NodeList)
+		if !ok || binding.Len() != 2 {
+			return NodeErrorf(nl, "Bad %s expression - bindings must be pairs", nl.First())
+		}
+		id, ok := binding.First().(*NodeIdentifier)
+		if !ok {
+			return NodeErrorf(nl, "Bad %s expression - invalid identifier", nl.First())
+		}
+		m, err := x.parseTransformer(binding.Nth(1), specScope)
+		if err != nil {
+			return err
+		}
+		x.bindMacro(id, m, inner)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := x.expandBody(nl, nl.Rest().Rest(), inner, false)
+	if err != nil {
+		return nil, err
+	}
+	let := synthIdentifier("let", nl.First())
+	return synthList(nl, append([]Node{let, synthList(nl)}, body...)...), nil
+}
+
+// listParts splits a proper or improper list into its elements and
+// tail, which is nil for a proper list
+func listParts(n Node) ([]Node, Node, bool) {
+	switch nl := n.(type) {
+	case *NodeList:
+		return listNodes(nl), nil, true
+	case *NodePair:
+		if nl.IsNil() {
+			return nil, nil, true
+		}
+	default:
+		return nil, nil, false
+	}
+
+	var elems []Node
+	var cur Node = n
+	for {
+		switch c := cur.(type) {
+		case *NodePair:
+			if c.IsNil() {
+				return elems, nil, true
+			}
+			elems = append(elems, c.Car)
+			cur = c.Cdr
+		case *NodeList:
+			return append(elems, listNodes(c)...), nil, true
+		default:
+			return elems, cur, true
+		}
+	}
+}
+
+// buildList is the inverse of listParts, spanned like source
+func buildList(source Node, elems []Node, tail Node) Node {
+	if tail == nil {
+		return synthList(source, elems...)
+	}
+	ret := tail
+	for i := len(elems) - 1; i >= 0; i-- {
+		ret = Cons(elems[i], ret)
+	}
+	setSpan(ret, source.Span())
+	return ret
+}
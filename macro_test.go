@@ -0,0 +1,71 @@
+package gol
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func expandString(t *testing.T, src string) string {
+	tree, err := NewLexerParser(NewLexer("<test>", strings.NewReader(src))).Parse()
+	if err != nil {
+		t.Fatalf("Error parsing [%s]: %s", src, err)
+	}
+	expanded, err := Expand(tree)
+	if err != nil {
+		t.Fatalf("Error expanding [%s]: %s", src, err)
+	}
+	return expanded.String()
+}
+
+func TestExpandRenaming(t *testing.T) {
+	gensyms := regexp.MustCompile(`%([a-z]+)[0-9]+`)
+	testCases := []struct {
+		src      string
+		expected string
+	}{
+		// Nothing to rename without macros
+		{"(let ((x 1)) x)", "(progn (let ((x 1)) x))"},
+		// The template's tmp is renamed, the program's isn't
+		{`(define-syntax swap!
+		    (syntax-rules () ((_ a b) (let ((tmp a)) (set! a b) (set! b tmp)))))
+		  (let ((tmp 1) (y 2)) (swap! tmp y))`,
+			"(progn (let ((tmp 1) (y 2)) (let ((%tmp tmp)) (set! tmp y) (set! y %tmp))))"},
+		// The program's x would capture the template's, so is renamed
+		{`(let ((x 1))
+		    (let-syntax ((get-x (syntax-rules () ((_) x))))
+		      (let ((x 2)) (get-x))))`,
+			"(progn (let ((x 1)) (let () (let ((%x 2)) x))))"},
+		// Only macro definitions leaves an unspecified value
+		{"(define-syntax ten (syntax-rules () ((_) 10)))", "(progn (%void))"},
+	}
+	for _, tc := range testCases {
+		got := gensyms.ReplaceAllString(expandString(t, tc.src), "%$1")
+		if got != tc.expected {
+			t.Errorf("Wrong expansion of [%s]:\n%s\n!=\n%s", tc.src, got, tc.expected)
+		}
+	}
+}
+
+func TestExpandErrors(t *testing.T) {
+	testCases := []struct {
+		src string
+		err string
+	}{
+		{"(define-syntax f (lambda (x) x))", "Bad macro transformer - expected syntax-rules"},
+		{"(define-syntax f (syntax-rules () ((_ x) (x ...)))) (f 1)", "No pattern variables before ellipsis in template"},
+		{"(define-syntax f (syntax-rules () ((_ x ...) (x)))) (f 1)", "Pattern variable [x] used without an ellipsis"},
+		{"(if #t (define-syntax f (syntax-rules () ((_) 1))) 2)", "define-syntax is only allowed at top level or in a body"},
+		{"(define-syntax f (er-macro-transformer (lambda (form rename compare) form)))", "Procedural macros need an evaluator"},
+	}
+	for _, tc := range testCases {
+		tree, err := NewLexerParser(NewLexer("<test>", strings.NewReader(tc.src))).Parse()
+		if err != nil {
+			t.Fatalf("Error parsing [%s]: %s", tc.src, err)
+		}
+		_, err = Expand(tree)
+		if err == nil || !strings.HasPrefix(err.Error(), tc.err) {
+			t.Errorf("Wrong error for [%s]: %v != %s", tc.src, err, tc.err)
+		}
+	}
+}
@@ -2,12 +2,33 @@ package gol
 
 import (
 	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
 
 	"github.com/jbert/gol/typ"
 )
 
 type NodeBase struct {
-	t typ.Type
+	t    typ.Type
+	span Span
+}
+
+// Span is zero for nodes which weren't built from source
+func (nb *NodeBase) Span() Span {
+	return nb.span
+}
+
+func (nb *NodeBase) SetSpan(s Span) {
+	nb.span = s
+}
+
+// setSpan is a no-op for nodes which can't record a span
+func setSpan(n Node, s Span) {
+	if spanner, ok := n.(interface{ SetSpan(Span) }); ok {
+		spanner.SetSpan(s)
+	}
 }
 
 func (nb *NodeBase) lazyInit() {
@@ -32,6 +53,7 @@ func (nb *NodeBase) NodeUnify(t typ.Type, env typ.Env) error {
 type Node interface {
 	String() string
 	Pos() Position
+	Span() Span
 	Type() typ.Type
 	NodeUnify(t typ.Type, env typ.Env) error
 }
@@ -42,25 +64,55 @@ type nodeAtom struct {
 }
 
 func (na nodeAtom) Pos() Position {
+	if !na.span.IsZero() {
+		return na.span.Start
+	}
 	return na.tok.Pos
 }
 
+// NodeInt is an exact integer. Values which fit an int64 are kept
+// there; big is only used for values which don't.
 type NodeInt struct {
 	nodeAtom
 	value int64
+	big   *big.Int
 }
 
 func NewNodeInt(n int64) Node {
 	return &NodeInt{value: n}
 }
 
+// NewNodeBigInt normalises back to an int64 if the value fits
+func NewNodeBigInt(b *big.Int) Node {
+	if b.IsInt64() {
+		return NewNodeInt(b.Int64())
+	}
+	return &NodeInt{big: b}
+}
+
 func (ni *NodeInt) String() string {
+	if ni.big != nil {
+		return ni.big.String()
+	}
 	return fmt.Sprintf("%d", ni.value)
 }
+
+// Value is only meaningful if !IsBig()
 func (ni *NodeInt) Value() int64 {
 	return ni.value
 }
 
+func (ni *NodeInt) IsBig() bool {
+	return ni.big != nil
+}
+
+func (ni *NodeInt) BigValue() *big.Int {
+	if ni.big != nil {
+		return ni.big
+	}
+	return big.NewInt(ni.value)
+}
+
 func (ni NodeInt) Type() typ.Type {
 	return typ.Int
 }
@@ -69,11 +121,80 @@ func (ni NodeInt) NodeUnify(t typ.Type, env typ.Env) error {
 	return t.Unify(typ.Int)
 }
 
+type NodeReal struct {
+	nodeAtom
+	value float64
+}
+
+func NewNodeReal(f float64) Node {
+	return &NodeReal{value: f}
+}
+
+// Reals always print with a decimal point, to distinguish them from
+// exact integers
+func (nr *NodeReal) String() string {
+	switch {
+	case math.IsInf(nr.value, 1):
+		return "+inf.0"
+	case math.IsInf(nr.value, -1):
+		return "-inf.0"
+	case math.IsNaN(nr.value):
+		return "+nan.0"
+	}
+	s := strconv.FormatFloat(nr.value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+func (nr *NodeReal) Value() float64 {
+	return nr.value
+}
+
+func (nr NodeReal) Type() typ.Type {
+	return typ.Real
+}
+
+func (nr NodeReal) NodeUnify(t typ.Type, env typ.Env) error {
+	return t.Unify(typ.Real)
+}
+
+type NodeRational struct {
+	nodeAtom
+	value *big.Rat
+}
+
+// NewNodeExact gives the simplest node for an exact number, which is
+// an int if there is no fractional part
+func NewNodeExact(r *big.Rat) Node {
+	if r.IsInt() {
+		return NewNodeBigInt(r.Num())
+	}
+	return &NodeRational{value: r}
+}
+
+func (nr *NodeRational) String() string {
+	return nr.value.RatString()
+}
+
+func (nr *NodeRational) Value() *big.Rat {
+	return nr.value
+}
+
+func (nr NodeRational) Type() typ.Type {
+	return typ.Rational
+}
+
+func (nr NodeRational) NodeUnify(t typ.Type, env typ.Env) error {
+	return t.Unify(typ.Rational)
+}
+
 type NodeIdentifier struct {
 	nodeAtom
 }
 
-func makeIdentifier(s string) *NodeIdentifier {
+func NewNodeIdentifier(s string) *NodeIdentifier {
 	return &NodeIdentifier{nodeAtom{tok: Token{
 		Type:  tokIdentifier,
 		Value: s,
@@ -108,6 +229,11 @@ func (ns NodeSymbol) NodeUnify(t typ.Type, env typ.Env) error {
 
 type NodeString struct {
 	nodeAtom
+	value string
+}
+
+func NewNodeString(s string) *NodeString {
+	return &NodeString{value: s}
 }
 
 func (ns NodeString) Type() typ.Type {
@@ -130,6 +256,13 @@ func (nb NodeBool) NodeUnify(t typ.Type, env typ.Env) error {
 	return t.Unify(typ.Bool)
 }
 
+func NewNodeBool(b bool) *NodeBool {
+	if b {
+		return NODE_TRUE
+	}
+	return NODE_FALSE
+}
+
 var NODE_FALSE = &NodeBool{
 	nodeAtom{
 		tok: Token{
@@ -152,33 +285,38 @@ func (nb *NodeBool) IsTrue() bool {
 	return nb.String() == "#t"
 }
 
+type NodeChar struct {
+	nodeAtom
+	value rune
+}
+
+func NewNodeChar(r rune) *NodeChar {
+	return &NodeChar{value: r}
+}
+
+func (nc *NodeChar) String() string {
+	return string(nc.value)
+}
+
+func (nc *NodeChar) Value() rune {
+	return nc.value
+}
+
+func (nc NodeChar) Type() typ.Type {
+	return typ.Char
+}
+
+func (nc NodeChar) NodeUnify(t typ.Type, env typ.Env) error {
+	return t.Unify(typ.Char)
+}
+
 func (ns *NodeString) String() string {
-	// Unescape
-	value := make([]rune, 0, len(ns.tok.Value))
-	escaped := false
-RUNE:
-	for _, r := range ns.tok.Value {
-		if r == '\\' {
-			if !escaped {
-				escaped = true
-				continue RUNE
-			} else {
-				escaped = false
-				// fall through
-			}
-		}
-		if escaped {
-			switch r {
-			case 'n':
-				value = append(value, '\n')
-			default:
-				value = append(value, r)
-			}
-		} else {
-			value = append(value, r)
-		}
-	}
-	return string(value)
+	return ns.value
+}
+
+// Value is the string with any escapes already processed
+func (ns *NodeString) Value() string {
+	return ns.value
 }
 
 func (na nodeAtom) String() string {
@@ -206,12 +344,14 @@ func NewNodePair(car, cdr Node) *NodePair {
 func (np *NodePair) String() string {
 	if np.IsNil() {
 		return "()"
-	} else {
-		return fmt.Sprintf("(%v . %v)", np.Car, np.Cdr)
 	}
+	return pairChainString(np)
 }
 
 func (np *NodePair) Pos() Position {
+	if !np.span.IsZero() || np.IsNil() {
+		return np.span.Start
+	}
 	return np.Car.Pos()
 }
 
@@ -223,6 +363,47 @@ func (np *NodePair) IsNil() bool {
 	return np.Car == nil && np.Cdr == nil
 }
 
+// ----------------------------------------
+
+// NodeVector is a fixed-length, mutable array of nodes
+type NodeVector struct {
+	NodeBase
+	elems []Node
+}
+
+func NewNodeVector(elems []Node) *NodeVector {
+	return &NodeVector{elems: elems}
+}
+
+func (nv *NodeVector) String() string {
+	strs := make([]string, len(nv.elems))
+	for i, n := range nv.elems {
+		strs[i] = n.String()
+	}
+	return "#(" + strings.Join(strs, " ") + ")"
+}
+
+func (nv *NodeVector) Pos() Position {
+	return nv.span.Start
+}
+
+func (nv *NodeVector) Len() int {
+	return len(nv.elems)
+}
+
+func (nv *NodeVector) Ref(i int) Node {
+	return nv.elems[i]
+}
+
+func (nv *NodeVector) Set(i int, n Node) {
+	nv.elems[i] = n
+}
+
+// Elems returns the underlying slice, not a copy
+func (nv *NodeVector) Elems() []Node {
+	return nv.elems
+}
+
 type NodeList struct {
 	NodeBase
 	children *NodePair
@@ -245,6 +426,9 @@ func NewNodeListType(t typ.Type) *NodeList {
 //}
 
 func (nl *NodeList) Pos() Position {
+	if !nl.span.IsZero() {
+		return nl.span.Start
+	}
 	if nl.Len() == 0 {
 		return Position{File: "<empty list>"}
 	} else {
@@ -258,6 +442,8 @@ func (nl *NodeList) Pos() Position {
 type NodeLambda struct {
 	*NodeList
 	Args *NodeList
+	// Rest, if set, is bound to a list of any args after Args
+	Rest *NodeIdentifier
 	Body Node
 }
 
@@ -327,8 +513,17 @@ type Frame map[string]Node
 
 type NodeLet struct {
 	*NodeList
-	Bindings Frame
+	// Bindings are in source order
+	Bindings []LetBinding
 	Body     Node
+	// Rec is set for letrec and letrec*, where the bindings can see each
+	// other
+	Rec bool
+}
+
+type LetBinding struct {
+	Id    *NodeIdentifier
+	Value Node
 }
 
 // ----------------------------------------
@@ -343,12 +538,26 @@ func (ne *NodeError) Pos() Position {
 	return ne.source.Pos()
 }
 
+func (ne *NodeError) Span() Span {
+	return ne.source.Span()
+}
+
 func (ne *NodeError) String() string {
 	return ne.Error()
 }
+
+// Message is the error without its position
+func (ne *NodeError) Message() string {
+	return ne.msg
+}
 func (ne *NodeError) Error() string {
-	pos := ne.source.Pos()
-	return fmt.Sprintf("%s: %s line %d:%d [%s]", ne.msg, pos.File, pos.Line, pos.Column, ne.source)
+	span := ne.source.Span()
+	if span.IsZero() {
+		pos := ne.source.Pos()
+		return fmt.Sprintf("%s: %s line %d:%d [%s]", ne.msg, pos.File, pos.Line, pos.Column, ne.source)
+	}
+	start, end := span.Start, span.End
+	return fmt.Sprintf("%s: %s line %d:%d-%d:%d [%s]", ne.msg, start.File, start.Line, start.Column, end.Line, end.Column, ne.source)
 }
 
 func NodeErrorf(n Node, f string, args ...interface{}) *NodeError {
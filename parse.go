@@ -3,7 +3,13 @@ package gol
 import (
 	"errors"
 	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
 var ErrNoMoreTokens = errors.New("No More Tokens")
@@ -21,29 +27,57 @@ func posErrorf(pos Position, f string, args ...interface{}) PosError {
 }
 
 type Parser struct {
-	tokens chan Token
-	peek   *Token
+	next func() (Token, error)
+	peek *Token
 }
 
+// NewParser reads tokens from a channel, as fed by Lexer.Run
 func NewParser(tokens chan Token) *Parser {
 	return &Parser{
-		tokens: tokens,
+		next: func() (Token, error) {
+			tok, ok := <-tokens
+			if !ok {
+				return TokBug, ErrNoMoreTokens
+			}
+			return tok, nil
+		},
 	}
 }
 
+// NewLexerParser pulls tokens from the lexer as it needs them, so no
+// goroutine is involved
+func NewLexerParser(l *Lexer) *Parser {
+	return &Parser{
+		next: func() (Token, error) {
+			tok, err := l.Next()
+			if err == io.EOF {
+				return TokBug, ErrNoMoreTokens
+			}
+			return tok, err
+		},
+	}
+}
+
+// ParseReader lexes, parses, expands macros in and transforms a whole
+// program
+func ParseReader(srcName string, r io.Reader) (Node, error) {
+	return ParseReaderWithEvaluator(srcName, r, nil)
+}
+
+func ParseFile(fname string) (Node, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseReader(fname, f)
+}
+
 func (p *Parser) Parse() (Node, error) {
 	// We evaluate a program as an implicit progn over the whole text
 	// TODO - bad idea, instead parse sexp by sexp with 'ParseOne' and a loop in 'ParseAll'
 	progn := NewNodeList()
-	progn = progn.Cons(&NodeIdentifier{
-		nodeAtom{
-			tok: Token{
-				tokIdentifier,
-				"progn",
-				Position{},
-			},
-		},
-	})
+	progn = progn.Cons(NewNodeIdentifier("progn"))
 	//	fmt.Printf("progn is: %s %T %d\n", progn, progn, progn.Len())
 	for {
 		tree, err := p.parseSexp()
@@ -56,15 +90,16 @@ func (p *Parser) Parse() (Node, error) {
 		progn = progn.Cons(tree)
 	}
 	progn = progn.ReverseCopy()
+	setProgramSpan(progn)
 	//	fmt.Printf("progn is: %s %T %d\n", progn, progn, progn.Len())
 	return progn, nil
 }
 
 func (p *Parser) peekToken() (Token, error) {
 	if p.peek == nil {
-		tok, ok := <-p.tokens
-		if !ok {
-			return TokBug, ErrNoMoreTokens
+		tok, err := p.next()
+		if err != nil {
+			return TokBug, err
 		}
 		p.peek = &tok
 	}
@@ -87,7 +122,32 @@ func (p *Parser) Error(tok Token, reason string) error {
 	return posErrorf(tok.Pos, "%s", reason)
 }
 
+// A datum comment '#;' discards the datum which follows it
+func (p *Parser) skipDatumComments() error {
+	for {
+		tok, err := p.peekToken()
+		if err != nil {
+			return err
+		}
+		if tok.Type != tokDatumComment {
+			return nil
+		}
+		p.stepToken()
+		_, err = p.parseSexp()
+		if err == ErrNoMoreTokens {
+			return p.Error(tok, "Datum comment with no datum")
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
 func (p *Parser) parseSexp() (Node, error) {
+	err := p.skipDatumComments()
+	if err != nil {
+		return nil, err
+	}
 	tok, err := p.peekToken()
 	if err != nil {
 		return nil, err
@@ -99,28 +159,122 @@ func (p *Parser) parseSexp() (Node, error) {
 		if err != nil {
 			return nil, err
 		}
-		return NewNodeQuote(arg, false), nil
+		return withSpan(NewNodeQuote(arg, false), tok, arg), nil
 	case tokBackQuote:
 		p.stepToken()
 		arg, err := p.parseSexp()
 		if err != nil {
 			return nil, err
 		}
-		return NewNodeQuote(arg, true), nil
+		return withSpan(NewNodeQuote(arg, true), tok, arg), nil
 	case tokComma:
 		p.stepToken()
 		arg, err := p.parseSexp()
 		if err != nil {
 			return nil, err
 		}
-		return NewNodeUnQuote(arg), nil
+		return withSpan(NewNodeUnQuote(arg), tok, arg), nil
 	case tokLParen:
 		return p.parseList()
+	case tokVectorStart:
+		return p.parseVector()
 	default:
-		return p.parseAtom()
+		node, err := p.parseAtom()
+		if err != nil {
+			return nil, err
+		}
+		setSpan(node, tok.Span())
+		return node, nil
+	}
+}
+
+// withSpan sets n's span to run from the start token to the end of last
+func withSpan(n Node, start Token, last Node) Node {
+	setSpan(n, Span{Start: start.Pos, End: last.Span().End})
+	return n
+}
+
+// setProgramSpan spans a top-level progn over all its forms
+func setProgramSpan(progn *NodeList) {
+	if progn.Len() < 2 {
+		return
+	}
+	first := progn.Nth(1).Span()
+	last := progn.Nth(progn.Len() - 1).Span()
+	progn.SetSpan(Span{Start: first.Start, End: last.End})
+}
+
+func (p *Parser) parseVector() (Node, error) {
+	start, err := p.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	p.stepToken()
+	var elems []Node
+	for {
+		err := p.skipDatumComments()
+		if err != nil {
+			return nil, p.unclosed(start, err)
+		}
+		t, err := p.peekToken()
+		if err != nil {
+			return nil, p.unclosed(start, err)
+		}
+		if t.Type == tokRParen {
+			p.stepToken()
+			nv := NewNodeVector(elems)
+			nv.SetSpan(Span{Start: start.Pos, End: t.End})
+			return nv, nil
+		}
+		node, err := p.parseSexp()
+		if err != nil {
+			return nil, p.unclosed(start, err)
+		}
+		elems = append(elems, node)
 	}
 }
 
+// unclosed is the error for running out of tokens inside the list or
+// vector opened by start
+func (p *Parser) unclosed(start Token, err error) error {
+	if err == ErrNoMoreTokens {
+		return p.Error(start, "Unclosed list")
+	}
+	return err
+}
+
+// parseDottedTail reads the single datum after a dot and the closing
+// paren, consing the list elements onto it.
+func (p *Parser) parseDottedTail(start Token, dot Token, nodeList *NodeList) (Node, error) {
+	tail, err := p.parseSexp()
+	if err == ErrNoMoreTokens {
+		return nil, p.Error(dot, "No datum after dot")
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = p.skipDatumComments()
+	if err != nil {
+		return nil, p.unclosed(start, err)
+	}
+	t, err := p.peekToken()
+	if err != nil {
+		return nil, p.unclosed(start, err)
+	}
+	if t.Type != tokRParen {
+		return nil, p.Error(t, "More than one datum after dot")
+	}
+	p.stepToken()
+
+	ret := tail
+	nodeList.ReverseCopy().Foreach(func(n Node) error {
+		ret = Cons(n, ret)
+		return nil
+	})
+	setSpan(ret, Span{Start: start.Pos, End: t.End})
+	return ret, nil
+}
+
 func (p *Parser) parseAtom() (Node, error) {
 	tok, err := p.peekToken()
 	if err != nil {
@@ -133,32 +287,186 @@ func (p *Parser) parseAtom() (Node, error) {
 	case tokRParen:
 		return nil, p.Error(tok, "Found R Paren, expected atom")
 	case tokIdentifier:
+		if f, ok := specialReals[tok.Value]; ok {
+			return NewNodeReal(f), nil
+		}
+		return &NodeIdentifier{nodeAtom{tok: tok}}, nil
+	case tokBarIdentifier:
+		name, err := parseString(tok)
+		if err != nil {
+			return nil, err
+		}
+		tok.Value = name
 		return &NodeIdentifier{nodeAtom{tok: tok}}, nil
 	case tokSymbol:
 		return &NodeSymbol{nodeAtom{tok: tok}}, nil
 	case tokString:
-		return &NodeString{nodeAtom{tok: tok}}, nil
+		value, err := parseString(tok)
+		if err != nil {
+			return nil, err
+		}
+		return &NodeString{nodeAtom: nodeAtom{tok: tok}, value: value}, nil
 	case tokBool:
 		if tok.Value != "#t" && tok.Value != "#f" {
 			return nil, posErrorf(tok.Pos, "Bad boolean value [%s]", tok.Value)
 		}
 		return &NodeBool{nodeAtom{tok: tok}}, nil
-	case tokInt:
-		// Special case '+' and '-'
-		if tok.Value == "+" || tok.Value == "-" {
-			return &NodeIdentifier{nodeAtom{tok: tok}}, nil
+	case tokChar:
+		r, err := parseChar(tok)
+		if err != nil {
+			return nil, err
 		}
+		return &NodeChar{nodeAtom: nodeAtom{tok: tok}, value: r}, nil
+	case tokNumber:
+		return parseNumber(tok)
+	default:
+		panic("Unknown atom type")
+	}
+}
+
+var stringEscapes = map[rune]rune{
+	'a':  '\a',
+	'b':  '\b',
+	't':  '\t',
+	'n':  '\n',
+	'r':  '\r',
+	'"':  '"',
+	'\\': '\\',
+	'|':  '|',
+}
 
-		v, err := strconv.ParseInt(tok.Value, 10, 64)
+// parseString processes the R7RS escapes in a string literal, including
+// \xHH; and line continuations
+func parseString(tok Token) (string, error) {
+	in := []rune(tok.Value)
+	value := make([]rune, 0, len(in))
+	for i := 0; i < len(in); i++ {
+		if in[i] != '\\' {
+			value = append(value, in[i])
+			continue
+		}
+		i++
+		if i >= len(in) {
+			return "", posErrorf(tok.Pos, "Unterminated escape in string")
+		}
+		if r, ok := stringEscapes[in[i]]; ok {
+			value = append(value, r)
+			continue
+		}
+		switch {
+		case in[i] == 'x':
+			end := i + 1
+			for end < len(in) && in[end] != ';' {
+				end++
+			}
+			if end >= len(in) {
+				return "", posErrorf(tok.Pos, "Missing ';' after hex escape in string")
+			}
+			n, err := strconv.ParseUint(string(in[i+1:end]), 16, 32)
+			if err != nil || !utf8.ValidRune(rune(n)) {
+				return "", posErrorf(tok.Pos, "Bad hex escape in string [%s]", string(in[i-1:end+1]))
+			}
+			value = append(value, rune(n))
+			i = end
+		case isIntralineSpace(in[i]) || in[i] == '\n':
+			// Line continuation: \<space>*<newline><space>*
+			for i < len(in) && isIntralineSpace(in[i]) {
+				i++
+			}
+			if i >= len(in) || in[i] != '\n' {
+				return "", posErrorf(tok.Pos, "Bad line continuation in string")
+			}
+			i++
+			for i < len(in) && isIntralineSpace(in[i]) {
+				i++
+			}
+			i--
+		default:
+			return "", posErrorf(tok.Pos, "Unknown escape in string [\\%c]", in[i])
+		}
+	}
+	return string(value), nil
+}
+
+func isIntralineSpace(r rune) bool {
+	return r == ' ' || r == '\t'
+}
+
+// ParseNumber parses s as a numeric literal
+func ParseNumber(s string) (Node, error) {
+	return parseNumber(Token{Type: tokNumber, Value: s})
+}
+
+// parseNumber handles integers (12), rationals (3/4) and reals (1.5, 1e3)
+func parseNumber(tok Token) (Node, error) {
+	s := strings.TrimPrefix(tok.Value, "+")
+	switch {
+	case strings.Contains(s, "/"):
+		r, ok := new(big.Rat).SetString(s)
+		if !ok {
+			return nil, posErrorf(tok.Pos, "Can't parse [%s] as rational", tok.Value)
+		}
+		return NewNodeExact(r), nil
+	case strings.ContainsAny(s, ".eE"):
+		f, err := strconv.ParseFloat(s, 64)
 		if err != nil {
-			return nil, posErrorf(tok.Pos, "Can't parse [%s] as integer: %s", tok.Value, err)
+			return nil, posErrorf(tok.Pos, "Can't parse [%s] as real: %s", tok.Value, err)
 		}
-		return NewNodeInt(v), nil
+		return NewNodeReal(f), nil
 	default:
-		panic("Unknown atom type")
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err == nil {
+			return NewNodeInt(v), nil
+		}
+		b, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return nil, posErrorf(tok.Pos, "Can't parse [%s] as integer: %s", tok.Value, err)
+		}
+		return NewNodeBigInt(b), nil
 	}
 }
 
+// specialReals lex as identifiers, since they start with a sign and a
+// letter
+var specialReals = map[string]float64{
+	"+inf.0": math.Inf(1),
+	"-inf.0": math.Inf(-1),
+	"+nan.0": math.NaN(),
+	"-nan.0": math.NaN(),
+}
+
+var charNames = map[string]rune{
+	"alarm":     '\a',
+	"backspace": '\b',
+	"delete":    0x7f,
+	"escape":    0x1b,
+	"newline":   '\n',
+	"null":      0,
+	"nul":       0,
+	"return":    '\r',
+	"space":     ' ',
+	"tab":       '\t',
+}
+
+// parseChar handles #\a, #\space and #\x41 style literals
+func parseChar(tok Token) (rune, error) {
+	name := strings.TrimPrefix(tok.Value, "#\\")
+	if utf8.RuneCountInString(name) == 1 {
+		r, _ := utf8.DecodeRuneInString(name)
+		return r, nil
+	}
+	if r, ok := charNames[name]; ok {
+		return r, nil
+	}
+	if name != "" && (name[0] == 'x' || name[0] == 'X') {
+		n, err := strconv.ParseUint(name[1:], 16, 32)
+		if err == nil && utf8.ValidRune(rune(n)) {
+			return rune(n), nil
+		}
+	}
+	return 0, posErrorf(tok.Pos, "Bad character literal [%s]", tok.Value)
+}
+
 func (p *Parser) parseList() (Node, error) {
 	tok, err := p.peekToken()
 	if err != nil {
@@ -168,19 +476,32 @@ func (p *Parser) parseList() (Node, error) {
 		return nil, p.Error(tok, "Parser logic error - missing L paren at start of list")
 	}
 	p.stepToken()
+	start := tok
 	nodeList := NewNodeList()
 	for {
+		err := p.skipDatumComments()
+		if err != nil {
+			return nil, p.unclosed(start, err)
+		}
 		t, err := p.peekToken()
 		if err != nil {
-			return nil, err
+			return nil, p.unclosed(start, err)
 		}
 		if t.Type == tokRParen {
 			p.stepToken()
+			nodeList.SetSpan(Span{Start: start.Pos, End: t.End})
 			return nodeList, nil
 		}
+		if t.Type == tokIdentifier && t.Value == "." {
+			if nodeList.Len() == 0 {
+				return nil, p.Error(t, "Dot at start of list")
+			}
+			p.stepToken()
+			return p.parseDottedTail(start, t, nodeList)
+		}
 		node, err := p.parseSexp()
 		if err != nil {
-			return nil, err
+			return nil, p.unclosed(start, err)
 		}
 		nodeList = nodeList.Append(node)
 	}
@@ -0,0 +1,106 @@
+package gol
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseReader(t *testing.T) {
+	tree, err := ParseReader("<test>", strings.NewReader("(define x 1) (+ x 2)"))
+	if err != nil {
+		t.Fatalf("Error parsing: %s", err)
+	}
+	if _, ok := tree.(*NodeProgn); !ok {
+		t.Fatalf("Parse tree isn't a transformed progn: %T", tree)
+	}
+
+	_, err = ParseReader("<test>", strings.NewReader("(+ 1 #| 2"))
+	if err == nil || !strings.HasPrefix(err.Error(), "Unterminated block comment") {
+		t.Fatalf("Wrong error for lexing failure: %v", err)
+	}
+
+	unclosed := []struct {
+		src string
+		msg string
+	}{
+		{"(define x 1) (display x", "Unclosed list: <test> line 1:14"},
+		{"(list 1 #(2 3", "Unclosed list: <test> line 1:9"},
+		{"(a . b", "Unclosed list: <test> line 1:1"},
+		{`(display "abc`, "Unterminated string: <test> line 1:10"},
+	}
+	for _, u := range unclosed {
+		_, err = ParseReader("<test>", strings.NewReader(u.src))
+		if err == nil || !strings.HasPrefix(err.Error(), u.msg) {
+			t.Errorf("Wrong error for [%s]: %v", u.src, err)
+		}
+	}
+
+	_, err = ParseReader("<test>", strings.NewReader("(display #\\"))
+	if err == nil || !strings.HasPrefix(err.Error(), "Bad character literal") {
+		t.Fatalf("Wrong error for character literal at end of input: %v", err)
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	_, err := ParseFile("chibi-tests/basic/test00-fact-3.scm")
+	if err != nil {
+		t.Fatalf("Error parsing file: %s", err)
+	}
+	_, err = ParseFile("no-such-file.scm")
+	if err == nil {
+		t.Fatalf("No error for missing file")
+	}
+}
+
+// Parse errors used to leave the lexer goroutine blocked forever
+func TestParseReaderNoLeak(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		_, err := ParseReader("<test>", strings.NewReader("(1 . 2 3) (more tokens) (to come)"))
+		if err == nil {
+			t.Fatalf("No error for bad dotted list")
+		}
+	}
+	// Give anything we did leak a chance to show up
+	time.Sleep(10 * time.Millisecond)
+	after := runtime.NumGoroutine()
+	if after > before {
+		t.Fatalf("Leaked goroutines: %d before, %d after", before, after)
+	}
+}
+
+func TestParseSpans(t *testing.T) {
+	src := "(define x\n  '(1 \"two\"))\n#(3 4)"
+	tree, err := ParseReader("<test>", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("Error parsing: %s", err)
+	}
+	progn, ok := tree.(*NodeProgn)
+	if !ok {
+		t.Fatalf("Parse tree isn't a progn: %T", tree)
+	}
+	checkSpan := func(what string, n Node, sl, sc, so, el, ec, eo int) {
+		s := n.Span()
+		if s.Start.Line != sl || s.Start.Column != sc || s.Start.Offset != so ||
+			s.End.Line != el || s.End.Column != ec || s.End.Offset != eo {
+			t.Errorf("Wrong span for %s [%s]: %d:%d@%d-%d:%d@%d", what, n,
+				s.Start.Line, s.Start.Column, s.Start.Offset,
+				s.End.Line, s.End.Column, s.End.Offset)
+		}
+		if s.Start.File != "<test>" {
+			t.Errorf("Wrong file name for %s: %s", what, s.Start.File)
+		}
+	}
+	checkSpan("program", progn, 1, 1, 0, 3, 7, 30)
+
+	def := progn.Nth(1)
+	checkSpan("define", def, 1, 1, 0, 2, 14, 23)
+	quoted := def.(*NodeDefine).Value
+	checkSpan("quote", quoted, 2, 3, 12, 2, 13, 22)
+	list := quoted.(*NodeQuote).Arg
+	checkSpan("quoted list", list, 2, 4, 13, 2, 13, 22)
+	checkSpan("int", list.(*NodeList).First(), 2, 5, 14, 2, 6, 15)
+	checkSpan("vector", progn.Nth(2), 3, 1, 24, 3, 7, 30)
+}
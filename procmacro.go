@@ -0,0 +1,262 @@
+package gol
+
+import (
+	"fmt"
+	"io"
+)
+
+// MacroEvaluator runs the procedural macro transformers
+// (er-macro-transformer and friends) at expansion time. The gol
+// package can't evaluate code itself, so a backend supplies this.
+type MacroEvaluator interface {
+	// Eval evaluates an expanded and transformed expression
+	Eval(n Node) (Node, error)
+	// Call applies a procedure value to args
+	Call(proc Node, args []Node) (Node, error)
+	// Procedure wraps f as a procedure value
+	Procedure(name string, f func(args []Node) (Node, error)) Node
+}
+
+// ParseReaderWithEvaluator is ParseReader, using ev to run procedural
+// macros
+func ParseReaderWithEvaluator(srcName string, r io.Reader, ev MacroEvaluator) (Node, error) {
+	p := NewLexerParser(NewLexer(srcName, r))
+	nodeTree, err := p.Parse()
+	if err != nil {
+		return nil, err
+	}
+	nodeTree, err = ExpandWith(nodeTree, ev)
+	if err != nil {
+		return nil, err
+	}
+	return Transform(nodeTree)
+}
+
+// TransformDatum expands macros in and transforms a datum, such as one
+// from ReadDatum or a quoted list, as a program of one expression. The
+// datum itself is left unchanged.
+func TransformDatum(datum Node, ev MacroEvaluator) (Node, error) {
+	progn := NewNodeList().Cons(datum).Cons(NewNodeIdentifier("progn"))
+	nodeTree, err := ExpandWith(progn, ev)
+	if err != nil {
+		return nil, err
+	}
+	return Transform(nodeTree)
+}
+
+// NodeSyntaxEnv is the environment passed to sc- and rsc-macro
+// transformers, for use with make-syntactic-closure
+type NodeSyntaxEnv struct {
+	NodeBase
+	// use is set for the environment of the macro use, rather than its
+	// definition
+	use bool
+}
+
+func (ne *NodeSyntaxEnv) String() string {
+	if ne.use {
+		return "#<usage environment>"
+	}
+	return "#<macro environment>"
+}
+
+func (ne *NodeSyntaxEnv) Pos() Position {
+	return ne.span.Start
+}
+
+// NodeSyntacticClosure is a form whose identifiers mean what they do in
+// env, apart from the free names which mean what they do where the
+// closure ends up
+type NodeSyntacticClosure struct {
+	NodeBase
+	env  *NodeSyntaxEnv
+	free map[string]bool
+	form Node
+}
+
+func NewNodeSyntacticClosure(env *NodeSyntaxEnv, free []string, form Node) *NodeSyntacticClosure {
+	sc := &NodeSyntacticClosure{env: env, free: make(map[string]bool), form: form}
+	for _, name := range free {
+		sc.free[name] = true
+	}
+	return sc
+}
+
+func (sc *NodeSyntacticClosure) String() string {
+	return fmt.Sprintf("#<syntactic-closure %s>", sc.form)
+}
+
+func (sc *NodeSyntacticClosure) Pos() Position {
+	return sc.form.Pos()
+}
+
+// procMacro is a transformer procedure, called with the whole form
+type procMacro struct {
+	kind string
+	proc Node
+	env  *syntaxScope
+}
+
+// parseTransformer handles the transformer spec of a macro definition
+func (x *expander) parseTransformer(spec Node, s *syntaxScope) (macroTransformer, error) {
+	kind := x.coreForm(spec, s)
+	switch kind {
+	case "syntax-rules":
+		return x.parseSyntaxRules(spec, s)
+	case "er-macro-transformer", "sc-macro-transformer", "rsc-macro-transformer":
+	default:
+		return nil, NodeErrorf(spec, "Bad macro transformer - expected syntax-rules or a macro transformer")
+	}
+
+	nl := spec.(*NodeList)
+	if nl.Len() != 2 {
+		return nil, NodeErrorf(spec, "Bad %s expression - exactly one procedure required", kind)
+	}
+	if x.ev == nil {
+		return nil, NodeErrorf(spec, "Procedural macros need an evaluator")
+	}
+	expanded, err := x.expand(nl.Nth(1), s)
+	if err != nil {
+		return nil, err
+	}
+	code, err := Transform(expanded)
+	if err != nil {
+		return nil, err
+	}
+	proc, err := x.ev.Eval(code)
+	if err != nil {
+		return nil, err
+	}
+	return &procMacro{kind: kind, proc: proc, env: s}, nil
+}
+
+func (m *procMacro) expand(x *expander, form *NodeList, s *syntaxScope) (Node, error) {
+	renames := make(map[string]*syntaxAlias)
+	// rename makes an alias meaning what id does where the macro was
+	// defined. All renames of one name in an expansion are the same.
+	rename := func(id *NodeIdentifier) *NodeIdentifier {
+		alias, ok := renames[id.String()]
+		if !ok {
+			alias = &syntaxAlias{orig: id, env: m.env}
+			renames[id.String()] = alias
+		}
+		newId := NewNodeIdentifier(id.String())
+		newId.SetSpan(form.Span())
+		x.aliases[newId] = alias
+		return newId
+	}
+	keep := func(id *NodeIdentifier) Node { return id }
+	close := func(id *NodeIdentifier) Node { return rename(id) }
+
+	var args []Node
+	var interp func(id *NodeIdentifier) Node
+	switch m.kind {
+	case "er-macro-transformer":
+		renameProc := x.ev.Procedure("rename", func(args []Node) (Node, error) {
+			if len(args) != 1 {
+				return nil, NodeErrorf(form, "Arity-error: rename expects 1 arg")
+			}
+			id, ok := args[0].(*NodeIdentifier)
+			if !ok {
+				return nil, NodeErrorf(form, "Non-identifier passed to rename: %s", args[0])
+			}
+			return rename(id), nil
+		})
+		compareProc := x.ev.Procedure("compare", func(args []Node) (Node, error) {
+			if len(args) != 2 {
+				return nil, NodeErrorf(form, "Arity-error: compare expects 2 args")
+			}
+			a, aOk := args[0].(*NodeIdentifier)
+			b, bOk := args[1].(*NodeIdentifier)
+			return NewNodeBool(aOk && bOk && x.sameBinding(a, s, b, s)), nil
+		})
+		args = []Node{form, renameProc, compareProc}
+		interp = keep
+	case "sc-macro-transformer":
+		// The output means what it does where the macro was defined
+		args = []Node{form, &NodeSyntaxEnv{use: true}}
+		interp = close
+	case "rsc-macro-transformer":
+		args = []Node{form, &NodeSyntaxEnv{use: false}}
+		interp = keep
+	}
+
+	output, err := x.ev.Call(m.proc, args)
+	if err != nil {
+		return nil, err
+	}
+	return x.syntaxFromDatum(output, form, interp, keep, close)
+}
+
+// syntaxFromDatum turns transformer output back into code. interp says
+// what a bare identifier means, and syntactic closures change it for
+// their contents.
+func (x *expander) syntaxFromDatum(n Node, form Node, interp, keep, close func(id *NodeIdentifier) Node) (Node, error) {
+	switch node := n.(type) {
+	case *NodeIdentifier:
+		return interp(node), nil
+	case *NodeBool:
+		// Don't let a span be set on the shared #t and #f
+		return synthBool(node.IsTrue(), form), nil
+	case *NodeSyntacticClosure:
+		inner := keep
+		if !node.env.use {
+			inner = close
+		}
+		closed := func(id *NodeIdentifier) Node {
+			if node.free[id.String()] {
+				return interp(id)
+			}
+			return inner(id)
+		}
+		return x.syntaxFromDatum(node.form, form, closed, keep, close)
+	case *NodeVector:
+		elems := make([]Node, node.Len())
+		for i, elem := range node.Elems() {
+			var err error
+			elems[i], err = x.syntaxFromDatum(elem, form, interp, keep, close)
+			if err != nil {
+				return nil, err
+			}
+		}
+		nv := NewNodeVector(elems)
+		nv.SetSpan(form.Span())
+		return nv, nil
+	case *NodeQuote:
+		arg, err := x.syntaxFromDatum(node.Arg, form, interp, keep, close)
+		if err != nil {
+			return nil, err
+		}
+		nq := NewNodeQuote(arg, node.Quasi)
+		nq.SetSpan(form.Span())
+		return nq, nil
+	case *NodeUnQuote:
+		arg, err := x.syntaxFromDatum(node.Arg, form, interp, keep, close)
+		if err != nil {
+			return nil, err
+		}
+		nu := NewNodeUnQuote(arg)
+		nu.SetSpan(form.Span())
+		return nu, nil
+	}
+
+	elems, tail, ok := listParts(n)
+	if !ok {
+		return n, nil
+	}
+	for i, elem := range elems {
+		var err error
+		elems[i], err = x.syntaxFromDatum(elem, form, interp, keep, close)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if tail != nil {
+		var err error
+		tail, err = x.syntaxFromDatum(tail, form, interp, keep, close)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buildList(form, elems, tail), nil
+}
@@ -0,0 +1,155 @@
+package gol
+
+import (
+	"sort"
+	"strings"
+)
+
+// Diagnostics are all the syntax errors found by ParseRecover
+type Diagnostics []PosError
+
+func (d Diagnostics) Error() string {
+	msgs := make([]string, len(d))
+	for i := range d {
+		msgs[i] = d[i].Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (pe PosError) Pos() Position {
+	return pe.pos
+}
+
+// ParseRecover parses as much as it can, rather than stopping at the
+// first error. If there is one, it resynchronises at each top-level
+// form, and returns the forms which parsed along with diagnostics for
+// those which didn't.
+func (p *Parser) ParseRecover() (Node, Diagnostics) {
+	var diags Diagnostics
+	var toks []Token
+	for {
+		tok, err := p.peekToken()
+		if err == ErrNoMoreTokens {
+			break
+		}
+		if err != nil {
+			// The lexer can't carry on, but we can check what it gave us
+			diags = append(diags, asPosError(err))
+			break
+		}
+		p.stepToken()
+		toks = append(toks, tok)
+	}
+	if len(diags) == 0 {
+		// Only guess at where forms start if there is an error, since
+		// valid code can have an opening paren in the first column
+		tree, err := newTokenSliceParser(toks).Parse()
+		if err == nil {
+			return tree, nil
+		}
+	}
+
+	progn := NewNodeList().Cons(NewNodeIdentifier("progn"))
+	forms, formDiags := splitForms(toks)
+	diags = append(diags, formDiags...)
+	for _, form := range forms {
+		fp := newTokenSliceParser(form)
+		for {
+			tree, err := fp.parseSexp()
+			if err == ErrNoMoreTokens {
+				break
+			}
+			if err != nil {
+				diags = append(diags, asPosError(err))
+				break
+			}
+			progn = progn.Cons(tree)
+		}
+	}
+
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i].pos, diags[j].pos
+		return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+	})
+	if len(diags) == 0 {
+		diags = nil
+	}
+	progn = progn.ReverseCopy()
+	setProgramSpan(progn)
+	return progn, diags
+}
+
+func newTokenSliceParser(toks []Token) *Parser {
+	return &Parser{
+		next: func() (Token, error) {
+			if len(toks) == 0 {
+				return TokBug, ErrNoMoreTokens
+			}
+			tok := toks[0]
+			toks = toks[1:]
+			return tok, nil
+		},
+	}
+}
+
+func asPosError(err error) PosError {
+	if pe, ok := err.(PosError); ok {
+		return pe
+	}
+	return posErrorf(Position{}, "%s", err)
+}
+
+func isOpener(tok Token) bool {
+	return tok.Type == tokLParen || tok.Type == tokVectorStart
+}
+
+// isPrefix is true for tokens which apply to the following datum
+func isPrefix(tok Token) bool {
+	switch tok.Type {
+	case tokQuote, tokBackQuote, tokComma, tokDatumComment:
+		return true
+	}
+	return false
+}
+
+// splitForms splits the tokens into balanced top-level forms. Stray
+// closing parens are dropped. An opening paren in the first column
+// inside a list is taken as the start of a new top-level form, so the
+// unclosed lists before it are dropped too.
+func splitForms(toks []Token) ([][]Token, Diagnostics) {
+	var forms [][]Token
+	var diags Diagnostics
+	var form []Token
+	var open []Token
+
+	unclosed := func() {
+		for _, tok := range open {
+			diags = append(diags, posErrorf(tok.Pos, "Unclosed list"))
+		}
+		open = nil
+		form = nil
+	}
+
+	for _, tok := range toks {
+		switch {
+		case isOpener(tok):
+			if len(open) > 0 && tok.Pos.Column == 1 {
+				unclosed()
+			}
+			open = append(open, tok)
+		case tok.Type == tokRParen:
+			if len(open) == 0 {
+				diags = append(diags, posErrorf(tok.Pos, "Unexpected closing paren"))
+				continue
+			}
+			open = open[:len(open)-1]
+		}
+		form = append(form, tok)
+		if len(open) == 0 && !isPrefix(tok) {
+			forms = append(forms, form)
+			form = nil
+		}
+	}
+	unclosed()
+	return forms, diags
+}
@@ -0,0 +1,78 @@
+package gol
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRecover(t *testing.T) {
+	src := `(define (f x)
+  (+ x 1)
+(define (g y) y))
+#(a b)
+(display #\bogus)
+(h 1)
+#(1 2`
+	p := NewLexerParser(NewLexer("<test>", strings.NewReader(src)))
+	tree, diags := p.ParseRecover()
+
+	expected := []struct {
+		msg  string
+		line int
+		col  int
+	}{
+		{"Unclosed list", 1, 1},
+		{"Unexpected closing paren", 3, 17},
+		{"Bad character literal", 5, 10},
+		{"Unclosed list", 7, 1},
+	}
+	if len(diags) != len(expected) {
+		t.Fatalf("Wrong number of diagnostics: %d != %d:\n%s", len(diags), len(expected), diags)
+	}
+	for i, e := range expected {
+		d := diags[i]
+		if !strings.HasPrefix(d.Error(), e.msg) || d.Pos().Line != e.line || d.Pos().Column != e.col {
+			t.Errorf("%d: got [%s], expected [%s] at %d:%d", i, d, e.msg, e.line, e.col)
+		}
+	}
+
+	if tree.String() != "(progn (define (g y) y) #(a b) (h 1))" {
+		t.Errorf("Wrong forms recovered: %s", tree)
+	}
+}
+
+func TestParseRecoverUnterminatedString(t *testing.T) {
+	p := NewLexerParser(NewLexer("<test>", strings.NewReader("(a 1)\n(display \"abc)")))
+	tree, diags := p.ParseRecover()
+	// The string runs to the end, so the list is unclosed too
+	if len(diags) != 2 || !strings.HasPrefix(diags[1].Error(), "Unterminated string") ||
+		diags[1].Pos().Line != 2 || diags[1].Pos().Column != 10 {
+		t.Fatalf("Wrong diagnostics: %s", diags)
+	}
+	if tree.String() != "(progn (a 1))" {
+		t.Fatalf("Wrong forms recovered: %s", tree)
+	}
+}
+
+func TestParseRecoverFirstColumn(t *testing.T) {
+	src := "(define (f x)\n(+ x 1))\n(display (f 2))"
+	p := NewLexerParser(NewLexer("<test>", strings.NewReader(src)))
+	tree, diags := p.ParseRecover()
+	if diags != nil {
+		t.Fatalf("Diagnostics for valid source: %s", diags)
+	}
+	if tree.String() != "(progn (define (f x) (+ x 1)) (display (f 2)))" {
+		t.Fatalf("Wrong parse: %s", tree)
+	}
+}
+
+func TestParseRecoverClean(t *testing.T) {
+	p := NewLexerParser(NewLexer("<test>", strings.NewReader("(a (b . c)) #;(d) e")))
+	tree, diags := p.ParseRecover()
+	if diags != nil {
+		t.Fatalf("Diagnostics for valid source: %s", diags)
+	}
+	if tree.String() != "(progn (a (b . c)) e)" {
+		t.Fatalf("Wrong parse: %s", tree)
+	}
+}
@@ -0,0 +1,477 @@
+package gol
+
+import (
+	"fmt"
+	"go/token"
+	"math/big"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jbert/gol/typ"
+)
+
+// RegisteredFunc is a Go function which gol programs can call, added
+// with Register
+type RegisteredFunc struct {
+	Name string
+	fn   reflect.Value
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]*RegisteredFunc{}
+	builtins   = map[string]bool{}
+)
+
+// AddBuiltins records the names of a backend's builtins, which Register
+// refuses. The backends add theirs as they are initialised.
+func AddBuiltins(names ...string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	for _, name := range names {
+		builtins[name] = true
+	}
+}
+
+// Register makes the Go function fn callable from gol programs as name.
+// Registering a name again replaces the function. A builtin's name
+// can't be registered, so that a name means the same to the interpreter
+// and to compiled code.
+//
+// Args and results are converted with reflection:
+//
+//	bool                   #t and #f
+//	ints, uints            exact integers
+//	floats                 reals (or exact integers, for args)
+//	string                 strings (or symbols, for args)
+//	slices                 lists (or vectors, for args)
+//	maps                   association lists, ordered by key
+//	structs                association lists of exported field names
+//	gol.Node               the value itself
+//
+// A final error result is raised as an error if it isn't nil, and
+// several other results are returned as multiple values.
+//
+// Compiled code can call fn too if it is a top-level function of an
+// importable package, and only uses bools, numbers, strings and slices
+// of them.
+func Register(name string, fn interface{}) error {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return fmt.Errorf("Can't register [%s]: %T isn't a function", name, fn)
+	}
+	ft := v.Type()
+	for i := 0; i < ft.NumIn(); i++ {
+		err := checkConvertible(ft.In(i))
+		if err != nil {
+			return fmt.Errorf("Can't register [%s]: arg %d: %s", name, i, err)
+		}
+	}
+	for i := 0; i < ft.NumOut(); i++ {
+		if ft.Out(i) == errorType {
+			if i != ft.NumOut()-1 {
+				return fmt.Errorf("Can't register [%s]: an error must be the last result", name)
+			}
+			continue
+		}
+		err := checkConvertible(ft.Out(i))
+		if err != nil {
+			return fmt.Errorf("Can't register [%s]: result %d: %s", name, i, err)
+		}
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if builtins[name] {
+		return fmt.Errorf("Can't register [%s]: it is a builtin", name)
+	}
+	registry[name] = &RegisteredFunc{Name: name, fn: v}
+	return nil
+}
+
+// Registered returns the registered functions, ordered by name
+func Registered() []*RegisteredFunc {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	rfs := make([]*RegisteredFunc, 0, len(registry))
+	for _, rf := range registry {
+		rfs = append(rfs, rf)
+	}
+	sort.Slice(rfs, func(i, j int) bool { return rfs[i].Name < rfs[j].Name })
+	return rfs
+}
+
+var (
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+	nodeType  = reflect.TypeOf((*Node)(nil)).Elem()
+)
+
+func checkConvertible(t reflect.Type) error {
+	return checkConvertibleIn(t, map[reflect.Type]bool{})
+}
+
+// checkConvertibleIn checks t, within the structs being checked, since
+// we'd convert a struct which contains itself forever
+func checkConvertibleIn(t reflect.Type, checking map[reflect.Type]bool) error {
+	if t == nodeType {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return nil
+	case reflect.Slice:
+		return checkConvertibleIn(t.Elem(), checking)
+	case reflect.Map:
+		err := checkConvertibleIn(t.Key(), checking)
+		if err != nil {
+			return err
+		}
+		return checkConvertibleIn(t.Elem(), checking)
+	case reflect.Struct:
+		if checking[t] {
+			return fmt.Errorf("can't convert recursive type %s", t)
+		}
+		checking[t] = true
+		defer delete(checking, t)
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			err := checkConvertibleIn(f.Type, checking)
+			if err != nil {
+				return fmt.Errorf("field %s: %s", f.Name, err)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("can't convert %s", t)
+	}
+}
+
+// Call calls the function with args, and returns its results other than
+// a final error, which is returned prefixed with our name
+func (rf *RegisteredFunc) Call(args []Node) ([]Node, error) {
+	ft := rf.fn.Type()
+	numFixed := ft.NumIn()
+	if ft.IsVariadic() {
+		numFixed--
+		if len(args) < numFixed {
+			return nil, fmt.Errorf("Arity-error: expected >= %d args", numFixed)
+		}
+	} else if len(args) != numFixed {
+		return nil, fmt.Errorf("Arity-error: expected == %d args", numFixed)
+	}
+
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		var t reflect.Type
+		if i < numFixed {
+			t = ft.In(i)
+		} else {
+			t = ft.In(numFixed).Elem()
+		}
+		v, err := toGo(arg, t)
+		if err != nil {
+			return nil, fmt.Errorf("Bad arg %d to %s: %s", i, rf.Name, err)
+		}
+		in[i] = v
+	}
+
+	out := rf.fn.Call(in)
+	if n := len(out); n > 0 && ft.Out(n-1) == errorType {
+		if !out[n-1].IsNil() {
+			return nil, fmt.Errorf("%s: %w", rf.Name, out[n-1].Interface().(error))
+		}
+		out = out[:n-1]
+	}
+	results := make([]Node, len(out))
+	for i := range out {
+		results[i] = fromGo(out[i])
+	}
+	return results, nil
+}
+
+// toGo converts n to a value of type t
+func toGo(n Node, t reflect.Type) (reflect.Value, error) {
+	if t == nodeType {
+		return reflect.ValueOf(&n).Elem(), nil
+	}
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Bool:
+		nb, ok := n.(*NodeBool)
+		if !ok {
+			return v, fmt.Errorf("%s isn't a bool", n)
+		}
+		v.SetBool(nb.IsTrue())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		ni, ok := n.(*NodeInt)
+		if !ok {
+			return v, fmt.Errorf("%s isn't an integer", n)
+		}
+		if ni.IsBig() || v.OverflowInt(ni.Value()) {
+			return v, fmt.Errorf("%s is out of range for %s", n, t)
+		}
+		v.SetInt(ni.Value())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		ni, ok := n.(*NodeInt)
+		if !ok {
+			return v, fmt.Errorf("%s isn't an integer", n)
+		}
+		b := ni.BigValue()
+		if b.Sign() < 0 || !b.IsUint64() || v.OverflowUint(b.Uint64()) {
+			return v, fmt.Errorf("%s is out of range for %s", n, t)
+		}
+		v.SetUint(b.Uint64())
+	case reflect.Float32, reflect.Float64:
+		switch num := n.(type) {
+		case *NodeReal:
+			v.SetFloat(num.Value())
+		case *NodeInt:
+			f, _ := new(big.Float).SetInt(num.BigValue()).Float64()
+			v.SetFloat(f)
+		default:
+			return v, fmt.Errorf("%s isn't a real", n)
+		}
+	case reflect.String:
+		switch s := n.(type) {
+		case *NodeString:
+			v.SetString(s.Value())
+		case *NodeIdentifier, *NodeSymbol:
+			v.SetString(s.String())
+		default:
+			return v, fmt.Errorf("%s isn't a string", n)
+		}
+	case reflect.Slice:
+		var elems []Node
+		if nv, ok := n.(*NodeVector); ok {
+			elems = nv.Elems()
+		} else {
+			var ok bool
+			elems, ok = properList(n)
+			if !ok {
+				return v, fmt.Errorf("%s isn't a list", n)
+			}
+		}
+		v.Set(reflect.MakeSlice(t, len(elems), len(elems)))
+		for i, elem := range elems {
+			ev, err := toGo(elem, t.Elem())
+			if err != nil {
+				return v, err
+			}
+			v.Index(i).Set(ev)
+		}
+	case reflect.Map:
+		entries, err := alistEntries(n)
+		if err != nil {
+			return v, err
+		}
+		v.Set(reflect.MakeMapWithSize(t, len(entries)))
+		for _, entry := range entries {
+			kv, err := toGo(entry[0], t.Key())
+			if err != nil {
+				return v, err
+			}
+			ev, err := toGo(entry[1], t.Elem())
+			if err != nil {
+				return v, err
+			}
+			v.SetMapIndex(kv, ev)
+		}
+	case reflect.Struct:
+		entries, err := alistEntries(n)
+		if err != nil {
+			return v, err
+		}
+		for _, entry := range entries {
+			name := entry[0].String()
+			f, ok := t.FieldByName(name)
+			if !ok || !f.IsExported() {
+				return v, fmt.Errorf("%s has no field %s", t, name)
+			}
+			fv, err := toGo(entry[1], f.Type)
+			if err != nil {
+				return v, err
+			}
+			v.FieldByIndex(f.Index).Set(fv)
+		}
+	default:
+		return v, fmt.Errorf("can't convert to %s", t)
+	}
+	return v, nil
+}
+
+// properList is the elements of a proper list
+func properList(n Node) ([]Node, bool) {
+	switch nl := n.(type) {
+	case *NodeList:
+		return listNodes(nl), true
+	case *NodePair:
+		return nil, nl.IsNil()
+	default:
+		return nil, false
+	}
+}
+
+// alistEntries is the keys and values of an association list
+func alistEntries(n Node) ([][2]Node, error) {
+	elems, ok := properList(n)
+	if !ok {
+		return nil, fmt.Errorf("%s isn't an association list", n)
+	}
+	entries := make([][2]Node, len(elems))
+	for i, elem := range elems {
+		k, ok := Car(elem)
+		if !ok {
+			return nil, fmt.Errorf("%s isn't an association list", n)
+		}
+		v, _ := Cdr(elem)
+		entries[i] = [2]Node{k, v}
+	}
+	return entries, nil
+}
+
+// fromGo converts v, which has a type checkConvertible accepts
+func fromGo(v reflect.Value) Node {
+	if v.Type() == nodeType {
+		if v.IsNil() {
+			return Nil()
+		}
+		return v.Interface().(Node)
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return NewNodeBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NewNodeInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return NewNodeBigInt(new(big.Int).SetUint64(v.Uint()))
+	case reflect.Float32, reflect.Float64:
+		return NewNodeReal(v.Float())
+	case reflect.String:
+		return NewNodeString(v.String())
+	case reflect.Slice:
+		nl := NewNodeList()
+		for i := 0; i < v.Len(); i++ {
+			nl = nl.Append(fromGo(v.Index(i)))
+		}
+		return nl
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return lessKey(keys[i], keys[j]) })
+		nl := NewNodeList()
+		for _, k := range keys {
+			nl = nl.Append(Cons(fromGo(k), fromGo(v.MapIndex(k))))
+		}
+		return nl
+	case reflect.Struct:
+		t := v.Type()
+		nl := NewNodeList()
+		for i := 0; i < t.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			nl = nl.Append(Cons(NewNodeIdentifier(t.Field(i).Name), fromGo(v.Field(i))))
+		}
+		return nl
+	default:
+		panic(fmt.Sprintf("Can't convert unchecked type %s", v.Type()))
+	}
+}
+
+// lessKey orders map keys, so that association lists are stable
+func lessKey(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	case reflect.String:
+		return a.String() < b.String()
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+	default:
+		return WriteString(fromGo(a)) < WriteString(fromGo(b))
+	}
+}
+
+// Type is the type of the function for compiled code, or an error if
+// compiled code can't call it
+func (rf *RegisteredFunc) Type() (typ.Type, error) {
+	ft := rf.fn.Type()
+	args := make([]typ.Type, ft.NumIn())
+	for i := range args {
+		var err error
+		if ft.IsVariadic() && i == len(args)-1 {
+			var elem typ.Type
+			elem, err = compiledType(ft.In(i).Elem())
+			args[i] = typ.NewVariadic(elem)
+		} else {
+			args[i], err = compiledType(ft.In(i))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	var results []typ.Type
+	for i := 0; i < ft.NumOut(); i++ {
+		if ft.Out(i) == errorType {
+			continue
+		}
+		t, err := compiledType(ft.Out(i))
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, t)
+	}
+	if len(results) == 0 {
+		return typ.NewFunc(args, typ.Void), nil
+	}
+	return typ.NewFunc(args, typ.NewTuple(results)), nil
+}
+
+func compiledType(t reflect.Type) (typ.Type, error) {
+	switch t.Kind() {
+	case reflect.Bool:
+		return typ.Bool, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return typ.Int, nil
+	case reflect.Float32, reflect.Float64:
+		return typ.Real, nil
+	case reflect.String:
+		return typ.String, nil
+	case reflect.Slice:
+		elem, err := compiledType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return typ.NewList(elem), nil
+	default:
+		return nil, fmt.Errorf("Compiled code can't use %s", t)
+	}
+}
+
+// GoName is the import path of the package the function is in, and its
+// name there, for compiled code to call it by
+func (rf *RegisteredFunc) GoName() (pkgPath string, name string, err error) {
+	full := runtime.FuncForPC(rf.fn.Pointer()).Name()
+	slash := strings.LastIndex(full, "/")
+	dot := strings.Index(full[slash+1:], ".")
+	if dot < 0 {
+		return "", "", fmt.Errorf("Can't find the package of %s", full)
+	}
+	pkgPath, name = full[:slash+1+dot], full[slash+1+dot+1:]
+	if pkgPath == "main" || !token.IsIdentifier(name) || !token.IsExported(name) {
+		return "", "", fmt.Errorf("%s isn't an exported function of an importable package", full)
+	}
+	return pkgPath, name, nil
+}
@@ -0,0 +1,80 @@
+package gol
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func RegisterTestDivide(a, b int64) (int64, error) {
+	if b == 0 {
+		return 0, errors.New("division by zero")
+	}
+	return a / b, nil
+}
+
+type registerTestTree struct {
+	Name string
+	Kids []registerTestTree
+}
+
+func TestRegister(t *testing.T) {
+	bad := []struct {
+		fn  interface{}
+		err string
+	}{
+		{42, "Can't register [bad]: int isn't a function"},
+		{func(c chan int) {}, "Can't register [bad]: arg 0: can't convert chan int"},
+		{func() (error, int) { return nil, 0 }, "Can't register [bad]: an error must be the last result"},
+		{func() *int { return nil }, "Can't register [bad]: result 0: can't convert *int"},
+		{func(registerTestTree) int64 { return 0 }, "Can't register [bad]: arg 0: field Kids: can't convert recursive type gol.registerTestTree"},
+	}
+	for _, b := range bad {
+		err := Register("bad", b.fn)
+		if err == nil || err.Error() != b.err {
+			t.Errorf("Wrong error registering %T: %v", b.fn, err)
+		}
+	}
+
+	AddBuiltins("test-builtin")
+	err := Register("test-builtin", RegisterTestDivide)
+	if err == nil || err.Error() != "Can't register [test-builtin]: it is a builtin" {
+		t.Errorf("Wrong error registering a builtin's name: %v", err)
+	}
+
+	err = Register("test-divide", RegisterTestDivide)
+	if err != nil {
+		t.Fatalf("Can't register: %s", err)
+	}
+	var rf *RegisteredFunc
+	for _, r := range Registered() {
+		if r.Name == "test-divide" {
+			rf = r
+		}
+	}
+	if rf == nil {
+		t.Fatalf("Registered function not found")
+	}
+
+	results, err := rf.Call([]Node{NewNodeInt(7), NewNodeInt(2)})
+	if err != nil || len(results) != 1 || results[0].String() != "3" {
+		t.Errorf("Wrong result: %v %v", results, err)
+	}
+	_, err = rf.Call([]Node{NewNodeInt(7), NewNodeInt(0)})
+	if err == nil || err.Error() != "test-divide: division by zero" {
+		t.Errorf("Wrong error: %v", err)
+	}
+	_, err = rf.Call([]Node{NewNodeInt(7), NewNodeString("x")})
+	if err == nil || !strings.HasPrefix(err.Error(), "Bad arg 1 to test-divide") {
+		t.Errorf("Wrong error for bad arg: %v", err)
+	}
+
+	ty, err := rf.Type()
+	if err != nil || ty.String() != "(Int,Int) -> Int" {
+		t.Errorf("Wrong type: %v %v", ty, err)
+	}
+	pkgPath, name, err := rf.GoName()
+	if err != nil || pkgPath != "github.com/jbert/gol" || name != "RegisterTestDivide" {
+		t.Errorf("Wrong Go name: %s %s %v", pkgPath, name, err)
+	}
+}
@@ -1,7 +1,5 @@
 package gol
 
 var STDLIB = `
-(define (write x) (display x))
-
-(define (newline) (display "\n"))
+(define (newline . port) (apply display "\n" port))
 `
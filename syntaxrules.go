@@ -0,0 +1,389 @@
+package gol
+
+import "reflect"
+
+// syntaxRules is a macro defined by
+//
+//	(syntax-rules [ellipsis] (literal ...) (pattern template) ...)
+type syntaxRules struct {
+	ellipsis *NodeIdentifier
+	literals []*NodeIdentifier
+	rules    []syntaxRule
+	// The scope the macro was defined in, for the aliases it introduces
+	env *syntaxScope
+}
+
+type syntaxRule struct {
+	pattern  Node
+	template Node
+}
+
+// syntaxMatch is what a pattern variable matched. Under an ellipsis it
+// holds one match per repetition.
+type syntaxMatch struct {
+	node  Node
+	isSeq bool
+	seq   []*syntaxMatch
+}
+
+type syntaxMatches map[interface{}]*syntaxMatch
+
+func (x *expander) parseSyntaxRules(spec Node, s *syntaxScope) (*syntaxRules, error) {
+	args := spec.(*NodeList).Rest()
+	m := &syntaxRules{env: s}
+
+	if id, ok := args.First().(*NodeIdentifier); ok {
+		m.ellipsis = id
+		args = args.Rest()
+	}
+	literals, ok := args.First().(*NodeList)
+	if !ok {
+		return nil, NodeErrorf(spec, "Bad syntax-rules - literals must be a list")
+	}
+	err := literals.Foreach(func(n Node) error {
+		id, ok := n.(*NodeIdentifier)
+		if !ok {
+			return NodeErrorf(spec, "Bad syntax-rules - literal must be an identifier")
+		}
+		m.literals = append(m.literals, id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = args.Rest().Foreach(func(n Node) error {
+		rule, ok := n.(*NodeList)
+		if !ok || rule.Len() != 2 {
+			return NodeErrorf(spec, "Bad syntax-rules - rule must be (pattern template)")
+		}
+		if _, _, ok := listParts(rule.First()); !ok {
+			return NodeErrorf(rule, "Bad syntax-rules - pattern must be a list")
+		}
+		m.rules = append(m.rules, syntaxRule{pattern: rule.First(), template: rule.Nth(1)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (x *expander) isEllipsis(m *syntaxRules, n Node) bool {
+	id, ok := n.(*NodeIdentifier)
+	if !ok {
+		return false
+	}
+	if m.ellipsis != nil {
+		return x.key(id) == x.key(m.ellipsis)
+	}
+	return id.String() == "..."
+}
+
+func (x *expander) isLiteral(m *syntaxRules, id *NodeIdentifier) bool {
+	for _, lit := range m.literals {
+		if x.key(lit) == x.key(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// expand rewrites form with the first rule which matches it
+func (m *syntaxRules) expand(x *expander, form *NodeList, s *syntaxScope) (Node, error) {
+	for _, rule := range m.rules {
+		// The keyword position is ignored
+		pattern, ok := Cdr(rule.pattern)
+		if !ok {
+			continue
+		}
+		matches := make(syntaxMatches)
+		if !x.match(m, pattern, form.Rest(), s, matches) {
+			continue
+		}
+		renames := make(map[interface{}]*syntaxAlias)
+		return x.instantiate(m, rule.template, matches, renames, form, false)
+	}
+	return nil, NodeErrorf(form, "Bad %s expression - no syntax rule matches", form.First())
+}
+
+func (x *expander) match(m *syntaxRules, pattern Node, input Node, s *syntaxScope, matches syntaxMatches) bool {
+	switch p := pattern.(type) {
+	case *NodeIdentifier:
+		if p.String() == "_" {
+			return true
+		}
+		if x.isLiteral(m, p) {
+			id, ok := input.(*NodeIdentifier)
+			return ok && x.sameBinding(p, m.env, id, s)
+		}
+		matches[x.key(p)] = &syntaxMatch{node: input}
+		return true
+	case *NodeVector:
+		v, ok := input.(*NodeVector)
+		if !ok {
+			return false
+		}
+		return x.matchList(m, p, p.Elems(), nil, v.Elems(), nil, s, matches)
+	}
+
+	if pElems, pTail, ok := listParts(pattern); ok {
+		iElems, iTail, ok := listParts(input)
+		if !ok {
+			return false
+		}
+		return x.matchList(m, pattern, pElems, pTail, iElems, iTail, s, matches)
+	}
+
+	// Any other datum must be equal
+	return sameDatum(pattern, input)
+}
+
+func (x *expander) matchList(m *syntaxRules, pattern Node, pElems []Node, pTail Node, iElems []Node, iTail Node, s *syntaxScope, matches syntaxMatches) bool {
+	ellipsisAt := -1
+	for i := 1; i < len(pElems); i++ {
+		if x.isEllipsis(m, pElems[i]) {
+			ellipsisAt = i - 1
+			break
+		}
+	}
+
+	if ellipsisAt < 0 {
+		if len(iElems) < len(pElems) {
+			return false
+		}
+		for i := range pElems {
+			if !x.match(m, pElems[i], iElems[i], s, matches) {
+				return false
+			}
+		}
+		rest := iElems[len(pElems):]
+		if pTail == nil {
+			return len(rest) == 0 && iTail == nil
+		}
+		return x.match(m, pTail, buildList(pattern, rest, iTail), s, matches)
+	}
+
+	before := pElems[:ellipsisAt]
+	repeated := pElems[ellipsisAt]
+	after := pElems[ellipsisAt+2:]
+	numRepeats := len(iElems) - len(before) - len(after)
+	if numRepeats < 0 {
+		return false
+	}
+	if pTail == nil && iTail != nil {
+		return false
+	}
+	for i := range before {
+		if !x.match(m, before[i], iElems[i], s, matches) {
+			return false
+		}
+	}
+
+	seqs := make(map[interface{}]*syntaxMatch)
+	for _, k := range x.patternVars(m, repeated) {
+		seqs[k] = &syntaxMatch{isSeq: true}
+		matches[k] = seqs[k]
+	}
+	for i := 0; i < numRepeats; i++ {
+		repeatMatches := make(syntaxMatches)
+		if !x.match(m, repeated, iElems[len(before)+i], s, repeatMatches) {
+			return false
+		}
+		for k, seq := range seqs {
+			seq.seq = append(seq.seq, repeatMatches[k])
+		}
+	}
+
+	afterInput := iElems[len(before)+numRepeats:]
+	for i := range after {
+		if !x.match(m, after[i], afterInput[i], s, matches) {
+			return false
+		}
+	}
+	if pTail != nil {
+		if iTail == nil {
+			iTail = NewNodeList()
+		}
+		return x.match(m, pTail, iTail, s, matches)
+	}
+	return true
+}
+
+// patternVars returns the keys of the pattern variables in pattern
+func (x *expander) patternVars(m *syntaxRules, pattern Node) []interface{} {
+	switch p := pattern.(type) {
+	case *NodeIdentifier:
+		if p.String() == "_" || x.isLiteral(m, p) || x.isEllipsis(m, p) {
+			return nil
+		}
+		return []interface{}{x.key(p)}
+	case *NodeVector:
+		var vars []interface{}
+		for _, elem := range p.Elems() {
+			vars = append(vars, x.patternVars(m, elem)...)
+		}
+		return vars
+	}
+	elems, tail, ok := listParts(pattern)
+	if !ok {
+		return nil
+	}
+	var vars []interface{}
+	for _, elem := range elems {
+		vars = append(vars, x.patternVars(m, elem)...)
+	}
+	if tail != nil {
+		vars = append(vars, x.patternVars(m, tail)...)
+	}
+	return vars
+}
+
+// sameDatum compares non-identifier pattern data, such as numbers
+func sameDatum(a, b Node) bool {
+	switch a.(type) {
+	case *NodeInt, *NodeReal, *NodeRational, *NodeString, *NodeChar, *NodeBool:
+		return reflect.TypeOf(a) == reflect.TypeOf(b) && a.String() == b.String()
+	}
+	return IsNull(a) && IsNull(b)
+}
+
+// instantiate fills in template. Identifiers which aren't pattern
+// variables become aliases, one per name for the whole expansion.
+// escaped is set inside (... ...), where the ellipsis is literal.
+func (x *expander) instantiate(m *syntaxRules, template Node, matches syntaxMatches, renames map[interface{}]*syntaxAlias, form Node, escaped bool) (Node, error) {
+	switch t := template.(type) {
+	case *NodeIdentifier:
+		if match, ok := matches[x.key(t)]; ok {
+			if match.isSeq {
+				return nil, NodeErrorf(form, "Pattern variable [%s] used without an ellipsis", t)
+			}
+			return match.node, nil
+		}
+		k := x.key(t)
+		alias, ok := renames[k]
+		if !ok {
+			alias = &syntaxAlias{orig: t, env: m.env}
+			renames[k] = alias
+		}
+		id := NewNodeIdentifier(t.String())
+		id.SetSpan(form.Span())
+		x.aliases[id] = alias
+		return id, nil
+	case *NodeVector:
+		elems, err := x.instantiateElems(m, t.Elems(), matches, renames, form, escaped)
+		if err != nil {
+			return nil, err
+		}
+		nv := NewNodeVector(elems)
+		nv.SetSpan(form.Span())
+		return nv, nil
+	case *NodeQuote:
+		arg, err := x.instantiate(m, t.Arg, matches, renames, form, escaped)
+		if err != nil {
+			return nil, err
+		}
+		nq := NewNodeQuote(arg, t.Quasi)
+		nq.SetSpan(form.Span())
+		return nq, nil
+	case *NodeUnQuote:
+		arg, err := x.instantiate(m, t.Arg, matches, renames, form, escaped)
+		if err != nil {
+			return nil, err
+		}
+		nu := NewNodeUnQuote(arg)
+		nu.SetSpan(form.Span())
+		return nu, nil
+	}
+
+	elems, tail, ok := listParts(template)
+	if !ok {
+		return template, nil
+	}
+	if !escaped && len(elems) == 2 && tail == nil && x.isEllipsis(m, elems[0]) {
+		return x.instantiate(m, elems[1], matches, renames, form, true)
+	}
+	newElems, err := x.instantiateElems(m, elems, matches, renames, form, escaped)
+	if err != nil {
+		return nil, err
+	}
+	var newTail Node
+	if tail != nil {
+		newTail, err = x.instantiate(m, tail, matches, renames, form, escaped)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buildList(form, newElems, newTail), nil
+}
+
+func (x *expander) instantiateElems(m *syntaxRules, elems []Node, matches syntaxMatches, renames map[interface{}]*syntaxAlias, form Node, escaped bool) ([]Node, error) {
+	var ret []Node
+	for i := 0; i < len(elems); i++ {
+		depth := 0
+		for !escaped && i+depth+1 < len(elems) && x.isEllipsis(m, elems[i+depth+1]) {
+			depth++
+		}
+		if depth == 0 {
+			n, err := x.instantiate(m, elems[i], matches, renames, form, escaped)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, n)
+			continue
+		}
+		repeated, err := x.instantiateRepeated(m, elems[i], depth, matches, renames, form)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, repeated...)
+		i += depth
+	}
+	return ret, nil
+}
+
+// instantiateRepeated expands a subtemplate followed by depth ellipses,
+// stepping through the sequence variables it uses
+func (x *expander) instantiateRepeated(m *syntaxRules, template Node, depth int, matches syntaxMatches, renames map[interface{}]*syntaxAlias, form Node) ([]Node, error) {
+	if depth == 0 {
+		n, err := x.instantiate(m, template, matches, renames, form, false)
+		if err != nil {
+			return nil, err
+		}
+		return []Node{n}, nil
+	}
+
+	var seqVars []interface{}
+	numRepeats := -1
+	for _, k := range x.patternVars(m, template) {
+		match, ok := matches[k]
+		if !ok || !match.isSeq {
+			continue
+		}
+		seqVars = append(seqVars, k)
+		if numRepeats >= 0 && numRepeats != len(match.seq) {
+			return nil, NodeErrorf(form, "Pattern variables under an ellipsis matched different lengths")
+		}
+		numRepeats = len(match.seq)
+	}
+	if len(seqVars) == 0 {
+		return nil, NodeErrorf(form, "No pattern variables before ellipsis in template")
+	}
+
+	var ret []Node
+	for i := 0; i < numRepeats; i++ {
+		repeatMatches := make(syntaxMatches, len(matches))
+		for k, v := range matches {
+			repeatMatches[k] = v
+		}
+		for _, k := range seqVars {
+			repeatMatches[k] = matches[k].seq[i]
+		}
+		nodes, err := x.instantiateRepeated(m, template, depth-1, repeatMatches, renames, form)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, nodes...)
+	}
+	return ret, nil
+}
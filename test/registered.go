@@ -0,0 +1,123 @@
+package test
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jbert/gol"
+)
+
+// The functions registered for RegisteredTestCases. They are in a
+// package of their own so that compiled code can import them.
+
+func Repeat(s string, n int64) string {
+	return strings.Repeat(s, int(n))
+}
+
+func Sum(xs ...int64) int64 {
+	var total int64
+	for _, x := range xs {
+		total += x
+	}
+	return total
+}
+
+func Fields(s string) []string {
+	return strings.Fields(s)
+}
+
+func Divide(a, b int64) (int64, error) {
+	if b == 0 {
+		return 0, errors.New("division by zero")
+	}
+	return a / b, nil
+}
+
+func MinMax(xs ...int64) (int64, int64) {
+	min, max := xs[0], xs[0]
+	for _, x := range xs {
+		if x < min {
+			min = x
+		}
+		if x > max {
+			max = x
+		}
+	}
+	return min, max
+}
+
+func Shout(s string, loud bool) string {
+	if loud {
+		return strings.ToUpper(s) + "!"
+	}
+	return s
+}
+
+func Scale(k float64, xs ...float64) []float64 {
+	scaled := make([]float64, len(xs))
+	for i := range xs {
+		scaled[i] = xs[i] * k
+	}
+	return scaled
+}
+
+func Join(words []string, sep string) string {
+	return strings.Join(words, sep)
+}
+
+func Small(n uint8) uint8 {
+	return n
+}
+
+func Ignore(s string) {
+}
+
+func WordCounts(s string) map[string]int64 {
+	counts := make(map[string]int64)
+	for _, w := range strings.Fields(s) {
+		counts[w]++
+	}
+	return counts
+}
+
+func Total(m map[string]int64) int64 {
+	var total int64
+	for _, v := range m {
+		total += v
+	}
+	return total
+}
+
+type Point struct {
+	X, Y int64
+}
+
+func Midpoint(a, b Point) Point {
+	return Point{X: (a.X + b.X) / 2, Y: (a.Y + b.Y) / 2}
+}
+
+// RegisterFunctions registers the functions for RegisteredTestCases
+func RegisterFunctions() error {
+	funcs := map[string]interface{}{
+		"repeat":      Repeat,
+		"sum":         Sum,
+		"fields":      Fields,
+		"divide":      Divide,
+		"min-max":     MinMax,
+		"shout":       Shout,
+		"scale":       Scale,
+		"join":        Join,
+		"small":       Small,
+		"ignore":      Ignore,
+		"word-counts": WordCounts,
+		"total":       Total,
+		"midpoint":    Midpoint,
+	}
+	for name, fn := range funcs {
+		err := gol.Register(name, fn)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -16,6 +16,31 @@ func FuncTestCases() []TestCase {
 
 (f 7)
 `, "10", ""},
+		{`(define (f x)
+		    (define y (* x 2))
+		    (+ y 1))
+		  (f 3)`, "7", ""},
+		{`(define (f x)
+		    (define (my-even? n) (if (= n 0) #t (my-odd? (- n 1))))
+		    (define (my-odd? n) (if (= n 0) #f (my-even? (- n 1))))
+		    (my-even? x))
+		  (f 10)`, "#t", ""},
+		{`(define b 1)
+		  (define c (let ((a 1000))
+		    (define b (+ a 3))
+		    b))
+		  (+ b c)`, "1004", ""},
+		{"((lambda () (define z 5)))", "", "Bad body - no expression after internal defines"},
+		{`(define x 10)
+		  (define (f) (display "a") (define x 1) x)
+		  (f)
+		  x`, "", "Bad body - internal define after an expression"},
+		{`(define a 'g)
+		  (define (f) (if #t (define a 'inner) 0) 1)
+		  (f)
+		  a`, "", "Bad define expression - only allowed at the top level or the start of a body"},
+		{"(+ 1 (define-values (a b) (values 1 2)))", "", "Bad define-values expression - only allowed at the top level or the start of a body"},
+		{"(begin (define a 1) (progn (define b 2))) (+ a b)", "3", ""},
 	}
 
 }
@@ -53,6 +78,637 @@ func QuoteTestCases() []TestCase {
 	}
 }
 
+func CharTestCases() []TestCase {
+	return []TestCase{
+		{`#\a`, "a", ""},
+		{`#\space`, " ", ""},
+		{`#\x41`, "A", ""},
+		{`#\(`, "(", ""},
+		{`(char->integer #\a)`, "97", ""},
+		{`(integer->char 98)`, "b", ""},
+		{`(char-upcase #\z)`, "Z", ""},
+		{`(char-downcase #\Z)`, "z", ""},
+		{`(char-alphabetic? #\a)`, "#t", ""},
+		{`(char-numeric? #\a)`, "#f", ""},
+		{`(char-whitespace? #\tab)`, "#t", ""},
+		{`(char=? #\a #\a)`, "#t", ""},
+		{`(char<? #\a #\b #\c)`, "#t", ""},
+		{`(char<? #\a #\c #\b)`, "#f", ""},
+		{`(char>=? #\b #\b #\a)`, "#t", ""},
+		{`(char? #\a)`, "#t", ""},
+		{`(char? 1)`, "#f", ""},
+		{`#\bogus`, "", "Bad character literal [#\\bogus]"},
+	}
+}
+
+func NumberTestCases() []TestCase {
+	return []TestCase{
+		{"1.5", "1.5", ""},
+		{"2.0", "2.0", ""},
+		{"-1.5e2", "-150.0", ""},
+		{"1/2", "1/2", ""},
+		{"6/4", "3/2", ""},
+		{"-4/2", "-2", ""},
+		{"(+ 1.5 2.5)", "4.0", ""},
+		{"(- 2.5)", "-2.5", ""},
+		{"(- 5)", "-5", ""},
+		{"(+ 1/2 1/3)", "5/6", ""},
+		{"(* 2/3 3/4)", "1/2", ""},
+		{"(/ 1.0 4.0)", "0.25", ""},
+		{"(/ 3/4 1/2)", "3/2", ""},
+		{"(< 1 2 3)", "#t", ""},
+		{"(< 1 3 2)", "#f", ""},
+		{"(>= 2.5 2.5 1.0)", "#t", ""},
+		{"(= 1/2 2/4)", "#t", ""},
+		{"(zero? 0.0)", "#t", ""},
+		{"(exact->inexact 1/4)", "0.25", ""},
+		{"(floor 2.5)", "2.0", ""},
+		{"(floor -5/2)", "-3", ""},
+		{"(ceiling 5/2)", "3", ""},
+		{"(truncate -2.5)", "-2.0", ""},
+		{"(round 5/2)", "2", ""},
+		{"(round 7/2)", "4", ""},
+		{"(round 3.5)", "4.0", ""},
+		{"(sqrt 2.25)", "1.5", ""},
+		{"(expt 2 10)", "1024", ""},
+		{"(expt 2/3 2)", "4/9", ""},
+		{"(expt 4.0 0.5)", "2.0", ""},
+		{`(let ((avg (lambda (a b) (/ (+ a b) 2.0))))
+			(avg 3.0 4.0))`, "3.5", ""},
+	}
+}
+
+// Mixed exactness can't be expressed in the statically typed backend
+func NumericTowerTestCases() []TestCase {
+	return []TestCase{
+		{"(/ 1 2)", "1/2", ""},
+		{"(/ 4 2)", "2", ""},
+		{"(/ 2)", "1/2", ""},
+		{"(+ 1 2.5)", "3.5", ""},
+		{"(+ 1/2 0.5)", "1.0", ""},
+		{"(* 1/2 4)", "2", ""},
+		{"(= 1 1.0)", "#t", ""},
+		{"(< 1/3 0.34 1)", "#t", ""},
+		{"(exact->inexact 1/3)", "0.3333333333333333", ""},
+		{"(inexact->exact 0.25)", "1/4", ""},
+		{"(exact? 1/2)", "#t", ""},
+		{"(inexact? 1.0)", "#t", ""},
+		{"(integer? 2.0)", "#t", ""},
+		{"(rational? 1/2)", "#t", ""},
+		{"(sqrt 16)", "4", ""},
+		{"(sqrt 9/4)", "3/2", ""},
+		{"(sqrt 2)", "1.4142135623730951", ""},
+		{"(expt 2 -2)", "1/4", ""},
+		{"(numerator 6/4)", "3", ""},
+		{"(denominator 6/4)", "2", ""},
+		{"(/ 1 0)", "", "Division by zero"},
+		{"(sqrt -4)", "", "sqrt of negative number"},
+	}
+}
+
+func BigIntTestCases() []TestCase {
+	return []TestCase{
+		{"9223372036854775807", "9223372036854775807", ""},
+		{"99999999999999999999", "99999999999999999999", ""},
+		{"(+ 9223372036854775807 1)", "9223372036854775808", ""},
+		{"(- -9223372036854775808 1)", "-9223372036854775809", ""},
+		{"(- -9223372036854775808)", "9223372036854775808", ""},
+		{"(* 4294967296 4294967296)", "18446744073709551616", ""},
+		{"(* -1 -9223372036854775808)", "9223372036854775808", ""},
+		{"(- (+ 9223372036854775807 10) 10)", "9223372036854775807", ""},
+		{"(* 1 2 3 4 5 6 7 8 9 10 11 12 13 14 15 16 17 18 19 20 21 22 23 24 25)", "15511210043330985984000000", ""},
+		{"(= 99999999999999999999 (+ 99999999999999999998 1))", "#t", ""},
+		{"(< 9223372036854775807 99999999999999999999)", "#t", ""},
+		{"(> -99999999999999999999 -9223372036854775808)", "#f", ""},
+		{"(expt 2 100)", "1267650600228229401496703205376", ""},
+	}
+}
+
+func PairTestCases() []TestCase {
+	return []TestCase{
+		{"'(1 . 2)", "(1 . 2)", ""},
+		{"'(1 2 . 3)", "(1 2 . 3)", ""},
+		{"'(1 . (2 3))", "(1 2 3)", ""},
+		{"'(1 #;2 . 3)", "(1 . 3)", ""},
+		{"(cons 1 2)", "(1 . 2)", ""},
+		{"(cons 1 '())", "(1)", ""},
+		{"(cons 1 (cons 2 3))", "(1 2 . 3)", ""},
+		{"(cons '(1 2) 3)", "((1 2) . 3)", ""},
+		{"(cons 1 '(2 3))", "(1 2 3)", ""},
+		{"(car (cons 1 2))", "1", ""},
+		{"(cdr (cons 1 2))", "2", ""},
+		{"(cdr '(1 2 . 3))", "(2 . 3)", ""},
+		{"(cdr '(1))", "()", ""},
+		{"(cadr '(1 2 3))", "2", ""},
+		{"(cddr '(1 2 3))", "(3)", ""},
+		{"(pair? (cons 1 2))", "#t", ""},
+		{"(pair? '(1))", "#t", ""},
+		{"(pair? '())", "#f", ""},
+		{"(pair? 1)", "#f", ""},
+		{"(null? '())", "#t", ""},
+		{"(null? (cdr '(1)))", "#t", ""},
+		{"(null? (cons 1 2))", "#f", ""},
+		{`(let ((l (list 1 2 3)))
+			(progn
+			  (set-car! l 10)
+			  (set-cdr! (cddr l) 4)
+			  l))`, "(10 2 3 . 4)", ""},
+		{`(let ((p (cons 1 2)))
+			(progn
+			  (set-cdr! p '(5 6))
+			  p))`, "(1 5 6)", ""},
+		{"`(1 . ,(+ 1 1))", "(1 . 2)", ""},
+		{"(car '())", "", "Non-pair passed to car"},
+		{"(cdr 1)", "", "Non-pair passed to cdr"},
+		{"(set-car! '() 1)", "", "Non-pair passed to set-car!"},
+		{"'( . 1)", "", "Dot at start of list"},
+		{"'(1 . 2 3)", "", "More than one datum after dot"},
+		{"(1 . 2)", "", "Can't evaluate improper list"},
+	}
+}
+
+func VectorTestCases() []TestCase {
+	return []TestCase{
+		{"#(1 2 3)", "#(1 2 3)", ""},
+		{"(vector-ref #(1 2 3) 1)", "2", ""},
+		{"(vector-length #(1 2 3))", "3", ""},
+		{"(vector-length (make-vector 4 #\\a))", "4", ""},
+		{"(make-vector 2 1.5)", "#(1.5 1.5)", ""},
+		{"(vector 1 2)", "#(1 2)", ""},
+		{"(vector-map (lambda (x) (* x x)) #(1 2 3))", "#(1 4 9)", ""},
+		{"(vector-ref (vector-map (lambda (x) (> x 1)) #(1 2 3)) 2)", "#t", ""},
+		{`(let ((v (make-vector 3 0)))
+			(progn
+			  (vector-set! v 1 5)
+			  (vector-ref v 1)))`, "5", ""},
+		{"(vector? #(1))", "#t", ""},
+		{"(vector? 1)", "#f", ""},
+	}
+}
+
+// EvalVectorTestCases need heterogeneous vectors or lists, which the
+// golang backend can't type
+func EvalVectorTestCases() []TestCase {
+	return []TestCase{
+		{"#()", "#()", ""},
+		{`#(1 "a" #\b (2 . 3) #(4))`, "#(1 a b (2 . 3) #(4))", ""},
+		{"(vector->list #(1 2 3))", "(1 2 3)", ""},
+		{"(list->vector '(1 2 3))", "#(1 2 3)", ""},
+		{"(vector-map + #(1 2 3) #(10 20))", "#(11 22)", ""},
+		{"(vector? '(1))", "#f", ""},
+		{`(let ((v (make-vector 3 0)))
+			(progn
+			  (vector-for-each (lambda (x) (vector-set! v x 'y)) #(0 2))
+			  v))`, "#(y 0 y)", ""},
+		{"(vector-ref #(1 2) 2)", "", "Index out of range for vector-ref"},
+		{"(vector-ref '(1 2) 0)", "", "Non-vector passed to vector-ref"},
+	}
+}
+
+func StringTestCases() []TestCase {
+	return []TestCase{
+		{`"a\tb"`, "a\tb", ""},
+		{`"say \"hi\"\\"`, `say "hi"\`, ""},
+		{`"\x41;\x3bb;"`, "A\u03bb", ""},
+		{"\"one \\\n     two\"", "one two", ""},
+		{`(string-length "a\nb")`, "3", ""},
+		{`(string-length "h\xe9;llo")`, "5", ""},
+		{`(string-length "")`, "0", ""},
+		{`(string-length "\"q")`, "2", ""},
+		{`(string-ref "abc" 1)`, "b", ""},
+		{`(substring "hello" 1 3)`, "el", ""},
+		{`(string-append "foo" "bar" "baz")`, "foobarbaz", ""},
+		{`(string->number "42")`, "42", ""},
+		{`(string->number "2.5")`, "2.5", ""},
+		{`(string->number "1/2")`, "1/2", ""},
+		{`(string->number "99999999999999999999")`, "99999999999999999999", ""},
+		{`(string->number "forty-two")`, "#f", ""},
+		{`(number->string (+ 1 2))`, "3", ""},
+		{`(number->string 1.5)`, "1.5", ""},
+		{`(string->symbol "foo")`, "foo", ""},
+		{`(string-split "a,b,,c" ",")`, "(a b  c)", ""},
+		{`(string-join (string-split "a b c" " ") "-")`, "a-b-c", ""},
+		{`(string-index "hello" #\l)`, "2", ""},
+		{`(string-index "hello" #\z)`, "#f", ""},
+		// The results may be #f, so are only checked where they're used
+		{`(+ 1 (string-index "abc" #\b))`, "2", ""},
+		{`(* 2 (string->number "21"))`, "42", ""},
+		{`(if (string-index "abc" #\z) 1 2)`, "2", ""},
+		{`(let ((i (string-index "abc" #\c))) (if i ((lambda (j) (* j 10)) i) 0))`, "20", ""},
+	}
+}
+
+// EvalStringTestCases use optional args and predicates, which the
+// golang backend doesn't support
+func EvalStringTestCases() []TestCase {
+	return []TestCase{
+		{`(substring "hello" 2)`, "llo", ""},
+		{`(string->number "ff" 16)`, "255", ""},
+		{`(number->string 255 2)`, "11111111", ""},
+		{`(string-split "  a b  c ")`, "(a b c)", ""},
+		{`(string-join '("x" "y"))`, "x y", ""},
+		{`(string-index "heLlo" char-upper-case?)`, "2", ""},
+		{`(length (string-split "a b"))`, "2", ""},
+		{`(string? "a")`, "#t", ""},
+		{`(string-ref "abc" 3)`, "", "Index out of range for string-ref"},
+		{`(substring "abc" 2 1)`, "", "Index out of range for substring"},
+		{`(string-append "a" 1)`, "", "Non-string passed to string-append"},
+		{`"bad \q"`, "", "Unknown escape in string [\\q]"},
+		{`"\x41"`, "", "Missing ';' after hex escape in string"},
+	}
+}
+
+func DerivedTestCases() []TestCase {
+	return []TestCase{
+		{"(and)", "#t", ""},
+		{"(and #t (> 2 1))", "#t", ""},
+		{"(and #t (< 2 1) (error \"not reached\"))", "#f", ""},
+		{"(or)", "#f", ""},
+		{"(or #f (> 2 1))", "#t", ""},
+		{"(or (> 2 1) (error \"not reached\"))", "#t", ""},
+		{"(let ((x 7)) (cond ((< x 5) 1) ((< x 10) 2) (else 3)))", "2", ""},
+		{"(let ((x 70)) (cond ((< x 5) 1) ((< x 10) 2) (else 3)))", "3", ""},
+		{"(cond ((> 2 1) => (lambda (b) b)) (else #f))", "#t", ""},
+		{"(case (* 2 3) ((2 3 5 7) 1) ((1 4 6 8 9) 2) (else 3))", "2", ""},
+		{"(case 10 ((2 3 5 7) 1) ((1 4 6 8 9) 2) (else => (lambda (x) (* x x))))", "100", ""},
+		{`(case #\b ((#\a) "a") ((#\b #\c) "bc") (else "?"))`, "bc", ""},
+		{"(let* ((x 1) (y (+ x 1)) (z (* y 10))) (+ x y z))", "23", ""},
+		{"(let* () 5)", "5", ""},
+		{"(let loop ((i 0) (acc 1)) (if (= i 5) acc (loop (+ i 1) (* acc 2))))", "32", ""},
+		{"(let ((loop 3)) (let loop ((i loop)) (if (zero? i) 0 (+ i (loop (- i 1))))))", "6", ""},
+		{`(do ((vec (make-vector 5 0))
+		       (i 0 (+ i 1)))
+		      ((= i 5) vec)
+		    (vector-set! vec i i))`, "#(0 1 2 3 4)", ""},
+		{"(do ((i 0 (+ i 1)) (sum 0 (+ sum i))) ((= i 5) sum))", "10", ""},
+		{"(not #f)", "#t", ""},
+		{"(not (> 2 1))", "#f", ""},
+		{"(eqv? 2 (+ 1 1))", "#t", ""},
+		{`(eqv? #\a #\b)`, "#f", ""},
+		// The rewrites call the builtins, rather than any local of that name
+		{"(define (f eqv?) (case 2 ((1 2) 10) (else 20))) (f 0)", "10", ""},
+	}
+}
+
+// EvalDerivedTestCases need the non-boolean truth values or
+// heterogeneous types which the golang backend can't type
+func EvalDerivedTestCases() []TestCase {
+	return []TestCase{
+		{"(or 1)", "1", ""},
+		{"(or #f 2)", "2", ""},
+		{"(or 3 #t)", "3", ""},
+		{"(let ((tmp 4)) (or #f tmp))", "4", ""},
+		{"(and 1 2 'c)", "c", ""},
+		{"(if '() 1 2)", "1", ""},
+		{`(cond ((string-index "abc" #\c) => (lambda (i) (* i 10))) (else 'nope))`, "20", ""},
+		{`(cond ((string-index "abc" #\z) => (lambda (i) (* i 10))) (else 'nope))`, "nope", ""},
+		{"(cond (#f 1) (2))", "2", ""},
+		{"(case 'x ((a e i o u) 'vowel) ((w y) 'semivowel) (else => (lambda (x) x)))", "x", ""},
+		{"(case 'y ((a e i o u) 'vowel) ((w y) 'semivowel) (else 'consonant))", "semivowel", ""},
+		{"(let ((x '(1 3 5 7 9))) (do ((x x (cdr x)) (sum 0 (+ sum (car x)))) ((null? x) sum)))", "25", ""},
+		{"(let ((r '())) (when (> 1 0) (set! r 'yes)) (unless (> 1 0) (set! r 'no)) r)", "yes", ""},
+		{"(define (f void) (when #f 1) void) (f 5)", "5", ""},
+		{"(equal? '(1 (2 #(3 \"x\"))) (list 1 (list 2 (vector 3 \"x\"))))", "#t", ""},
+		{"(eqv? '(1) '(1))", "#f", ""},
+		{"(eqv? '() '())", "#t", ""},
+		{"(let ((p '(1))) (eq? p p))", "#t", ""},
+		{"(cond (else 1) (#t 2))", "", "Bad cond expression - else clause must be last"},
+		{"(when #t)", "", "Bad when expression - missing test or body"},
+		{"(do ((i 0 1 2)) (#t))", "", "Bad do expression - spec must be (var init [step])"},
+	}
+}
+
+func MacroTestCases() []TestCase {
+	return []TestCase{
+		{`(define-syntax ten (syntax-rules () ((_) 10)))
+		  (ten)`, "10", ""},
+		{`(define-syntax my-or
+		    (syntax-rules ()
+		      ((_) #f)
+		      ((_ e) e)
+		      ((_ e r ...) (let ((t e)) (if t t (my-or r ...))))))
+		  (let ((t #t)) (my-or #f t))`, "#t", ""},
+		{`(define-syntax my-let*
+		    (syntax-rules ()
+		      ((_ () body ...) (let () body ...))
+		      ((_ ((x v) rest ...) body ...) (let ((x v)) (my-let* (rest ...) body ...)))))
+		  (my-let* ((a 1) (b (+ a 1)) (c (* b 10))) (+ a b c))`, "23", ""},
+		{`(define-syntax my-if
+		    (syntax-rules (then else)
+		      ((_ c then t else e) (if c t e))))
+		  (my-if (> 1 2) then 1 else 2)`, "2", ""},
+		{`(define-syntax sum
+		    (syntax-rules ()
+		      ((_ (a ...) ...) (+ a ... ...))))
+		  (sum (1 2) (3) () (4 5))`, "15", ""},
+		{`(let ((x 1))
+		    (let-syntax ((get-x (syntax-rules () ((_) x))))
+		      (let ((x 2))
+		        (+ (get-x) (* 10 x)))))`, "21", ""},
+		{`(letrec-syntax
+		      ((count (syntax-rules ()
+		                ((_) 0)
+		                ((_ x rest ...) (+ 1 (count rest ...))))))
+		    (count a b c))`, "3", ""},
+		{`(define-syntax my-if
+		    (er-macro-transformer
+		      (lambda (form rename compare)
+		        (list (rename 'if) (cadr form) (car (cddr form)) (cadr (cddr form))))))
+		  (my-if (> 1 2) 1 2)`, "2", ""},
+		{`(define-syntax is-else
+		    (er-macro-transformer
+		      (lambda (form rename compare)
+		        (if (compare (cadr form) (rename 'else)) 1 0))))
+		  (+ (is-else else) (* 10 (is-else foo)))`, "1", ""},
+		{`(define-syntax add-tmp
+		    (er-macro-transformer
+		      (lambda (form rename compare)
+		        (list (rename 'let) (list (list (rename 'tmp) 1))
+		              (list (rename '+) (rename 'tmp) (cadr form))))))
+		  (let ((tmp 10)) (add-tmp tmp))`, "11", ""},
+		{`(define-syntax with-it
+		    (sc-macro-transformer
+		      (lambda (form env)
+		        (let ((value (make-syntactic-closure env '() (cadr form)))
+		              (body (make-syntactic-closure env '(it) (car (cddr form)))))
+		          ` + "`" + `(let ((it ,value)) ,body)))))
+		  (let ((it 4)) (with-it 3 (* it 2)))`, "6", ""},
+	}
+}
+
+// EvalMacroTestCases use set! or heterogeneous data, which the golang
+// backend can't compile
+func EvalMacroTestCases() []TestCase {
+	return []TestCase{
+		{`(define-syntax swap!
+		    (syntax-rules ()
+		      ((_ a b) (let ((tmp a)) (set! a b) (set! b tmp)))))
+		  (let ((tmp 1) (other 2))
+		    (swap! tmp other)
+		    (list tmp other))`, "(2 1)", ""},
+		{`(define-syntax while
+		    (syntax-rules ()
+		      ((_ c body ...) (let lp () (when c body ... (lp))))))
+		  (define i 0)
+		  (define acc '())
+		  (while (< i 3) (set! acc (cons i acc)) (set! i (+ i 1)))
+		  acc`, "(2 1 0)", ""},
+		{`(define-syntax my-or
+		    (syntax-rules ()
+		      ((_) #f)
+		      ((_ e) e)
+		      ((_ e r ...) (let ((t e)) (if t t (my-or r ...))))))
+		  (let ((if list)) (my-or #f 7))`, "7", ""},
+		{`(define-syntax tail (syntax-rules () ((_ a . b) 'b)))
+		  (tail 1 2 3)`, "(2 3)", ""},
+		{`(define-syntax vec (syntax-rules () ((_ #(a ...)) (list a ...))))
+		  (vec #(1 2 3))`, "(1 2 3)", ""},
+		{`(define-syntax my-list
+		    (syntax-rules ::: ()
+		      ((_ x :::) (list x :::))))
+		  (my-list 1 2 3)`, "(1 2 3)", ""},
+		{`(define-syntax be-like-progn
+		    (syntax-rules ()
+		      ((_ name)
+		       (define-syntax name
+		         (syntax-rules ()
+		           ((name expr (... ...)) (progn expr (... ...))))))))
+		  (be-like-progn sequence)
+		  (sequence 1 2 3 4)`, "4", ""},
+		{`(let ((x 'outer))
+		    (let-syntax ((with-x
+		                  (syntax-rules ()
+		                    ((_ y expr)
+		                     (let-syntax ((y (syntax-rules () ((_) x))))
+		                       expr)))))
+		      (let ((x 'inner))
+		        (with-x z (z)))))`, "outer", ""},
+		{`(define-syntax ten (syntax-rules () ((_) 10)))
+		  (ten 1)`, "", "Bad ten expression - no syntax rule matches"},
+		{`(define-syntax ten (syntax-rules () ((_) 10)))
+		  (+ ten 1)`, "", "Syntax keyword [ten] used as a variable"},
+		{`(define-syntax my-or
+		    (er-macro-transformer
+		      (lambda (expr rename compare)
+		        (if (null? (cdr expr))
+		            #f
+		            (list (rename 'let) (list (list (rename 'tmp) (cadr expr)))
+		                  (list (rename 'if) (rename 'tmp)
+		                        (rename 'tmp)
+		                        (cons (rename 'my-or) (cddr expr))))))))
+		  (let ((tmp 6)) (my-or #f tmp))`, "6", ""},
+		{`(define-syntax define-foo
+		    (sc-macro-transformer
+		      (lambda (form env)
+		        (make-syntactic-closure env '(foo) ` + "`" + `(define foo 8)))))
+		  (define-foo)
+		  foo`, "8", ""},
+		{`(define-syntax aif
+		    (rsc-macro-transformer
+		      (lambda (form env)
+		        (list (make-syntactic-closure env '() 'let) (list (list 'it (cadr form)))
+		              (list (make-syntactic-closure env '() 'if) 'it (car (cddr form)) (cadr (cddr form)))))))
+		  (let ((if list)) (aif (+ 1 2) (* it 2) 0))`, "6", ""},
+		{`(define-syntax bad (er-macro-transformer (lambda (form) 1)))
+		  (bad)`, "", "Arg mismatch"},
+	}
+}
+
+func VariadicTestCases() []TestCase {
+	return []TestCase{
+		{`(define (join . parts) (string-join parts ", "))
+		  (join "a" "b" "c")`, "a, b, c", ""},
+		{`(define (join sep . parts) (string-join parts sep))
+		  (join "-")`, "", ""},
+		{`(define (args . xs) xs)
+		  (args 1 2 3)`, "(1 2 3)", ""},
+		{`(define (f a b . rest) (+ a b))
+		  (f 1 2 3 4)`, "3", ""},
+		{`(define (f a . xs) (+ a (apply + xs)))
+		  (f 1 2 3)`, "6", ""},
+		{`(define (g . xs) (apply + 10 20 xs))
+		  (g 1 2)`, "33", ""},
+		{"((lambda args args) 1 2)", "(1 2)", ""},
+		{`(define (f a . xs) a)
+		  (define (g . xs) (apply f xs))
+		  (g 1 2)`, "1", ""},
+		{`(define (f a . rest) (apply + a rest))
+		  (f 1 2 3)`, "6", ""},
+		{`(define plus
+		    (case-lambda
+		      (() 0)
+		      ((x) x)
+		      ((x y) (+ x y))
+		      ((x y . z) (apply plus (+ x y) z))))
+		  (+ (plus) (plus 1) (plus 1 2) (plus 1 2 3 4))`, "14", ""},
+		{`(define f (case-lambda ((a) 1) (args (length args))))
+		  (+ (f 1) (f) (f 1 2 3))`, "4", ""},
+		{"((case-lambda ((a) (+ a 1))))", "", "No case-lambda clause matches the args"},
+	}
+}
+
+// GolangVariadicTestCases check that the golang backend reports an
+// apply it can't compile, which the interpreter runs
+func GolangVariadicTestCases() []TestCase {
+	return []TestCase{
+		{`(define (f n s) (string-ref s n))
+		  (define (g . xs) (apply f xs))
+		  (g 0 "a")`, "", "apply in compiled code needs a procedure whose args all have one type"},
+	}
+}
+
+// EvalVariadicTestCases need list, heterogeneous data or a rest list
+// whose type nothing constrains, which the golang backend can't
+// compile, or check errors which it reports differently
+func EvalVariadicTestCases() []TestCase {
+	return []TestCase{
+		{"((lambda args args))", "()", ""},
+		{"((lambda (a . b) (list a b)) 1 2 3)", "(1 (2 3))", ""},
+		{"((lambda (a . b) (list a b)) 1)", "(1 ())", ""},
+		{`(define plus
+		    (case-lambda
+		      (() 0)
+		      ((x) x)
+		      ((x y) (+ x y))
+		      ((x y . z) (apply plus (+ x y) z))))
+		  (list (plus) (plus 1) (plus 1 2) (plus 1 2 3 4))`, "(0 1 3 10)", ""},
+		{`(define f (case-lambda ((a) 'one) (args (length args))))
+		  (list (f 1) (f) (f 1 2 3))`, "(one 0 3)", ""},
+		{"((lambda (a b . c) a) 1)", "", "Arg mismatch"},
+		{"((case-lambda ((a) a)))", "", "No case-lambda clause matches the args"},
+		{"(define (f length) ((case-lambda ((a) 1) ((a b) 2)) 1 2)) (f 0)", "2", ""},
+		{"(define (f error) ((case-lambda ((a) a)))) (f 0)", "", "No case-lambda clause matches the args"},
+		{"((lambda (a . 1) a) 1)", "", "Bad lambda expression - rest arg must be identifier"},
+	}
+}
+
+func LetTestCases() []TestCase {
+	return []TestCase{
+		{"(let ((x 1)) (let ((x 2) (y x)) (+ (* 10 x) y)))", "21", ""},
+		{"(letrec* ((a 1) (b (+ a 1)) (c (* b 10))) (+ a b c))", "23", ""},
+		{`(letrec ((my-even? (lambda (n) (if (= n 0) #t (my-odd? (- n 1)))))
+		           (my-odd? (lambda (n) (if (= n 0) #f (my-even? (- n 1))))))
+		    (my-odd? 7))`, "#t", ""},
+		{"(letrec ((a b) (b 1)) a)", "", "Identifier [b] used before it was assigned"},
+		{"(letrec* ((a (+ b 1)) (b 1)) a)", "", "Identifier [b] used before it was assigned"},
+		{"(letrec ((a (let ((b 2)) b)) (b 1)) (+ a b))", "3", ""},
+	}
+}
+
+// EvalLetTestCases check evaluation order and errors, which the golang
+// backend doesn't report the same way
+func EvalLetTestCases() []TestCase {
+	return []TestCase{
+		{`(define acc '())
+		  (let ((a (set! acc (cons 1 acc)))
+		        (b (set! acc (cons 2 acc)))
+		        (c (set! acc (cons 3 acc))))
+		    acc)`, "(3 2 1)", ""},
+		{"(letrec ((f (lambda () g)) (g 5)) (f))", "5", ""},
+		{"(let ((f (lambda () 1))) (let ((f (lambda () 2)) (g f)) (g)))", "1", ""},
+		{"(let ((x 1) (y x)) y)", "", "Failed to find [x]"},
+	}
+}
+
+// ContinuationTestCases need call/cc, which the golang backend can't
+// compile
+func ContinuationTestCases() []TestCase {
+	return []TestCase{
+		{"(+ 1 (call/cc (lambda (k) 2)))", "3", ""},
+		{"(+ 1 (call/cc (lambda (k) (+ 10 (k 2)))))", "3", ""},
+		{"(call-with-current-continuation (lambda (k) (k)))", "()", ""},
+		{`(define (find-first pred l)
+		    (call/cc (lambda (return)
+		      (vector-for-each (lambda (x) (if (pred x) (return x) #f)) (list->vector l))
+		      #f)))
+		  (find-first (lambda (x) (> x 2)) '(1 2 3 4))`, "3", ""},
+		// Re-entering a continuation more than once
+		{`(define k #f)
+		  (define n 0)
+		  (define acc '())
+		  (set! acc (cons (call/cc (lambda (c) (set! k c) 0)) acc))
+		  (set! n (+ n 1))
+		  (if (< n 3) (k n) #f)
+		  acc`, "(2 1 0)", ""},
+		// A generator, resuming its walk over a list each time
+		{`(define (make-gen l)
+		    (define return #f)
+		    (define (walk)
+		      (let loop ((l l))
+		        (if (null? l)
+		          (return 'done)
+		          (begin
+		            (call/cc (lambda (resume)
+		              (set! walk (lambda () (resume #f)))
+		              (return (car l))))
+		            (loop (cdr l))))))
+		    (lambda () (call/cc (lambda (r) (set! return r) (walk)))))
+		  (define g (make-gen '(a b)))
+		  (let* ((x (g)) (y (g)) (z (g))) (list x y z))`, "(a b done)", ""},
+		{`(define acc '())
+		  (define (note x) (set! acc (cons x acc)))
+		  (dynamic-wind
+		    (lambda () (note 'before))
+		    (lambda () (note 'during))
+		    (lambda () (note 'after)))
+		  (reverse acc)`, "(before during after)", ""},
+		{`(define acc '())
+		  (define (note x) (set! acc (cons x acc)))
+		  (call/cc (lambda (k)
+		    (dynamic-wind
+		      (lambda () (note 'before))
+		      (lambda () (k 'out) (note 'skipped))
+		      (lambda () (note 'after)))))
+		  (reverse acc)`, "(before after)", ""},
+		// Re-entering a dynamic-wind runs the before thunk again
+		{`(define acc '())
+		  (define (note x) (set! acc (cons x acc)))
+		  (define k #f)
+		  (define n 0)
+		  (dynamic-wind
+		    (lambda () (note 'before))
+		    (lambda () (call/cc (lambda (c) (set! k c))) (note 'during))
+		    (lambda () (note 'after)))
+		  (set! n (+ n 1))
+		  (if (< n 2) (k #f) #f)
+		  (reverse acc)`, "(before during after before during after)", ""},
+		{"(call/cc (lambda (k) (k 1 2)))", "", "Arity-error"},
+		// Builtins which call procedures can be re-entered after they
+		// have returned
+		{`(define k #f)
+		  (define n 0)
+		  (define v (vector-map (lambda (x) (call/cc (lambda (c) (when (= x 2) (set! k c)) x))) (vector 1 2 3)))
+		  (set! n (+ n 1))
+		  (if (< n 3) (k (* 10 n)) v)`, "#(1 20 3)", ""},
+		{`(define acc '())
+		  (define k #f)
+		  (vector-for-each (lambda (x) (call/cc (lambda (c) (when (= x 1) (set! k c)))) (set! acc (cons x acc))) (vector 1 2))
+		  (if (< (length acc) 4) (k #f) (reverse acc))`, "(1 2 1 2)", ""},
+		{`(define k #f)
+		  (define n 0)
+		  (define i (string-index "abc" (lambda (c) (call/cc (lambda (r) (set! k r) (char=? c #\a))))))
+		  (set! n (+ n 1))
+		  (if (< n 2) (k #f) i)`, "#f", ""},
+	}
+}
+
+// EvalTailCallTestCases loop through calls in each tail position, which
+// the golang backend doesn't run in constant space
+func EvalTailCallTestCases() []TestCase {
+	return []TestCase{
+		{"(define (loop n acc) (if (= n 0) acc (loop (- n 1) (+ acc 1)))) (loop 20000 0)", "20000", ""},
+		{`(define (ev? n) (if (= n 0) #t (od? (- n 1))))
+		  (define (od? n) (if (= n 0) #f (ev? (- n 1))))
+		  (ev? 20001)`, "#f", ""},
+		{"(define (loop n) (progn (+ n 1) (if (= n 0) 'done (loop (- n 1))))) (loop 20000)", "done", ""},
+		{"(define (loop n) (let ((m (- n 1))) (if (< m 0) 'done (loop m)))) (loop 20000)", "done", ""},
+		{"(let loop ((i 0)) (cond ((= i 20000) 'done) (else (loop (+ i 1)))))", "done", ""},
+		{"(define (loop n) (and #t (or #f (when #t (if (= n 0) 'done (loop (- n 1))))))) (loop 20000)", "done", ""},
+		{"(define (loop n) (if (= n 0) 'done (apply loop (list (- n 1))))) (loop 20000)", "done", ""},
+		{"(do ((i 0 (+ i 1))) ((= i 20000) i))", "20000", ""},
+	}
+}
+
 func ErrorTestCases() []TestCase {
 	return []TestCase{
 		{"()", "", "Empty application"},
@@ -63,6 +719,226 @@ func ErrorTestCases() []TestCase {
 	}
 }
 
+func ExceptionTestCases() []TestCase {
+	return []TestCase{
+		{"(guard (e (#t 42)) (raise 5))", "42", ""},
+		{"(guard (e (#t 0)) (+ 1 2))", "3", ""},
+		{`(guard (e ((error-object? e) (error-object-message e))) (error "bad thing" 1 2))`, "bad thing", ""},
+		{`(guard (e ((error-object? e) (error-object-irritants e))) (error "bad thing" 1 2))`, "(1 2)", ""},
+		{"(guard (e ((error-object? e) 1) (else 2)) (raise 5))", "2", ""},
+		{"(guard (e ((number? e) e)) (raise 5))", "5", ""},
+		{`(guard (e ((string? e) 1) ((integer? e) 2)) (raise 5))`, "2", ""},
+		{`(guard (e ((error-object? e) => (lambda (b) (if b 1 2)))) (error "x"))`, "1", ""},
+		// No clause matches, so the outer guard gets it
+		{"(guard (e (#t 2)) (guard (e2 ((error-object? e2) 1)) (raise 5)))", "2", ""},
+		{"(guard (e (#t 2)) (let ((raise-continuable 0)) (guard (e2 (#f 1)) (raise 5))))", "2", ""},
+		{"(guard (e (#t (+ e 1))) (raise 5))", "6", ""},
+		{"(+ 1 (with-exception-handler (lambda (c) 42) (lambda () (+ (raise-continuable 5) 1))))", "44", ""},
+		{`(+ 1 (error "foo" 2))`, "", "foo 2"},
+		{"(with-exception-handler (lambda (c) 0) (lambda () (raise 5)))", "", "Exception handler returned from non-continuable raise"},
+	}
+}
+
+// EvalExceptionTestCases raise values or catch failures which the
+// golang backend can't type or describe the same way
+func EvalExceptionTestCases() []TestCase {
+	return []TestCase{
+		{"(guard (e ((pair? e) (cdr e))) (raise (cons 'a 42)))", "42", ""},
+		{"(guard (e ((string? e) e)) (raise 'boom))", "", "Uncaught exception: boom"},
+		{"(error 5)", "", "Non-string passed to error"},
+		{`(guard (e ((error-object? e) (error-object-message e))) (vector-ref (vector 1 2) 5))`, "Index out of range for vector-ref: 5", ""},
+		{`(guard (e ((error-object? e) (error-object-message e))) (car '()))`, "Non-pair passed to car", ""},
+		{`(define acc '())
+		  (define (note x) (set! acc (cons x acc)))
+		  (guard (e (#t (note e)))
+		    (dynamic-wind
+		      (lambda () (note 'before))
+		      (lambda () (raise 'oops))
+		      (lambda () (note 'after))))
+		  (reverse acc)`, "(before after oops)", ""},
+		// The handler runs where raise was called, outside itself
+		{`(with-exception-handler
+		    (lambda (c) (+ c 1))
+		    (lambda ()
+		      (with-exception-handler
+		        (lambda (c) (* 10 (raise-continuable c)))
+		        (lambda () (raise-continuable 1)))))`, "20", ""},
+		{`(define (safe-div a b)
+		    (guard (e ((error-object? e) (list (error-object-message e) (error-object-irritants e))))
+		      (if (= b 0) (error "divide by zero" a) (/ a b))))
+		  (list (safe-div 6 3) (safe-div 1 0))`, "(2 (divide by zero (1)))", ""},
+	}
+}
+
+func ValuesTestCases() []TestCase {
+	return []TestCase{
+		{"(call-with-values (lambda () (values 1 2)) (lambda (a b) (+ a b)))", "3", ""},
+		{"(call-with-values (lambda () 5) (lambda (x) (* x x)))", "25", ""},
+		{"(define (split n) (values (- n 1) (+ n 1))) (call-with-values (lambda () (split 5)) *)", "24", ""},
+		{`(let-values (((a b) (values 1 2)) ((c) (values "x"))) (string-append c (number->string (+ a b))))`, "x3", ""},
+		// The inits don't see the bindings
+		{"(let ((a 10)) (let-values (((a b) (values 1 a))) (+ a b)))", "11", ""},
+		{"(let*-values (((a b) (values 1 2)) ((c d) (values (+ a b) 4))) (* c d))", "12", ""},
+		{"(let*-values () 5)", "5", ""},
+		{"(receive (q r) (values 7 2) (- q r))", "5", ""},
+		{"(let ((q 1)) (receive (q r) (values 7 q) (* q r)))", "7", ""},
+		{"(define (f call-with-values) (let-values (((a b) (values 1 2))) (+ a b call-with-values))) (f 3)", "6", ""},
+		{`(define (f)
+		    (define a 1)
+		    (define-values (b c) (values 2 3))
+		    (define d 4)
+		    (+ a b c d))
+		  (f)`, "10", ""},
+	}
+}
+
+// GolangValuesTestCases check that the golang backend reports the
+// set! a top-level define-values expands to, which it can't compile
+func GolangValuesTestCases() []TestCase {
+	return []TestCase{
+		{"(define-values (a b) (values 1 2)) (+ a b)", "", "set! isn't supported in compiled code"},
+	}
+}
+
+// EvalValuesTestCases need rest formals, no values or a top-level
+// define-values, which the golang backend can't compile
+func EvalValuesTestCases() []TestCase {
+	return []TestCase{
+		{"(call-with-values (lambda () (values 1 2 3)) list)", "(1 2 3)", ""},
+		{"(call-with-values (lambda () (values)) (lambda () 4))", "4", ""},
+		{"(call-with-values values list)", "()", ""},
+		{"(let-values (((a . rest) (values 1 2 3)) (all (values 4 5))) (list a rest all))", "(1 (2 3) (4 5))", ""},
+		{"(define-values (q r) (values 7 8)) (list q r)", "(7 8)", ""},
+		{"(define-values (h . t) (values 1 2 3)) (list h t)", "(1 (2 3))", ""},
+		{"(define-values all (values 1 2)) all", "(1 2)", ""},
+		{"(values 1 2)", "1 2", ""},
+		{"(call-with-values (lambda () (values 1 2)) (lambda (a) a))", "", "Arg mismatch"},
+		{"(let-values ((a)) a)", "", "Bad let-values expression"},
+		{"(receive (h . t) (values 1 2 3) (list h t))", "(1 (2 3))", ""},
+		{"(receive all (values 1 2) all)", "(1 2)", ""},
+		{"(receive (a) (values 1))", "", "Bad receive expression - expected formals, an expression and a body"},
+		{"(receive (1) (values 1) 2)", "", "Bad formals - invalid identifier"},
+		{"(define (f) (define-values (a b) (values 1 2)))", "", "Bad body"},
+	}
+}
+
+// EvalPortTestCases need ports, which the golang backend can't compile
+func EvalPortTestCases() []TestCase {
+	return []TestCase{
+		{`(define p (open-input-string "ab\ncd\n\nlast")) (list (read-line p) (read-line p) (read-line p) (read-line p) (eof-object? (read-line p)))`, `(ab cd  last #t)`, ""},
+		{`(define p (open-input-string "xy")) (list (peek-char p) (read-char p) (read-char p) (eof-object? (peek-char p)) (eof-object? (read-char p)))`, "(x x y #t #t)", ""},
+		{`(define p (open-output-string)) (write-string "foo" p) (display 42 p) (newline p) (get-output-string p)`, "foo42\n", ""},
+		{`(with-output-to-string (lambda () (display "a") (write-string "b") (display 1)))`, "ab1", ""},
+		{`(define p (open-output-string))
+		  (display "in" p)
+		  (string-append (with-output-to-string (lambda () (display "out"))) (get-output-string p))`, "outin", ""},
+		// An escape from the thunk restores the current output port
+		{`(define p (open-output-string))
+		  (call/cc (lambda (k) (with-output-to-string (lambda () (k 1)))))
+		  (eq? (current-output-port) (with-output-to-string (lambda () (display (current-output-port)))))`, "#f", ""},
+		{`(list (input-port? (current-input-port)) (output-port? (current-input-port)) (port? (current-error-port)) (port? 1))`, "(#t #f #t #f)", ""},
+		{`(eof-object? (eof-object))`, "#t", ""},
+		{`(define p (open-input-string "x")) (close-port p) (read-char p)`, "", "Closed port passed to read-char"},
+		{`(read-line (open-output-string))`, "", "Non-input-port passed to read-line"},
+		{`(write-string "x" (open-input-string ""))`, "", "Non-output-port passed to write-string"},
+		{`(get-output-string (current-output-port))`, "", "Non-string-port passed to get-output-string"},
+		{`(open-input-file "/no/such/file")`, "", "Can't open file"},
+	}
+}
+
+// EvalReadTestCases need read and write, which the golang backend
+// can't compile
+func EvalReadTestCases() []TestCase {
+	return []TestCase{
+		{`(read (open-input-string "(a (b . c) #(1 2) \"s\")"))`, `(a (b . c) #(1 2) s)`, ""},
+		{`(define p (open-input-string "1 foo\n(2 3)"))
+		  (list (read p) (read p) (read-line p) (read p) (eof-object? (read p)))`, "(1 foo  (2 3) #t)", ""},
+		{`(read-from-string "'x")`, "(quote x)", ""},
+		{`(car (read-from-string "` + "`" + `(a ,b)"))`, "quasiquote", ""},
+		{`(eof-object? (read-from-string " ; nothing"))`, "#t", ""},
+		{`(read-from-string "(1 2")`, "", "Read failed: Unclosed list"},
+		{`(with-output-to-string (lambda () (write "a\"b\n")))`, `"a\"b\n"`, ""},
+		{`(with-output-to-string (lambda () (write (list #\space #\a 1.5 'x))))`, `(#\space #\a 1.5 x)`, ""},
+		{`(define d '(a "b" #\c (d . 1) #(e "f") -2.5 ""))
+		  (equal? d (read-from-string (with-output-to-string (lambda () (write d)))))`, "#t", ""},
+		{`(with-output-to-string (lambda () (write (list (string->symbol "a b") (string->symbol "12")))))`, `(|a b| |12|)`, ""},
+		{`(define s (string->symbol "a b"))
+		  (eq? s (read-from-string (with-output-to-string (lambda () (write s)))))`, "#t", ""},
+		{`(define l (list 1 2))
+		  (set-cdr! (cdr l) l)
+		  (with-output-to-string (lambda () (write l)))`, "#0=(1 2 . #0#)", ""},
+		{`(read-from-string "|x")`, "", "Read failed: Unterminated identifier"},
+		{`(define p (open-output-string)) (write "x" p) (get-output-string p)`, `"x"`, ""},
+		{`(read (open-output-string))`, "", "Non-input-port passed to read"},
+	}
+}
+
+// EvalEnvironmentTestCases need eval, which the golang backend can't
+// compile
+func EvalEnvironmentTestCases() []TestCase {
+	return []TestCase{
+		{`(eval '(+ 1 2))`, "3", ""},
+		{`(define x 10) (eval '(* x 2) (interaction-environment))`, "20", ""},
+		{`(eval '(define y 5) (interaction-environment)) (+ y 1)`, "6", ""},
+		{`(define (make-rule op n) (list op 'v n))
+		  (define v 3)
+		  (list (eval (make-rule '> 2)) (eval (make-rule '< 2)))`, "(#t #f)", ""},
+		{`(define d '(let loop ((i 0)) (if (< i 10) (loop (+ i 1)) i))) (list (eval d) (eval d) d)`,
+			"(10 10 (let loop ((i 0)) (if (< i 10) (loop (+ i 1)) i)))", ""},
+		{`(eval (read (open-input-string "(when #t (string-append \"a\" \"b\"))")))`, "ab", ""},
+		{`(eval (list + 1 2))`, "3", ""},
+		{`(define x 1)
+		  (define env (scheme-report-environment 5))
+		  (eval '(define x 2) env)
+		  (list x (eval 'x env))`, "(1 2)", ""},
+		{`(define x 1) (eval 'x (scheme-report-environment 5))`, "", "Failed to find [x]"},
+		{`(eval '(s:list (s:+ 1 2)) (environment '(prefix (only (scheme base) + list) s:)))`, "(3)", ""},
+		{`(eval '(plus 1 2) (environment '(rename (scheme base) (+ plus))))`, "3", ""},
+		{`(eval '(list 1) (environment '(except (scheme base) list)))`, "", "Failed to find [list]"},
+		{`(list (environment? (interaction-environment)) (environment? 1))`, "(#t #f)", ""},
+		{`(guard (e (#t (error-object-message e))) (eval 'nope))`, "Failed to find [nope]: Identifier [nope] not found", ""},
+		{`(environment '(no such))`, "", "Unknown library passed to environment"},
+		{`(scheme-report-environment 7)`, "", "Unsupported version passed to scheme-report-environment"},
+		{`(eval 1 2)`, "", "Non-environment passed to eval"},
+	}
+}
+
+// RegisteredTestCases call the Go functions of RegisterFunctions
+func RegisteredTestCases() []TestCase {
+	return []TestCase{
+		{`(repeat "ab" 3)`, "ababab", ""},
+		{`(sum 1 2 3)`, "6", ""},
+		{`(sum)`, "0", ""},
+		{`(fields " a  b c ")`, "(a b c)", ""},
+		{`(divide 7 2)`, "3", ""},
+		{`(guard (e (#t (error-object-message e))) (number->string (divide 1 0)))`, "divide: division by zero", ""},
+		{`(call-with-values (lambda () (min-max 3 1 4 1 5)) (lambda (lo hi) (- hi lo)))`, "4", ""},
+		{`(string-append (shout "hi" #t) (shout "there" #f))`, "HI!there", ""},
+		{`(scale 2.0 1.5 2.5)`, "(3.0 5.0)", ""},
+		{`(join (fields "a b  c") "+")`, "a+b+c", ""},
+		{`(define (f s) (ignore s) 1) (f "x")`, "1", ""},
+		{`(let ((repeat (lambda (s n) n))) (repeat "ab" 2))`, "2", ""},
+		{`(small 255)`, "255", ""},
+		{`(divide 1 0)`, "", "divide: division by zero"},
+	}
+}
+
+// EvalRegisteredTestCases call Go functions which use maps and structs,
+// which the golang backend can't convert
+func EvalRegisteredTestCases() []TestCase {
+	return []TestCase{
+		{`(word-counts "b a b")`, "((a . 1) (b . 2))", ""},
+		{`(total '(("x" . 2) (y . 3)))`, "5", ""},
+		{`(midpoint '((X . 0) (Y . 10)) '((X . 4) (Y . 20)))`, "((X . 2) (Y . 15))", ""},
+		{`(midpoint '((Z . 0)) '())`, "", "Bad arg 0 to midpoint: test.Point has no field Z"},
+		{`(fields (vector "a"))`, "", "Bad arg 0 to fields: #(a) isn't a string"},
+		{`(join #("a" b) "-")`, "a-b", ""},
+		{`(small 256)`, "", "Bad arg 0 to small: 256 is out of range for uint8"},
+		{`(repeat "a")`, "", "Arity-error: expected == 2 args"},
+		{`(eval '(repeat "x" 2) (scheme-report-environment 5))`, "xx", ""},
+	}
+}
+
 func TypeTestCases() []TestCase {
 	return []TestCase{
 		{`(+ "foo" 1)`, "", "error - what kind?"},
@@ -193,6 +1069,13 @@ func BasicTestCases() []TestCase {
 (fact 3)
   `, "6", ""},
 		{`(display "hello, world\n")`, "()", ""},
+
+		{`; leading comment
+(+ 1 ; trailing comment
+   2)`, "3", ""},
+		{`#| block #| nested |# comment |# (+ 2 2)`, "4", ""},
+		{`(+ 1 #;(error "ignored") 4)`, "5", ""},
+		{`#;1 6`, "6", ""},
 	}
 	//	s := `
 	//(func (inc (x))
@@ -2,23 +2,49 @@ package gol
 
 import "errors"
 
+// Transform transforms a program, or a form at its top level. A define
+// is only allowed at the top level, in a top-level progn or begin, or
+// at the start of a body, since anywhere else it would still define a
+// global.
 func Transform(node Node) (Node, error) {
+	n, ok := node.(*NodeList)
+	if !ok || n.Len() == 0 {
+		return transform(node)
+	}
+	switch {
+	case isDefine(n):
+		return transformDefine(n)
+	case isDefineValues(n):
+		expansion, err := expandDefineValues(n)
+		if err != nil {
+			return nil, err
+		}
+		return Transform(expansion)
+	case isIdentifier(n.First(), "progn"), isIdentifier(n.First(), "begin"):
+		return transformProgn(n, Transform)
+	}
+	return transform(node)
+}
+
+func transform(node Node) (Node, error) {
 	switch n := node.(type) {
 	case *NodeList:
-		return transformList(n)
+		ret, err := transformList(n)
+		if err != nil {
+			return nil, err
+		}
+		// Nodes we synthesize stand in for the source list
+		if ret.Span().IsZero() {
+			setSpan(ret, n.Span())
+		}
+		return ret, nil
 	default:
 		return node, nil
 	}
 }
 
 func transformNodes(nl *NodeList) (*NodeList, error) {
-	return nl.Map(func(child Node) (Node, error) {
-		newNode, err := Transform(child)
-		if err != nil {
-			return nil, err
-		}
-		return newNode, nil
-	})
+	return nl.Map(transform)
 }
 
 func transformList(n *NodeList) (Node, error) {
@@ -30,18 +56,18 @@ func transformList(n *NodeList) (Node, error) {
 	id, ok := first.(*NodeIdentifier)
 	if ok {
 		switch id.String() {
-		case "define":
-			return transformDefine(n)
+		case "define", "define-values":
+			return nil, NodeErrorf(n, "Bad %s expression - only allowed at the top level or the start of a body", id)
 		case "let":
 			return transformLet(n)
 		case "letrec":
 			return transformLet(n)
-		case "progn":
-			return transformProgn(n)
+		case "letrec*":
+			return transformLet(n)
+		case "progn", "begin":
+			return transformProgn(n, transform)
 		case "lambda":
 			return transformLambda(n)
-		case "error":
-			return transformError(n)
 		case "if":
 			return transformIf(n)
 		case "set!":
@@ -52,6 +78,32 @@ func transformList(n *NodeList) (Node, error) {
 			return transformQuasiQuote(n)
 		case "unquote":
 			return transformUnQuote(n)
+		case "cond":
+			return transformDerived(expandCond)(n)
+		case "case":
+			return transformDerived(expandCase)(n)
+		case "and":
+			return transformDerived(expandAnd)(n)
+		case "or":
+			return transformDerived(expandOr)(n)
+		case "when":
+			return transformDerived(expandWhen)(n)
+		case "unless":
+			return transformDerived(expandUnless)(n)
+		case "let*":
+			return transformDerived(expandLetStar)(n)
+		case "do":
+			return transformDerived(expandDo)(n)
+		case "case-lambda":
+			return transformDerived(expandCaseLambda)(n)
+		case "guard":
+			return transformDerived(expandGuard)(n)
+		case "let-values":
+			return transformDerived(expandLetValues)(n)
+		case "let*-values":
+			return transformDerived(expandLetStarValues)(n)
+		case "receive":
+			return transformDerived(expandReceive)(n)
 		}
 	}
 	ret, err := transformNodes(n)
@@ -65,7 +117,7 @@ func transformQuasiQuote(n *NodeList) (Node, error) {
 	if n.Len() != 2 {
 		return nil, NodeErrorf(n, "Bad quasiquote expression - more than one arg")
 	}
-	child, err := Transform(n.Rest().First())
+	child, err := transform(n.Rest().First())
 	if err != nil {
 		return nil, err
 	}
@@ -76,7 +128,7 @@ func transformQuote(n *NodeList) (Node, error) {
 	if n.Len() != 2 {
 		return nil, NodeErrorf(n, "Bad quote expression - more than one arg")
 	}
-	child, err := Transform(n.Rest().First())
+	child, err := transform(n.Rest().First())
 	if err != nil {
 		return nil, err
 	}
@@ -87,7 +139,7 @@ func transformUnQuote(n *NodeList) (Node, error) {
 	if n.Len() != 2 {
 		return nil, NodeErrorf(n, "Bad quote expression - more than one arg")
 	}
-	child, err := Transform(n.Rest().First())
+	child, err := transform(n.Rest().First())
 	if err != nil {
 		return nil, err
 	}
@@ -129,35 +181,34 @@ func transformSet(n *NodeList) (Node, error) {
 	}, nil
 }
 
-func transformError(n *NodeList) (Node, error) {
-	if n.Len() != 2 {
-		return nil, NodeErrorf(n, "Bad error expression - exactly one string required")
-	}
-	return NodeErrorf(n.Nth(1), n.Nth(1).String()), nil
-}
-
-func transformProgn(n *NodeList) (Node, error) {
+// transformProgn transforms the children of a progn with f, which is
+// Transform for one at the top level
+func transformProgn(n *NodeList, f func(Node) (Node, error)) (Node, error) {
 	if n.Len() == 1 {
 		// Empty progn
 		return nil, errors.New("Empty progn not allowed")
 	}
 
-	children, err := transformNodes(n.Rest())
+	children, err := n.Rest().Map(f)
 	if err != nil {
 		return nil, err
 	}
-	return &NodeProgn{children.Cons(n.First())}, nil
+	progn := children.Cons(n.First())
+	progn.SetSpan(n.Span())
+	return &NodeProgn{progn}, nil
 }
 
 func transformDefine(n *NodeList) (Node, error) {
-	if n.Len() != 3 {
+	if n.Len() < 3 {
 		return nil, NodeErrorf(n, "Bad define expression - wrong arity")
 	}
 
-	// Syntactix suger '(define (f x) body) -> '(define f (lambda (x) body))'
-	IDAndArgs, ok := n.Nth(1).(*NodeList)
-	if ok {
-		return transformSugaryDefine(IDAndArgs, n)
+	// Syntactix suger '(define (f x) body ...) -> '(define f (lambda (x) body ...))'
+	if IDAndArgs, tail, ok := listParts(n.Nth(1)); ok {
+		return transformSugaryDefine(IDAndArgs, tail, n)
+	}
+	if n.Len() != 3 {
+		return nil, NodeErrorf(n, "Bad define expression - wrong arity")
 	}
 
 	id, ok := n.Nth(1).(*NodeIdentifier)
@@ -178,28 +229,29 @@ func transformDefine(n *NodeList) (Node, error) {
 	}, nil
 }
 
-func transformSugaryDefine(IDAndArgs *NodeList, n *NodeList) (Node, error) {
+func transformSugaryDefine(IDAndArgs []Node, tail Node, n *NodeList) (Node, error) {
 	//		fmt.Printf("define lambda: %s\n", n)
 	//fmt.Printf("len id + args %d - %s\n", len(IDAndArgs.children), IDAndArgs)
-	if IDAndArgs.Len() == 0 {
+	if len(IDAndArgs) == 0 {
 		return nil, NodeErrorf(n, "Bad func define expression - no name")
 	}
-	id := IDAndArgs.First()
-	args := IDAndArgs.Rest()
+	id := IDAndArgs[0]
+	// (f a . rest) has the improper formals (a . rest)
+	args := buildList(n.Nth(1), IDAndArgs[1:], tail)
 
 	newDefine := NewNodeList()
+	newDefine.SetSpan(n.Span())
 	newDefine = newDefine.Append(n.First())
 
 	// Replace (f x) -> f
 	newDefine = newDefine.Append(id)
 
-	// Replace body -> (lambda 'args' body)
-	body := NewNodeList()
-	lambdaBody := n.Rest().Rest()
-	lambdaBody = lambdaBody.Cons(makeIdentifier("progn"))
-	body = body.Cons(lambdaBody)
+	// Replace body ... -> (lambda 'args' body ...), so that any internal
+	// defines are at the start of the lambda body
+	body := n.Rest().Rest()
 	body = body.Cons(args)
-	body = body.Cons(makeIdentifier("lambda"))
+	body = body.Cons(synthIdentifier("lambda", n))
+	body.SetSpan(n.Span())
 
 	newDefine = newDefine.Append(body)
 	//fmt.Printf("define lambda: %s\n", n)
@@ -210,7 +262,11 @@ func transformLet(n *NodeList) (Node, error) {
 	if n.Len() < 3 {
 		return nil, NodeErrorf(n, "Bad let expression - missing bindings or body")
 	}
-	nLet := &NodeLet{NodeList: n, Bindings: make(map[string]Node)}
+	if _, ok := n.Nth(1).(*NodeIdentifier); ok && isIdentifier(n.First(), "let") {
+		return transformDerived(expandNamedLet)(n)
+	}
+	rec := isIdentifier(n.First(), "letrec") || isIdentifier(n.First(), "letrec*")
+	nLet := &NodeLet{NodeList: n, Rec: rec}
 	bindings, ok := n.Nth(1).(*NodeList)
 	if !ok {
 		return nil, NodeErrorf(n, "Bad let expression - bindings must be a list")
@@ -227,37 +283,48 @@ func transformLet(n *NodeList) (Node, error) {
 		if !ok {
 			return NodeErrorf(n, "Bad let expression - invalid identifier")
 		}
-		var err error
-		nLet.Bindings[id.String()], err = Transform(pair.Nth(1))
+		value, err := transform(pair.Nth(1))
 		if err != nil {
 			return err
 		}
+		nLet.Bindings = append(nLet.Bindings, LetBinding{Id: id, Value: value})
 		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	children, err := transformNodes(n.Rest().Rest())
+	nLet.Body, err = transformBody(n, n.Rest().Rest())
 	if err != nil {
 		return nil, err
 	}
-	nLet.Body = makeProgn(children)
 	return nLet, nil
 }
 
-func transformLambda(n *NodeList) (Node, error) {
-	if n.Len() < 3 {
-		return nil, NodeErrorf(n, "Bad lambda expression - missing args or body [len %d]: %s", n.Len(), n)
+// transformBody transforms a lambda or let body. Any leading internal
+// defines become a letrec* scope around the rest of the body, rather
+// than defining globals, and a define-values binds the rest of the body.
+func transformBody(n *NodeList, body *NodeList) (*NodeProgn, error) {
+	var defines []Node
+	for body.Len() > 0 && isDefine(body.First()) {
+		defines = append(defines, body.First())
+		body = body.Rest()
 	}
-	args, ok := n.Nth(1).(*NodeList)
-	if !ok {
-		return nil, NodeErrorf(n, "Bad lambda expression - args must be a list")
+	if body.Len() > 0 && isDefineValues(body.First()) {
+		rest, err := expandBodyDefineValues(body.First().(*NodeList), body.Rest())
+		if err != nil {
+			return nil, err
+		}
+		body = synthList(n, rest)
 	}
-	err := args.Foreach(func(argNode Node) error {
-		_, ok := argNode.(*NodeIdentifier)
-		if !ok {
-			return NodeErrorf(n, "Bad lambda expression - arg must be identifier")
+	if len(defines) > 0 && body.Len() == 0 {
+		return nil, NodeErrorf(n, "Bad body - no expression after internal defines")
+	}
+	// Only leading defines are in the body's scope, so a later one
+	// would define a global
+	err := body.Foreach(func(child Node) error {
+		if isDefine(child) || isDefineValues(child) {
+			return NodeErrorf(child, "Bad body - internal define after an expression")
 		}
 		return nil
 	})
@@ -265,16 +332,100 @@ func transformLambda(n *NodeList) (Node, error) {
 		return nil, err
 	}
 
-	body := n.Rest().Rest()
-	body, err = transformNodes(body)
+	children, err := transformNodes(body)
 	if err != nil {
 		return nil, err
 	}
-	nLambda := &NodeLambda{NodeList: n, Args: args, Body: makeProgn(body)}
+	if len(defines) == 0 {
+		return makeProgn(children), nil
+	}
+
+	nLet := &NodeLet{
+		NodeList: synthList(n, append([]Node{synthIdentifier("letrec*", n)}, defines...)...),
+		Rec:      true,
+	}
+	for _, define := range defines {
+		node, err := transformDefine(define.(*NodeList))
+		if err != nil {
+			return nil, err
+		}
+		nDefine := node.(*NodeDefine)
+		nLet.Bindings = append(nLet.Bindings, LetBinding{Id: nDefine.Symbol.(*NodeIdentifier), Value: nDefine.Value})
+	}
+	nLet.Body = makeProgn(children)
+	return makeProgn(synthList(n, nLet)), nil
+}
+
+func isDefine(n Node) bool {
+	nl, ok := n.(*NodeList)
+	return ok && isIdentifier(nl.First(), "define")
+}
+
+func isDefineValues(n Node) bool {
+	nl, ok := n.(*NodeList)
+	return ok && isIdentifier(nl.First(), "define-values")
+}
+
+func transformLambda(n *NodeList) (Node, error) {
+	if n.Len() < 3 {
+		return nil, NodeErrorf(n, "Bad lambda expression - missing args or body [len %d]: %s", n.Len(), n)
+	}
+	args, rest, err := transformFormals(n, n.Nth(1))
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := transformBody(n, n.Rest().Rest())
+	if err != nil {
+		return nil, err
+	}
+	nLambda := &NodeLambda{NodeList: n, Args: args, Rest: rest, Body: body}
 	return nLambda, nil
 }
 
+// transformFormals splits lambda formals into the required args and
+// the rest arg, if any: (a b), (a b . rest) or just rest
+func transformFormals(n *NodeList, formals Node) (*NodeList, *NodeIdentifier, error) {
+	if id, ok := formals.(*NodeIdentifier); ok {
+		return NewNodeList(), id, nil
+	}
+	elems, tail, ok := listParts(formals)
+	if !ok {
+		return nil, nil, NodeErrorf(n, "Bad lambda expression - args must be a list")
+	}
+	for _, elem := range elems {
+		if _, ok := elem.(*NodeIdentifier); !ok {
+			return nil, nil, NodeErrorf(n, "Bad lambda expression - arg must be identifier")
+		}
+	}
+	var rest *NodeIdentifier
+	if tail != nil {
+		rest, ok = tail.(*NodeIdentifier)
+		if !ok {
+			return nil, nil, NodeErrorf(n, "Bad lambda expression - rest arg must be identifier")
+		}
+	}
+	args := synthList(formals, elems...)
+	return args, rest, nil
+}
+
+// makeProgn spans the progn over its body forms
 func makeProgn(nl *NodeList) *NodeProgn {
-	nl = nl.Cons(makeIdentifier("progn"))
+	var span Span
+	if nl.Len() > 0 {
+		span = Span{Start: nl.First().Span().Start, End: nl.Nth(nl.Len() - 1).Span().End}
+	}
+	id := NewNodeIdentifier("progn")
+	id.SetSpan(span)
+	nl = nl.Cons(id)
+	nl.SetSpan(span)
 	return &NodeProgn{nl}
 }
+
+// synthIdentifier makes an identifier which stands in for source,
+// taking its span
+func synthIdentifier(name string, source Node) *NodeIdentifier {
+	id := NewNodeIdentifier(name)
+	id.SetSpan(source.Span())
+	return id
+}
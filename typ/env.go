@@ -20,6 +20,9 @@ func (e Env) Lookup(s string) (Type, error) {
 	for _, f := range []Frame(e) {
 		t, ok := f[s]
 		if ok {
+			if g, isGeneric := t.(Generic); isGeneric {
+				return g.Instantiate(), nil
+			}
 			return t, nil
 		}
 	}
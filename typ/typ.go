@@ -14,6 +14,9 @@ const (
 	Symbol
 	String
 	Void
+	Char
+	Real
+	Rational
 )
 
 type Type interface {
@@ -35,16 +38,26 @@ func (p Primitive) String() string {
 		return "String"
 	case Void:
 		return "Void"
+	case Char:
+		return "Char"
+	case Real:
+		return "Real"
+	case Rational:
+		return "Rational"
 	default:
 		panic("Unrecognised primitive")
 	}
 }
 
 func (p Primitive) Unify(t Type) error {
-	if tPrim, ok := t.(Primitive); ok && p == tPrim {
+	if tPrim, ok := t.(Primitive); ok && (p == tPrim || tPrim == Any) {
 		// Both same primitive type
 		return nil
 	}
+	if p == Any {
+		// Any accepts everything, without constraining it
+		return nil
+	}
 	return unifyWithVarOrError(p, t)
 }
 
@@ -70,13 +83,25 @@ func (f Func) String() string {
 	return fmt.Sprintf("(%s) -> %s", strings.Join(args, ","), result)
 }
 
+func endsVariadic(args []Type) bool {
+	if len(args) == 0 {
+		return false
+	}
+	_, ok := args[len(args)-1].(Variadic)
+	return ok
+}
+
 func variadicUnify(a, b []Type) bool {
 	if len(a) == 0 && len(b) == 0 {
 		return true
 	}
-	if len(b) < len(a) {
+	// a is the variadic one, or the shorter if both are
+	if !endsVariadic(a) || (endsVariadic(b) && len(b) < len(a)) {
 		a, b = b, a
 	}
+	if !endsVariadic(a) || len(b) < len(a)-1 {
+		return false
+	}
 
 	for i := 0; i < len(a)-1; i++ {
 		err := a[i].Unify(b[i])
@@ -85,12 +110,13 @@ func variadicUnify(a, b []Type) bool {
 		}
 	}
 	// Last in a must b variadic, all in b same type
-	variadic, ok := a[len(a)-1].(Variadic)
-	if !ok {
-		return false
-	}
-	for j := len(a); j < len(b); j++ {
-		err := b[j].Unify(variadic.X)
+	variadic := a[len(a)-1].(Variadic)
+	for j := len(a) - 1; j < len(b); j++ {
+		bj := b[j]
+		if bVariadic, ok := bj.(Variadic); ok {
+			bj = bVariadic.X
+		}
+		err := bj.Unify(variadic.X)
 		if err != nil {
 			return false
 		}
@@ -105,7 +131,9 @@ func (f Func) Unify(t Type) error {
 		return unifyWithVarOrError(f, t)
 	}
 
-	err := f.Result.Unify(newFunc.Result)
+	// This way round, so that a function with an Any result still binds
+	// a var result, rather than Any accepting it as a wildcard
+	err := newFunc.Result.Unify(f.Result)
 	if err != nil {
 		return err
 	}
@@ -163,6 +191,47 @@ func (p Pair) String() string {
 	return fmt.Sprintf("Pair{%s,%s}", p.car.String(), p.cdr.String())
 }
 
+type Vector struct {
+	Elem Type
+}
+
+func NewVector(elem Type) Vector {
+	return Vector{Elem: elem}
+}
+
+func (v Vector) Unify(t Type) error {
+	newVector, ok := t.(Vector)
+	if ok {
+		return v.Elem.Unify(newVector.Elem)
+	}
+	return unifyWithVarOrError(v, t)
+}
+
+func (v Vector) String() string {
+	return fmt.Sprintf("Vector{%s}", v.Elem)
+}
+
+// List is a proper list whose elements all have the same type
+type List struct {
+	Elem Type
+}
+
+func NewList(elem Type) List {
+	return List{Elem: elem}
+}
+
+func (l List) Unify(t Type) error {
+	newList, ok := t.(List)
+	if ok {
+		return l.Elem.Unify(newList.Elem)
+	}
+	return unifyWithVarOrError(l, t)
+}
+
+func (l List) String() string {
+	return fmt.Sprintf("List{%s}", l.Elem)
+}
+
 type Variadic struct {
 	X Type
 }
@@ -182,3 +251,64 @@ func (v Variadic) Unify(t Type) error {
 func NewVariadic(t Type) Variadic {
 	return Variadic{t}
 }
+
+// Generic is a polymorphic type. Each lookup in an Env instantiates it
+// afresh, so that (e.g.) numeric '+' can be used with Ints in one place
+// and Reals in another.
+type Generic struct {
+	Name        string
+	Instantiate func() Type
+}
+
+func NewGeneric(name string, instantiate func() Type) Generic {
+	return Generic{Name: name, Instantiate: instantiate}
+}
+
+func (g Generic) String() string {
+	return fmt.Sprintf("Generic{%s}", g.Name)
+}
+
+func (g Generic) Unify(t Type) error {
+	return g.Instantiate().Unify(t)
+}
+
+// Tuple is the type of the multiple values returned by values. One
+// value is just that value, so there is no Tuple of one.
+type Tuple struct {
+	Elems []Type
+}
+
+// NewTuple is the type of returning elems, which is the type of the
+// only elem if there is just one
+func NewTuple(elems []Type) Type {
+	if len(elems) == 1 {
+		return elems[0]
+	}
+	return Tuple{Elems: elems}
+}
+
+func (tu Tuple) Unify(t Type) error {
+	newTuple, ok := t.(Tuple)
+	if !ok {
+		return unifyWithVarOrError(tu, t)
+	}
+	if len(tu.Elems) != len(newTuple.Elems) {
+		return fmt.Errorf("Can't unify: value count mismatch %d != %d [%s] [%s]",
+			len(tu.Elems), len(newTuple.Elems), tu, newTuple)
+	}
+	for i := range tu.Elems {
+		err := tu.Elems[i].Unify(newTuple.Elems[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tu Tuple) String() string {
+	elems := make([]string, len(tu.Elems))
+	for i := range tu.Elems {
+		elems[i] = tu.Elems[i].String()
+	}
+	return fmt.Sprintf("Tuple{%s}", strings.Join(elems, ","))
+}
@@ -217,3 +217,66 @@ func TestUnifyFail(t *testing.T) {
 		log.Printf("Good - var(String) and var(symbol) failed to unify with: %s\n", err)
 	}
 }
+
+func TestVectorUnify(t *testing.T) {
+	elem := NewVar()
+	v := NewVector(elem)
+	err := v.Unify(NewVector(Int))
+	if err != nil {
+		t.Fatalf("Can't unify vectors: %s", err)
+	}
+	if v.String() != "Vector{Int}" {
+		t.Fatalf("Elem not resolved: %s", v)
+	}
+	err = v.Unify(NewVector(String))
+	if err == nil {
+		t.Fatalf("Unified Vector{Int} with Vector{String}")
+	}
+	err = v.Unify(Int)
+	if err == nil {
+		t.Fatalf("Unified Vector{Int} with Int")
+	}
+}
+
+func TestTupleUnify(t *testing.T) {
+	elem := NewVar()
+	tu := NewTuple([]Type{Int, elem})
+	err := tu.Unify(NewTuple([]Type{Int, String}))
+	if err != nil {
+		t.Fatalf("Can't unify tuples: %s", err)
+	}
+	if tu.String() != "Tuple{Int,String}" {
+		t.Fatalf("Elem not resolved: %s", tu)
+	}
+	err = tu.Unify(NewTuple([]Type{Int, String, Int}))
+	if err == nil {
+		t.Fatalf("Unified tuples of different lengths")
+	}
+	if NewTuple([]Type{Int}) != Int {
+		t.Fatalf("Tuple of one isn't its elem")
+	}
+}
+
+func TestVariadicUnify(t *testing.T) {
+	elem := NewVar()
+	f := NewFunc([]Type{String, NewVariadic(elem)}, Int)
+	err := f.Unify(NewFunc([]Type{String, Int, Int}, Int))
+	if err != nil {
+		t.Fatalf("Can't unify variadic func with call: %s", err)
+	}
+	if elem.String() != "Int" {
+		t.Fatalf("Elem not resolved: %s", elem)
+	}
+	err = f.Unify(NewFunc([]Type{String}, Int))
+	if err != nil {
+		t.Fatalf("Can't unify variadic func with no rest args: %s", err)
+	}
+	err = f.Unify(NewFunc([]Type{}, Int))
+	if err == nil {
+		t.Fatalf("Unified variadic func with too few args")
+	}
+	err = f.Unify(NewFunc([]Type{String, String}, Int))
+	if err == nil {
+		t.Fatalf("Unified Variadic{Int} with String")
+	}
+}